@@ -1,21 +1,64 @@
 package auth
 
 import (
+	"time"
+
 	"github.com/bookpanda/mygraderlist-auth/src/constant/auth"
 	"github.com/golang-jwt/jwt/v4"
 )
 
 type TokenPayloadAuth struct {
 	jwt.RegisteredClaims
-	UserId string `json:"user_id"`
+	UserId string       `json:"user_id"`
+	Role   auth.Role    `json:"role,omitempty"`
+	Act    *ActorClaims `json:"act,omitempty"`
+	// Sid is the device session of the token, empty for the session of the account
+	Sid string `json:"sid,omitempty"`
+}
+
+type TokenPayloadService struct {
+	jwt.RegisteredClaims
+	ClientId string `json:"client_id"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// ActorClaims identifies the party acting on behalf of the token subject (RFC 8693)
+type ActorClaims struct {
+	Sub string `json:"sub"`
 }
 
 type UserCredential struct {
-	UserId string    `json:"user_id"`
-	Role   auth.Role `json:"role"`
+	UserId    string    `json:"user_id"`
+	Role      auth.Role `json:"role"`
+	ActorId   string    `json:"actor_id,omitempty"`
+	SessionId string    `json:"session_id,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	Audience  []string  `json:"audience,omitempty"`
+	ExpiresAt int64     `json:"expires_at,omitempty"`
+	IssuedAt  int64     `json:"issued_at,omitempty"`
 }
 
 type CacheAuth struct {
-	Token string    `json:"token"`
-	Role  auth.Role `json:"role"`
+	Token          string      `json:"token"`
+	Role           auth.Role   `json:"role"`
+	Status         auth.Status `json:"status,omitempty"`
+	SuspendedUntil *time.Time  `json:"suspended_until,omitempty"`
+	StatusReason   string      `json:"status_reason,omitempty"`
+}
+
+// CacheImpersonators are the actors impersonating the user, so the impersonations end along with the account
+type CacheImpersonators struct {
+	ActorIDs []string `json:"actor_ids"`
+}
+
+// RevocationEvent tells the services caching validations that the ones of the user are stale. The
+// offset is assigned when the event is appended to the log.
+type RevocationEvent struct {
+	Offset     string          `json:"-"`
+	Type       auth.Revocation `json:"type"`
+	UserID     string          `json:"user_id"`
+	ActorID    string          `json:"actor_id,omitempty"`
+	Role       string          `json:"role,omitempty"`
+	Status     string          `json:"status,omitempty"`
+	OccurredAt int64           `json:"occurred_at"`
 }
@@ -0,0 +1,18 @@
+package device
+
+import "github.com/bookpanda/mygraderlist-auth/src/constant/device"
+
+type CacheDeviceAuthorization struct {
+	ClientID  string        `json:"client_id"`
+	UserCode  string        `json:"user_code"`
+	Status    device.Status `json:"status"`
+	UserID    string        `json:"user_id,omitempty"`
+	Interval  int32         `json:"interval"`
+	ExpiresAt int64         `json:"expires_at"`
+}
+
+// CacheDevicePoll is kept apart from the authorization, so a poll never writes over its approval
+type CacheDevicePoll struct {
+	Interval     int32 `json:"interval"`
+	LastPolledAt int64 `json:"last_polled_at"`
+}
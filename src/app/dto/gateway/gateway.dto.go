@@ -0,0 +1,20 @@
+package gateway
+
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody mirrors the gRPC status of the failed call so http and gRPC clients see the same error
+type ErrorBody struct {
+	Code     int               `json:"code"`
+	Status   string            `json:"status"`
+	Message  string            `json:"message"`
+	Reason   string            `json:"reason,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// SessionResponse is returned instead of the credential when the session is kept in cookies
+type SessionResponse struct {
+	ExpiresIn int32  `json:"expiresIn"`
+	CsrfToken string `json:"csrfToken"`
+}
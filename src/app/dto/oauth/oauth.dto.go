@@ -0,0 +1,19 @@
+package oauth
+
+import dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+
+type IntrospectionResponse struct {
+	Active   bool             `json:"active"`
+	Sub      string           `json:"sub,omitempty"`
+	Exp      int64            `json:"exp,omitempty"`
+	Iat      int64            `json:"iat,omitempty"`
+	Scope    string           `json:"scope,omitempty"`
+	Role     string           `json:"role,omitempty"`
+	ClientId string           `json:"client_id,omitempty"`
+	Act      *dto.ActorClaims `json:"act,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
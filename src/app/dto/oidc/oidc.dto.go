@@ -0,0 +1,126 @@
+package oidc
+
+import "github.com/golang-jwt/jwt/v4"
+
+type AuthorizeRequest struct {
+	ClientID            string
+	RedirectUri         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	Prompt              string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectUri  string
+	CodeVerifier string
+	ClientID     string
+	ClientSecret string
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int32  `json:"expires_in"`
+	IdToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+type UserInfoResponse struct {
+	Sub   string `json:"sub"`
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+// ConsentPrompt is what the user is asked to approve before the client receives a code
+type ConsentPrompt struct {
+	RequestID  string
+	ClientName string
+	Scopes     []string
+}
+
+type TokenPayloadId struct {
+	jwt.RegisteredClaims
+	AuthTime int64  `json:"auth_time"`
+	Nonce    string `json:"nonce,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Name     string `json:"name,omitempty"`
+}
+
+// CacheAuthorizationRequest is an authorization request waiting for the upstream login and the user's consent
+type CacheAuthorizationRequest struct {
+	ClientID      string   `json:"client_id"`
+	RedirectUri   string   `json:"redirect_uri"`
+	Scopes        []string `json:"scopes"`
+	State         string   `json:"state,omitempty"`
+	Nonce         string   `json:"nonce,omitempty"`
+	Prompt        string   `json:"prompt,omitempty"`
+	CodeChallenge string   `json:"code_challenge"`
+	UserID        string   `json:"user_id,omitempty"`
+	Email         string   `json:"email,omitempty"`
+	Name          string   `json:"name,omitempty"`
+	AuthTime      int64    `json:"auth_time,omitempty"`
+}
+
+type CacheAuthorizationCode struct {
+	ClientID      string   `json:"client_id"`
+	RedirectUri   string   `json:"redirect_uri"`
+	Scopes        []string `json:"scopes"`
+	Nonce         string   `json:"nonce,omitempty"`
+	CodeChallenge string   `json:"code_challenge"`
+	UserID        string   `json:"user_id"`
+	Email         string   `json:"email,omitempty"`
+	Name          string   `json:"name,omitempty"`
+	AuthTime      int64    `json:"auth_time"`
+}
+
+type CacheAccessToken struct {
+	ClientID  string   `json:"client_id"`
+	Scopes    []string `json:"scopes"`
+	UserID    string   `json:"user_id"`
+	Email     string   `json:"email,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	IssuedAt  int64    `json:"issued_at"`
+	ExpiresAt int64    `json:"expires_at"`
+}
+
+// CacheRevokedAccessTokens marks the access tokens of the user issued until RevokedAt as revoked
+type CacheRevokedAccessTokens struct {
+	RevokedAt int64 `json:"revoked_at"`
+}
+
+type DiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksUri                           string   `json:"jwks_uri"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JwkSet struct {
+	Keys []Jwk `json:"keys"`
+}
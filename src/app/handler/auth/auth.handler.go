@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bookpanda/mygraderlist-auth/src/app/dto/gateway"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxBodySize limits the request bodies, which only ever carry a token or a code
+const maxBodySize = 64 << 10
+
+var (
+	marshaler   = protojson.MarshalOptions{EmitUnpopulated: true}
+	unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// Handler serves the AuthService over http/json, the bodies use the proto json mapping of the gRPC messages
+type Handler struct {
+	service IService
+	conf    config.Session
+}
+
+type IService interface {
+	Validate(context.Context, *auth_proto.ValidateRequest) (*auth_proto.ValidateResponse, error)
+	RefreshToken(context.Context, *auth_proto.RefreshTokenRequest) (*auth_proto.RefreshTokenResponse, error)
+	GetGoogleLoginUrl(context.Context, *auth_proto.GetGoogleLoginUrlRequest) (*auth_proto.GetGoogleLoginUrlResponse, error)
+	VerifyGoogleLogin(context.Context, *auth_proto.VerifyGoogleLoginRequest) (*auth_proto.VerifyGoogleLoginResponse, error)
+	Logout(context.Context, *auth_proto.LogoutRequest) (*auth_proto.LogoutResponse, error)
+}
+
+func NewHandler(service IService, conf config.Session) *Handler {
+	return &Handler{
+		service: service,
+		conf:    conf,
+	}
+}
+
+// Validate accepts the token in the body, as a bearer token or from the session cookie
+func (h *Handler) Validate(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	in := &auth_proto.ValidateRequest{}
+	if !readBody(w, r, in) {
+		return
+	}
+
+	if in.Token == "" {
+		in.Token, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+
+	if in.Token == "" {
+		token, ok := sessionToken(w, r)
+		if !ok {
+			return
+		}
+		in.Token = token
+	}
+
+	res, err := h.service.Validate(r.Context(), in)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeProto(w, http.StatusOK, res)
+}
+
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	in := &auth_proto.RefreshTokenRequest{}
+	if !readBody(w, r, in) {
+		return
+	}
+
+	res, err := h.service.RefreshToken(r.Context(), in)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeProto(w, http.StatusOK, res)
+}
+
+func (h *Handler) GetGoogleLoginUrl(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	res, err := h.service.GetGoogleLoginUrl(r.Context(), &auth_proto.GetGoogleLoginUrlRequest{})
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeProto(w, http.StatusOK, res)
+}
+
+func (h *Handler) VerifyGoogleLogin(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	in := &auth_proto.VerifyGoogleLoginRequest{}
+	if !readBody(w, r, in) {
+		return
+	}
+
+	res, err := h.service.VerifyGoogleLogin(r.Context(), in)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	writeProto(w, http.StatusOK, res)
+}
+
+// WriteError writes the gRPC status as a json error body with the matching http status code
+func WriteError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+
+	body := gateway.ErrorBody{
+		Code:    HTTPStatusFromCode(st.Code()),
+		Status:  st.Code().String(),
+		Message: st.Message(),
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			body.Reason = info.Reason
+			body.Metadata = info.Metadata
+		}
+	}
+
+	if st.Code() == codes.Unauthenticated {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mygraderlist"`)
+	}
+
+	writeJSON(w, body.Code, &gateway.ErrorResponse{Error: body})
+}
+
+// HTTPStatusFromCode maps the gRPC status code to the http status code, following google.rpc.Code
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func readBody(w http.ResponseWriter, r *http.Request, in proto.Message) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		WriteError(w, status.Error(codes.InvalidArgument, "Cannot read the request body"))
+		return false
+	}
+
+	if len(body) == 0 {
+		return true
+	}
+
+	if err := unmarshaler.Unmarshal(body, in); err != nil {
+		WriteError(w, status.Error(codes.InvalidArgument, "Invalid json body"))
+		return false
+	}
+
+	return true
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, &gateway.ErrorResponse{Error: gateway.ErrorBody{
+		Code:    http.StatusMethodNotAllowed,
+		Status:  codes.Unimplemented.String(),
+		Message: "Method not allowed",
+	}})
+	return false
+}
+
+func writeProto(w http.ResponseWriter, code int, m proto.Message) {
+	body, err := marshaler.Marshal(m)
+	if err != nil {
+		WriteError(w, status.Error(codes.Internal, "Cannot encode the response"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	if _, err := w.Write(body); err != nil {
+		log.Error().Err(err).
+			Str("service", "gateway").
+			Msg("Cannot write the response")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error().Err(err).
+			Str("service", "gateway").
+			Msg("Cannot write the response")
+	}
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bookpanda/mygraderlist-auth/src/app/dto/gateway"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	mock "github.com/bookpanda/mygraderlist-auth/src/mocks/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+	testifyMock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type AuthHandlerTest struct {
+	suite.Suite
+	Token      string
+	Credential *auth_proto.Credential
+	Conf       config.Session
+}
+
+func TestAuthHandler(t *testing.T) {
+	suite.Run(t, new(AuthHandlerTest))
+}
+
+func (t *AuthHandlerTest) SetupTest() {
+	t.Token = faker.Word()
+	t.Conf = config.Session{
+		Domain:           "mygraderlist.bookpanda.dev",
+		SameSite:         "lax",
+		RefreshExpiresIn: 2592000,
+	}
+	t.Credential = &auth_proto.Credential{
+		AccessToken:  faker.Word(),
+		RefreshToken: faker.Word(),
+		ExpiresIn:    3600,
+	}
+}
+
+// protoArg matches a proto request decoded by the handler, which can't be compared with reflect.DeepEqual
+func protoArg(want proto.Message) interface{} {
+	return testifyMock.MatchedBy(func(in proto.Message) bool {
+		return proto.Equal(want, in)
+	})
+}
+
+func (t *AuthHandlerTest) TestValidateFromBearerToken() {
+	srv := &mock.ServiceMock{}
+	srv.On("Validate", protoArg(&auth_proto.ValidateRequest{Token: t.Token})).Return(&auth_proto.ValidateResponse{
+		UserId: faker.UUIDDigit(),
+		Role:   "user",
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/validate", nil)
+	req.Header.Set("Authorization", "Bearer "+t.Token)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).Validate(w, req)
+
+	actual := map[string]interface{}{}
+	_ = json.NewDecoder(w.Body).Decode(&actual)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	assert.Equal(t.T(), "user", actual["role"])
+	assert.Contains(t.T(), actual, "userId")
+}
+
+func (t *AuthHandlerTest) TestRefreshTokenSuccess() {
+	srv := &mock.ServiceMock{}
+	srv.On("RefreshToken", protoArg(&auth_proto.RefreshTokenRequest{RefreshToken: t.Token})).Return(&auth_proto.RefreshTokenResponse{
+		Credential: t.Credential,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/refresh", strings.NewReader(`{"refreshToken":"`+t.Token+`"}`))
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).RefreshToken(w, req)
+
+	actual := &auth_proto.RefreshTokenResponse{}
+	_ = unmarshaler.Unmarshal(w.Body.Bytes(), actual)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	assert.Equal(t.T(), "no-store", w.Header().Get("Cache-Control"))
+	assert.True(t.T(), proto.Equal(t.Credential, actual.Credential))
+}
+
+func (t *AuthHandlerTest) TestVerifyGoogleLoginInvalidBody() {
+	srv := &mock.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/google/verify", strings.NewReader(`{"code":`))
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).VerifyGoogleLogin(w, req)
+
+	actual := &gateway.ErrorResponse{}
+	_ = json.NewDecoder(w.Body).Decode(actual)
+
+	assert.Equal(t.T(), http.StatusBadRequest, w.Code)
+	assert.Equal(t.T(), "InvalidArgument", actual.Error.Status)
+	srv.AssertNotCalled(t.T(), "VerifyGoogleLogin", testifyMock.Anything)
+}
+
+func (t *AuthHandlerTest) TestVerifyGoogleLoginSuspendedAccount() {
+	st, _ := status.New(codes.PermissionDenied, "Account is suspended").WithDetails(&errdetails.ErrorInfo{
+		Reason:   "ACCOUNT_SUSPENDED",
+		Domain:   role.ERROR_DOMAIN,
+		Metadata: map[string]string{"until": "2030-01-01T00:00:00Z"},
+	})
+
+	srv := &mock.ServiceMock{}
+	srv.On("VerifyGoogleLogin", protoArg(&auth_proto.VerifyGoogleLoginRequest{Code: t.Token})).Return(nil, st.Err())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/google/verify", strings.NewReader(`{"code":"`+t.Token+`"}`))
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).VerifyGoogleLogin(w, req)
+
+	actual := &gateway.ErrorResponse{}
+	_ = json.NewDecoder(w.Body).Decode(actual)
+
+	assert.Equal(t.T(), http.StatusForbidden, w.Code)
+	assert.Equal(t.T(), gateway.ErrorBody{
+		Code:     http.StatusForbidden,
+		Status:   "PermissionDenied",
+		Message:  "Account is suspended",
+		Reason:   "ACCOUNT_SUSPENDED",
+		Metadata: map[string]string{"until": "2030-01-01T00:00:00Z"},
+	}, actual.Error)
+}
+
+func (t *AuthHandlerTest) TestGetGoogleLoginUrlMethodNotAllowed() {
+	srv := &mock.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/google/url", nil)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).GetGoogleLoginUrl(w, req)
+
+	assert.Equal(t.T(), http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t.T(), http.MethodGet, w.Header().Get("Allow"))
+}
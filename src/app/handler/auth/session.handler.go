@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/bookpanda/mygraderlist-auth/src/app/dto/gateway"
+	"github.com/bookpanda/mygraderlist-auth/src/app/utils"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	AccessCookie  = "mgl_access"
+	RefreshCookie = "mgl_refresh"
+	CsrfCookie    = "mgl_csrf"
+	CsrfHeader    = "X-CSRF-Token"
+
+	// the refresh cookie is only sent to the session endpoints, which redeem or revoke it
+	refreshCookiePath = "/v1/session"
+)
+
+// SessionLogin signs in with google like VerifyGoogleLogin, but keeps the credential in cookies
+// instead of returning it to the browser. It only takes a json body, which a cross-site form can't
+// send, so another site can't log the browser into an account of its choosing
+func (h *Handler) SessionLogin(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	if !requireJSON(w, r) {
+		return
+	}
+
+	in := &auth_proto.VerifyGoogleLoginRequest{}
+	if !readBody(w, r, in) {
+		return
+	}
+
+	res, err := h.service.VerifyGoogleLogin(r.Context(), in)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	h.writeSession(w, res.Credential)
+}
+
+// SessionRefresh redeems the refresh cookie for a new session
+func (h *Handler) SessionRefresh(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	if !checkCsrf(w, r) {
+		return
+	}
+
+	cookie, err := r.Cookie(RefreshCookie)
+	if err != nil || cookie.Value == "" {
+		WriteError(w, status.Error(codes.Unauthenticated, "No session"))
+		return
+	}
+
+	res, err := h.service.RefreshToken(r.Context(), &auth_proto.RefreshTokenRequest{RefreshToken: cookie.Value})
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			h.clearSession(w)
+		}
+		WriteError(w, err)
+		return
+	}
+
+	h.writeSession(w, res.Credential)
+}
+
+// SessionLogout revokes the session on the server, then clears the session cookies. The cookies are
+// kept when the revocation fails so the browser can retry
+func (h *Handler) SessionLogout(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	if !checkCsrf(w, r) {
+		return
+	}
+
+	in := &auth_proto.LogoutRequest{}
+	if cookie, err := r.Cookie(AccessCookie); err == nil {
+		in.AccessToken = cookie.Value
+	}
+	if cookie, err := r.Cookie(RefreshCookie); err == nil {
+		in.RefreshToken = cookie.Value
+	}
+
+	if _, err := h.service.Logout(r.Context(), in); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	h.clearSession(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionToken returns the access token from the session cookie. The request must carry the csrf token
+// unless it uses a safe method, since the browser attaches the cookie to cross-site requests as well
+func sessionToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(AccessCookie)
+	if err != nil || cookie.Value == "" {
+		return "", true
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !checkCsrf(w, r) {
+		return "", false
+	}
+
+	return cookie.Value, true
+}
+
+// requireJSON rejects the bodies that aren't application/json, the content types a html form can send
+// don't need a preflight
+func requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && mediaType == "application/json" {
+		return true
+	}
+
+	writeJSON(w, http.StatusUnsupportedMediaType, &gateway.ErrorResponse{Error: gateway.ErrorBody{
+		Code:    http.StatusUnsupportedMediaType,
+		Status:  codes.InvalidArgument.String(),
+		Message: "Content-Type must be application/json",
+	}})
+	return false
+}
+
+// checkCsrf verifies the double-submit token, the header can only be set by scripts able to read the csrf cookie
+func checkCsrf(w http.ResponseWriter, r *http.Request) bool {
+	cookie, err := r.Cookie(CsrfCookie)
+	header := r.Header.Get(CsrfHeader)
+
+	if err != nil || cookie.Value == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+		WriteError(w, status.Error(codes.PermissionDenied, "Invalid csrf token"))
+		return false
+	}
+
+	return true
+}
+
+func (h *Handler) writeSession(w http.ResponseWriter, credential *auth_proto.Credential) {
+	csrfToken, err := utils.GenerateRandomString(32)
+	if err != nil {
+		WriteError(w, status.Error(codes.Internal, "Internal server error"))
+		return
+	}
+
+	http.SetCookie(w, h.newCookie(AccessCookie, credential.AccessToken, "/", int(credential.ExpiresIn), true))
+	http.SetCookie(w, h.newCookie(RefreshCookie, credential.RefreshToken, refreshCookiePath, int(h.conf.RefreshExpiresIn), true))
+	http.SetCookie(w, h.newCookie(CsrfCookie, csrfToken, "/", int(h.conf.RefreshExpiresIn), false))
+
+	writeJSON(w, http.StatusOK, &gateway.SessionResponse{
+		ExpiresIn: credential.ExpiresIn,
+		CsrfToken: csrfToken,
+	})
+}
+
+func (h *Handler) clearSession(w http.ResponseWriter) {
+	http.SetCookie(w, h.newCookie(AccessCookie, "", "/", -1, true))
+	http.SetCookie(w, h.newCookie(RefreshCookie, "", refreshCookiePath, -1, true))
+	http.SetCookie(w, h.newCookie(CsrfCookie, "", "/", -1, false))
+}
+
+func (h *Handler) newCookie(name string, value string, path string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.conf.Domain,
+		MaxAge:   maxAge,
+		HttpOnly: httpOnly,
+		Secure:   !h.conf.Insecure,
+		SameSite: parseSameSite(h.conf.SameSite),
+	}
+}
+
+func parseSameSite(sameSite string) http.SameSite {
+	switch strings.ToLower(sameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bookpanda/mygraderlist-auth/src/app/dto/gateway"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	mock "github.com/bookpanda/mygraderlist-auth/src/mocks/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+	testifyMock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type SessionHandlerTest struct {
+	suite.Suite
+	Conf       config.Session
+	Credential *auth_proto.Credential
+	CsrfToken  string
+}
+
+func TestSessionHandler(t *testing.T) {
+	suite.Run(t, new(SessionHandlerTest))
+}
+
+func (t *SessionHandlerTest) SetupTest() {
+	t.Conf = config.Session{
+		Domain:           "mygraderlist.bookpanda.dev",
+		SameSite:         "strict",
+		RefreshExpiresIn: 2592000,
+	}
+
+	t.Credential = &auth_proto.Credential{
+		AccessToken:  faker.Word(),
+		RefreshToken: faker.Word(),
+		ExpiresIn:    3600,
+	}
+
+	t.CsrfToken = faker.Password()
+}
+
+func cookiesByName(res *http.Response) map[string]*http.Cookie {
+	cookies := map[string]*http.Cookie{}
+	for _, c := range res.Cookies() {
+		cookies[c.Name] = c
+	}
+
+	return cookies
+}
+
+func (t *SessionHandlerTest) TestSessionLoginSetsCookies() {
+	code := faker.Word()
+
+	srv := &mock.ServiceMock{}
+	srv.On("VerifyGoogleLogin", protoArg(&auth_proto.VerifyGoogleLoginRequest{Code: code})).Return(&auth_proto.VerifyGoogleLoginResponse{
+		Credential: t.Credential,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/session/google/verify", strings.NewReader(`{"code":"`+code+`"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).SessionLogin(w, req)
+
+	cookies := cookiesByName(w.Result())
+
+	actual := &gateway.SessionResponse{}
+	_ = json.NewDecoder(w.Body).Decode(actual)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	assert.NotContains(t.T(), w.Body.String(), t.Credential.AccessToken)
+
+	assert.Equal(t.T(), t.Credential.AccessToken, cookies[AccessCookie].Value)
+	assert.True(t.T(), cookies[AccessCookie].HttpOnly)
+	assert.True(t.T(), cookies[AccessCookie].Secure)
+	assert.Equal(t.T(), http.SameSiteStrictMode, cookies[AccessCookie].SameSite)
+	assert.Equal(t.T(), 3600, cookies[AccessCookie].MaxAge)
+
+	assert.Equal(t.T(), t.Credential.RefreshToken, cookies[RefreshCookie].Value)
+	assert.True(t.T(), cookies[RefreshCookie].HttpOnly)
+	assert.Equal(t.T(), refreshCookiePath, cookies[RefreshCookie].Path)
+
+	assert.False(t.T(), cookies[CsrfCookie].HttpOnly)
+	assert.Equal(t.T(), actual.CsrfToken, cookies[CsrfCookie].Value)
+	assert.Equal(t.T(), int32(3600), actual.ExpiresIn)
+}
+
+func (t *SessionHandlerTest) TestSessionLoginRequiresJSON() {
+	srv := &mock.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/session/google/verify", strings.NewReader(`{"code":"`+faker.Word()+`"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).SessionLogin(w, req)
+
+	assert.Equal(t.T(), http.StatusUnsupportedMediaType, w.Code)
+	assert.Empty(t.T(), w.Result().Cookies())
+	srv.AssertNotCalled(t.T(), "VerifyGoogleLogin", testifyMock.Anything)
+}
+
+func (t *SessionHandlerTest) TestSessionRefreshRequiresCsrf() {
+	srv := &mock.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/session/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: RefreshCookie, Value: t.Credential.RefreshToken})
+	req.AddCookie(&http.Cookie{Name: CsrfCookie, Value: t.CsrfToken})
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).SessionRefresh(w, req)
+
+	assert.Equal(t.T(), http.StatusForbidden, w.Code)
+	srv.AssertNotCalled(t.T(), "RefreshToken", testifyMock.Anything)
+}
+
+func (t *SessionHandlerTest) TestSessionRefreshSuccess() {
+	renewed := &auth_proto.Credential{
+		AccessToken:  faker.Word(),
+		RefreshToken: faker.Word(),
+		ExpiresIn:    3600,
+	}
+
+	srv := &mock.ServiceMock{}
+	srv.On("RefreshToken", protoArg(&auth_proto.RefreshTokenRequest{RefreshToken: t.Credential.RefreshToken})).Return(&auth_proto.RefreshTokenResponse{
+		Credential: renewed,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/session/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: RefreshCookie, Value: t.Credential.RefreshToken})
+	req.AddCookie(&http.Cookie{Name: CsrfCookie, Value: t.CsrfToken})
+	req.Header.Set(CsrfHeader, t.CsrfToken)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).SessionRefresh(w, req)
+
+	cookies := cookiesByName(w.Result())
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	assert.Equal(t.T(), renewed.AccessToken, cookies[AccessCookie].Value)
+	assert.Equal(t.T(), renewed.RefreshToken, cookies[RefreshCookie].Value)
+	assert.NotEqual(t.T(), t.CsrfToken, cookies[CsrfCookie].Value)
+}
+
+func (t *SessionHandlerTest) TestValidateFromSessionCookie() {
+	srv := &mock.ServiceMock{}
+	srv.On("Validate", protoArg(&auth_proto.ValidateRequest{Token: t.Credential.AccessToken})).Return(&auth_proto.ValidateResponse{
+		UserId: faker.UUIDDigit(),
+		Role:   "user",
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/validate", nil)
+	req.AddCookie(&http.Cookie{Name: AccessCookie, Value: t.Credential.AccessToken})
+	req.AddCookie(&http.Cookie{Name: CsrfCookie, Value: t.CsrfToken})
+	req.Header.Set(CsrfHeader, t.CsrfToken)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).Validate(w, req)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+}
+
+func (t *SessionHandlerTest) TestValidateFromSessionCookieWithoutCsrf() {
+	srv := &mock.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/validate", nil)
+	req.AddCookie(&http.Cookie{Name: AccessCookie, Value: t.Credential.AccessToken})
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).Validate(w, req)
+
+	assert.Equal(t.T(), http.StatusForbidden, w.Code)
+	srv.AssertNotCalled(t.T(), "Validate", testifyMock.Anything)
+}
+
+func (t *SessionHandlerTest) TestSessionLogoutRevokesSession() {
+	srv := &mock.ServiceMock{}
+	srv.On("Logout", protoArg(&auth_proto.LogoutRequest{
+		AccessToken:  t.Credential.AccessToken,
+		RefreshToken: t.Credential.RefreshToken,
+	})).Return(&auth_proto.LogoutResponse{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/session/logout", nil)
+	req.AddCookie(&http.Cookie{Name: AccessCookie, Value: t.Credential.AccessToken})
+	req.AddCookie(&http.Cookie{Name: RefreshCookie, Value: t.Credential.RefreshToken})
+	req.AddCookie(&http.Cookie{Name: CsrfCookie, Value: t.CsrfToken})
+	req.Header.Set(CsrfHeader, t.CsrfToken)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).SessionLogout(w, req)
+
+	cookies := cookiesByName(w.Result())
+
+	assert.Equal(t.T(), http.StatusNoContent, w.Code)
+	assert.Equal(t.T(), "", cookies[AccessCookie].Value)
+	assert.True(t.T(), cookies[AccessCookie].MaxAge < 0)
+	assert.True(t.T(), cookies[RefreshCookie].MaxAge < 0)
+	srv.AssertExpectations(t.T())
+}
+
+func (t *SessionHandlerTest) TestSessionLogoutKeepsCookiesOnFailure() {
+	srv := &mock.ServiceMock{}
+	srv.On("Logout", testifyMock.Anything).Return(nil, status.Error(codes.Unavailable, "Service is unavailable"))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/session/logout", nil)
+	req.AddCookie(&http.Cookie{Name: AccessCookie, Value: t.Credential.AccessToken})
+	req.AddCookie(&http.Cookie{Name: CsrfCookie, Value: t.CsrfToken})
+	req.Header.Set(CsrfHeader, t.CsrfToken)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).SessionLogout(w, req)
+
+	assert.Equal(t.T(), http.StatusServiceUnavailable, w.Code)
+	assert.Empty(t.T(), w.Result().Cookies())
+}
+
+func (t *SessionHandlerTest) TestSessionLogoutRequiresCsrf() {
+	srv := &mock.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/session/logout", nil)
+	req.AddCookie(&http.Cookie{Name: AccessCookie, Value: t.Credential.AccessToken})
+	req.AddCookie(&http.Cookie{Name: CsrfCookie, Value: t.CsrfToken})
+	w := httptest.NewRecorder()
+
+	NewHandler(srv, t.Conf).SessionLogout(w, req)
+
+	assert.Equal(t.T(), http.StatusForbidden, w.Code)
+	srv.AssertNotCalled(t.T(), "Logout", testifyMock.Anything)
+}
@@ -0,0 +1,42 @@
+package grpcweb
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	_grpcweb "github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+)
+
+// Handler serves gRPC-Web calls from browsers with the gRPC server and passes every other request to next
+type Handler struct {
+	wrapped *_grpcweb.WrappedGrpcServer
+	next    http.Handler
+}
+
+func NewHandler(server *grpc.Server, next http.Handler, conf config.GrpcWeb) *Handler {
+	return &Handler{
+		wrapped: _grpcweb.WrapServer(server,
+			_grpcweb.WithOriginFunc(AllowOrigin(conf.AllowedOrigins)),
+			_grpcweb.WithCorsForRegisteredEndpointsOnly(true),
+		),
+		next: next,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.wrapped.IsGrpcWebRequest(r) || h.wrapped.IsAcceptableGrpcCorsRequest(r) {
+		h.wrapped.ServeHTTP(w, r)
+		return
+	}
+
+	h.next.ServeHTTP(w, r)
+}
+
+// AllowOrigin accepts the listed origins, "*" accepts any origin
+func AllowOrigin(origins []string) func(string) bool {
+	return func(origin string) bool {
+		return slices.Contains(origins, "*") || slices.Contains(origins, origin)
+	}
+}
@@ -0,0 +1,94 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+type GrpcWebHandlerTest struct {
+	suite.Suite
+	Origin  string
+	Handler *Handler
+}
+
+func TestGrpcWebHandler(t *testing.T) {
+	suite.Run(t, new(GrpcWebHandlerTest))
+}
+
+func (t *GrpcWebHandlerTest) SetupTest() {
+	t.Origin = "https://mygraderlist.bookpanda.dev"
+
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	t.Handler = NewHandler(server, next, config.GrpcWeb{AllowedOrigins: []string{t.Origin}})
+}
+
+func (t *GrpcWebHandlerTest) TestServeGrpcWebCall() {
+	msg, _ := proto.Marshal(&grpc_health_v1.HealthCheckRequest{})
+
+	// gRPC-Web frame: compression flag, big endian length, message
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	frame = append(frame, msg...)
+
+	req := httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", bytes.NewReader(frame))
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("Origin", t.Origin)
+	w := httptest.NewRecorder()
+
+	t.Handler.ServeHTTP(w, req)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	// gRPC-Web carries the trailers in the last frame of the body
+	assert.Contains(t.T(), w.Body.String(), "grpc-status: 0")
+	assert.Equal(t.T(), t.Origin, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func (t *GrpcWebHandlerTest) TestPreflightAllowedOrigin() {
+	req := httptest.NewRequest(http.MethodOptions, "/grpc.health.v1.Health/Check", nil)
+	req.Header.Set("Origin", t.Origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+	w := httptest.NewRecorder()
+
+	t.Handler.ServeHTTP(w, req)
+
+	assert.Equal(t.T(), t.Origin, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func (t *GrpcWebHandlerTest) TestPreflightUnknownOrigin() {
+	req := httptest.NewRequest(http.MethodOptions, "/grpc.health.v1.Health/Check", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+	w := httptest.NewRecorder()
+
+	t.Handler.ServeHTTP(w, req)
+
+	assert.Empty(t.T(), w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func (t *GrpcWebHandlerTest) TestPassThroughOtherRequests() {
+	req := httptest.NewRequest(http.MethodGet, "/oidc/userinfo", nil)
+	w := httptest.NewRecorder()
+
+	t.Handler.ServeHTTP(w, req)
+
+	assert.Equal(t.T(), http.StatusTeapot, w.Code)
+}
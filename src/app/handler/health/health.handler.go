@@ -0,0 +1,62 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/health"
+)
+
+type Handler struct {
+	service IService
+}
+
+type IService interface {
+	Status() (bool, map[string]string)
+}
+
+func NewHandler(service IService) *Handler {
+	return &Handler{service: service}
+}
+
+// Liveness answers as long as the process can serve http, it doesn't depend on the dependencies
+// so an outage of mysql or redis doesn't get the pod restarted
+func (h *Handler) Liveness(w http.ResponseWriter, r *http.Request) {
+	if !allowProbe(w, r) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &dto.Response{Status: "ok"})
+}
+
+// Readiness reports whether the service can take traffic along with the state of every dependency
+func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	if !allowProbe(w, r) {
+		return
+	}
+
+	ready, checks := h.service.Status()
+	if !ready {
+		writeJSON(w, http.StatusServiceUnavailable, &dto.Response{Status: "unavailable", Checks: checks})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &dto.Response{Status: "ok", Checks: checks})
+}
+
+func allowProbe(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/health"
+	mock "github.com/bookpanda/mygraderlist-auth/src/mocks/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HealthHandlerTest struct {
+	suite.Suite
+}
+
+func TestHealthHandler(t *testing.T) {
+	suite.Run(t, new(HealthHandlerTest))
+}
+
+func (t *HealthHandlerTest) TestLivenessIgnoresDependencies() {
+	srv := &mock.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Liveness(w, req)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	srv.AssertNotCalled(t.T(), "Status")
+}
+
+func (t *HealthHandlerTest) TestReadinessReady() {
+	checks := map[string]string{"database": "ok", "cache": "ok"}
+
+	srv := &mock.ServiceMock{}
+	srv.On("Status").Return(true, checks)
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Readiness(w, req)
+
+	actual := &dto.Response{}
+	_ = json.NewDecoder(w.Body).Decode(actual)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	assert.Equal(t.T(), &dto.Response{Status: "ok", Checks: checks}, actual)
+}
+
+func (t *HealthHandlerTest) TestReadinessNotReady() {
+	checks := map[string]string{"database": "ok", "cache": "failing"}
+
+	srv := &mock.ServiceMock{}
+	srv.On("Status").Return(false, checks)
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Readiness(w, req)
+
+	actual := &dto.Response{}
+	_ = json.NewDecoder(w.Body).Decode(actual)
+
+	assert.Equal(t.T(), http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t.T(), &dto.Response{Status: "unavailable", Checks: checks}, actual)
+}
+
+func (t *HealthHandlerTest) TestReadinessMethodNotAllowed() {
+	srv := &mock.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodPost, "/readyz", nil)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Readiness(w, req)
+
+	assert.Equal(t.T(), http.StatusMethodNotAllowed, w.Code)
+}
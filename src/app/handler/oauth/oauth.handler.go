@@ -0,0 +1,156 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	oauthDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oauth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Handler struct {
+	service IService
+}
+
+type IService interface {
+	Introspect(context.Context, *auth_proto.IntrospectRequest) (*auth_proto.IntrospectResponse, error)
+	Revoke(context.Context, *auth_proto.RevokeRequest) (*auth_proto.RevokeResponse, error)
+}
+
+func NewHandler(service IService) *Handler {
+	return &Handler{service: service}
+}
+
+// Introspect implements the token introspection endpoint of RFC 7662
+func (h *Handler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "Method not allowed")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid form body")
+		return
+	}
+
+	clientID, clientSecret := clientCredentials(r)
+
+	res, err := h.service.Introspect(r.Context(), &auth_proto.IntrospectRequest{
+		Token:         r.PostForm.Get("token"),
+		TokenTypeHint: r.PostForm.Get("token_type_hint"),
+		ClientId:      clientID,
+		ClientSecret:  clientSecret,
+	})
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rawToIntrospectionDto(res))
+}
+
+// Revoke implements the token revocation endpoint of RFC 7009, which answers 200 for unknown tokens too
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "Method not allowed")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid form body")
+		return
+	}
+
+	clientID, clientSecret := clientCredentials(r)
+
+	_, err := h.service.Revoke(r.Context(), &auth_proto.RevokeRequest{
+		Token:         r.PostForm.Get("token"),
+		TokenTypeHint: r.PostForm.Get("token_type_hint"),
+		ClientId:      clientID,
+		ClientSecret:  clientSecret,
+	})
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// clientCredentials reads the client credentials from the basic authorization header,
+// falling back to the form body (RFC 6749 section 2.3.1)
+func clientCredentials(r *http.Request) (string, string) {
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		return clientID, clientSecret
+	}
+
+	return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+}
+
+func handleError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+
+	switch st.Code() {
+	case codes.Unauthenticated:
+		w.Header().Set("WWW-Authenticate", `Basic realm="mygraderlist"`)
+		writeError(w, http.StatusUnauthorized, "invalid_client", st.Message())
+	case codes.InvalidArgument:
+		writeError(w, http.StatusBadRequest, "invalid_request", st.Message())
+	case codes.Unavailable:
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusServiceUnavailable, "temporarily_unavailable", st.Message())
+	default:
+		log.Error().Err(err).
+			Str("service", "oauth").
+			Msg("Unexpected error from the auth service")
+		writeError(w, http.StatusInternalServerError, "server_error", "Internal server error")
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err string, description string) {
+	writeJSON(w, code, &oauthDto.ErrorResponse{
+		Error:            err,
+		ErrorDescription: description,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error().Err(err).
+			Str("service", "oauth").
+			Msg("Cannot write the response")
+	}
+}
+
+func rawToIntrospectionDto(in *auth_proto.IntrospectResponse) *oauthDto.IntrospectionResponse {
+	if !in.Active {
+		return &oauthDto.IntrospectionResponse{Active: false}
+	}
+
+	res := &oauthDto.IntrospectionResponse{
+		Active:   true,
+		Sub:      in.Sub,
+		Exp:      in.Exp,
+		Iat:      in.Iat,
+		Scope:    in.Scope,
+		Role:     in.Role,
+		ClientId: in.ClientId,
+	}
+	if in.ActorId != "" {
+		res.Act = &dto.ActorClaims{Sub: in.ActorId}
+	}
+
+	return res
+}
@@ -0,0 +1,168 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	oauthDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oauth"
+	"github.com/bookpanda/mygraderlist-auth/src/mocks/oauth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type OauthHandlerTest struct {
+	suite.Suite
+	ClientID     string
+	ClientSecret string
+	Token        string
+}
+
+func TestOauthHandler(t *testing.T) {
+	suite.Run(t, new(OauthHandlerTest))
+}
+
+func (t *OauthHandlerTest) SetupTest() {
+	t.ClientID = faker.Word()
+	t.ClientSecret = faker.Password()
+	t.Token = faker.Word()
+}
+
+func (t *OauthHandlerTest) newRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req
+}
+
+func (t *OauthHandlerTest) TestIntrospectActive() {
+	want := &oauthDto.IntrospectionResponse{
+		Active: true,
+		Sub:    faker.UUIDDigit(),
+		Exp:    1700000000,
+		Iat:    1699996400,
+		Role:   "user",
+	}
+
+	srv := &oauth.ServiceMock{}
+	srv.On("Introspect", &auth_proto.IntrospectRequest{
+		Token:         t.Token,
+		TokenTypeHint: "access_token",
+		ClientId:      t.ClientID,
+		ClientSecret:  t.ClientSecret,
+	}).Return(&auth_proto.IntrospectResponse{
+		Active: true,
+		Sub:    want.Sub,
+		Exp:    want.Exp,
+		Iat:    want.Iat,
+		Role:   want.Role,
+	}, nil)
+
+	req := t.newRequest(url.Values{"token": {t.Token}, "token_type_hint": {"access_token"}})
+	req.SetBasicAuth(t.ClientID, t.ClientSecret)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Introspect(w, req)
+
+	actual := &oauthDto.IntrospectionResponse{}
+	_ = json.NewDecoder(w.Body).Decode(actual)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	assert.Equal(t.T(), "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *OauthHandlerTest) TestIntrospectInactiveOmitsClaims() {
+	srv := &oauth.ServiceMock{}
+	srv.On("Introspect", &auth_proto.IntrospectRequest{
+		Token:        t.Token,
+		ClientId:     t.ClientID,
+		ClientSecret: t.ClientSecret,
+	}).Return(&auth_proto.IntrospectResponse{Active: false}, nil)
+
+	req := t.newRequest(url.Values{"token": {t.Token}, "client_id": {t.ClientID}, "client_secret": {t.ClientSecret}})
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Introspect(w, req)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	assert.JSONEq(t.T(), `{"active":false}`, w.Body.String())
+}
+
+func (t *OauthHandlerTest) TestIntrospectInvalidClient() {
+	srv := &oauth.ServiceMock{}
+	srv.On("Introspect", &auth_proto.IntrospectRequest{
+		Token:        t.Token,
+		ClientId:     t.ClientID,
+		ClientSecret: t.ClientSecret,
+	}).Return(nil, status.Error(codes.Unauthenticated, "Invalid client credentials"))
+
+	req := t.newRequest(url.Values{"token": {t.Token}})
+	req.SetBasicAuth(t.ClientID, t.ClientSecret)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Introspect(w, req)
+
+	actual := &oauthDto.ErrorResponse{}
+	_ = json.NewDecoder(w.Body).Decode(actual)
+
+	assert.Equal(t.T(), http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t.T(), w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t.T(), "invalid_client", actual.Error)
+}
+
+func (t *OauthHandlerTest) TestIntrospectMethodNotAllowed() {
+	srv := &oauth.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth/introspect", nil)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Introspect(w, req)
+
+	assert.Equal(t.T(), http.StatusMethodNotAllowed, w.Code)
+	srv.AssertNotCalled(t.T(), "Introspect")
+}
+
+func (t *OauthHandlerTest) TestRevokeSuccess() {
+	srv := &oauth.ServiceMock{}
+	srv.On("Revoke", &auth_proto.RevokeRequest{
+		Token:         t.Token,
+		TokenTypeHint: "refresh_token",
+		ClientId:      t.ClientID,
+		ClientSecret:  t.ClientSecret,
+	}).Return(&auth_proto.RevokeResponse{}, nil)
+
+	req := t.newRequest(url.Values{"token": {t.Token}, "token_type_hint": {"refresh_token"}})
+	req.SetBasicAuth(t.ClientID, t.ClientSecret)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Revoke(w, req)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	assert.Empty(t.T(), w.Body.String())
+}
+
+func (t *OauthHandlerTest) TestRevokeUnavailable() {
+	srv := &oauth.ServiceMock{}
+	srv.On("Revoke", &auth_proto.RevokeRequest{
+		Token:        t.Token,
+		ClientId:     t.ClientID,
+		ClientSecret: t.ClientSecret,
+	}).Return(nil, status.Error(codes.Unavailable, "Service is unavailable"))
+
+	req := t.newRequest(url.Values{"token": {t.Token}})
+	req.SetBasicAuth(t.ClientID, t.ClientSecret)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Revoke(w, req)
+
+	assert.Equal(t.T(), http.StatusServiceUnavailable, w.Code)
+	assert.NotEmpty(t.T(), w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,314 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	oauthDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oauth"
+	oidcDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oidc"
+	oidcSrv "github.com/bookpanda/mygraderlist-auth/src/app/service/oidc"
+	"github.com/rs/zerolog/log"
+)
+
+// requestCookie binds the pending authorization request to the browser that started it, so a request id
+// leaked through the upstream redirect or the consent form cannot be completed from another browser
+const requestCookie = "mgl_oidc_request"
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Sign in with MyGraderList</title></head>
+<body>
+<h1>{{.ClientName}} wants to access your MyGraderList account</h1>
+<ul>
+{{range .Scopes}}<li>{{.}}</li>
+{{end}}</ul>
+<form method="post" action="/oidc/consent">
+<input type="hidden" name="request_id" value="{{.RequestID}}">
+<button type="submit" name="decision" value="allow">Allow</button>
+<button type="submit" name="decision" value="deny">Deny</button>
+</form>
+</body>
+</html>
+`))
+
+type Handler struct {
+	service IService
+}
+
+type IService interface {
+	Discovery() *oidcDto.DiscoveryDocument
+	Jwks() *oidcDto.JwkSet
+	Authorize(context.Context, *oidcDto.AuthorizeRequest) (string, string, error)
+	Callback(context.Context, string, string) (*oidcDto.ConsentPrompt, string, error)
+	Consent(context.Context, string, bool) (string, error)
+	Token(context.Context, *oidcDto.TokenRequest) (*oidcDto.TokenResponse, error)
+	UserInfo(context.Context, string) (*oidcDto.UserInfoResponse, error)
+}
+
+func NewHandler(service IService) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) Discovery(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.service.Discovery(), "public, max-age=3600")
+}
+
+func (h *Handler) Jwks(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.service.Jwks(), "public, max-age=3600")
+}
+
+// Authorize starts the authorization code flow and sends the user to the google login
+func (h *Handler) Authorize(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid request")
+		return
+	}
+
+	requestID, loginUrl, err := h.service.Authorize(r.Context(), &oidcDto.AuthorizeRequest{
+		ClientID:            r.Form.Get("client_id"),
+		RedirectUri:         r.Form.Get("redirect_uri"),
+		ResponseType:        r.Form.Get("response_type"),
+		Scope:               r.Form.Get("scope"),
+		State:               r.Form.Get("state"),
+		Nonce:               r.Form.Get("nonce"),
+		Prompt:              r.Form.Get("prompt"),
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+	})
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     requestCookie,
+		Value:    requestID,
+		Path:     "/oidc",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, loginUrl, http.StatusFound)
+}
+
+// Callback receives the user back from google and either asks for consent or returns to the client
+func (h *Handler) Callback(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	requestID := r.URL.Query().Get("state")
+	if !matchRequestCookie(r, requestID) {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Authorization request was not started by this browser")
+		return
+	}
+
+	prompt, redirect, err := h.service.Callback(r.Context(), requestID, r.URL.Query().Get("code"))
+	if err != nil {
+		clearRequestCookie(w)
+		handleError(w, r, err)
+		return
+	}
+
+	if prompt == nil {
+		clearRequestCookie(w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	if err := consentTemplate.Execute(w, prompt); err != nil {
+		log.Error().Err(err).
+			Str("service", "oidc").
+			Msg("Cannot render the consent page")
+	}
+}
+
+// Consent handles the user's answer on the consent page
+func (h *Handler) Consent(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid form body")
+		return
+	}
+
+	requestID := r.PostForm.Get("request_id")
+	if !matchRequestCookie(r, requestID) {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Authorization request was not started by this browser")
+		return
+	}
+
+	clearRequestCookie(w)
+
+	redirect, err := h.service.Consent(r.Context(), requestID, r.PostForm.Get("decision") == "allow")
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// Token implements the token endpoint for the authorization code grant
+func (h *Handler) Token(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid form body")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	res, err := h.service.Token(r.Context(), &oidcDto.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectUri:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	})
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res, "no-store")
+}
+
+func (h *Handler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mygraderlist"`)
+		writeError(w, http.StatusUnauthorized, "invalid_token", "No access token is provided")
+		return
+	}
+
+	res, err := h.service.UserInfo(r.Context(), token)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res, "no-store")
+}
+
+func matchRequestCookie(r *http.Request, requestID string) bool {
+	cookie, err := r.Cookie(requestCookie)
+	if err != nil || requestID == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(requestID)) == 1
+}
+
+func clearRequestCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     requestCookie,
+		Path:     "/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "invalid_request", "Method not allowed")
+	return false
+}
+
+func handleError(w http.ResponseWriter, r *http.Request, err error) {
+	var oidcErr *oidcSrv.Error
+	if !errors.As(err, &oidcErr) {
+		log.Error().Err(err).
+			Str("service", "oidc").
+			Msg("Unexpected error from the oidc service")
+		writeError(w, http.StatusInternalServerError, "server_error", "Internal server error")
+		return
+	}
+
+	if oidcErr.RedirectUri != "" {
+		params := url.Values{
+			"error":             {oidcErr.Code},
+			"error_description": {oidcErr.Description},
+		}
+		if oidcErr.State != "" {
+			params.Set("state", oidcErr.State)
+		}
+
+		http.Redirect(w, r, oidcSrv.AppendQuery(oidcErr.RedirectUri, params), http.StatusFound)
+		return
+	}
+
+	switch oidcErr.Code {
+	case "invalid_client":
+		w.Header().Set("WWW-Authenticate", `Basic realm="mygraderlist"`)
+		writeError(w, http.StatusUnauthorized, oidcErr.Code, oidcErr.Description)
+	case "invalid_token":
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mygraderlist", error="invalid_token"`)
+		writeError(w, http.StatusUnauthorized, oidcErr.Code, oidcErr.Description)
+	case "server_error":
+		writeError(w, http.StatusInternalServerError, oidcErr.Code, oidcErr.Description)
+	default:
+		writeError(w, http.StatusBadRequest, oidcErr.Code, oidcErr.Description)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err string, description string) {
+	writeJSON(w, code, &oauthDto.ErrorResponse{
+		Error:            err,
+		ErrorDescription: description,
+	}, "no-store")
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}, cacheControl string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error().Err(err).
+			Str("service", "oidc").
+			Msg("Cannot write the response")
+	}
+}
@@ -0,0 +1,170 @@
+package oidc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	oauthDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oauth"
+	oidcDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oidc"
+	oidcSrv "github.com/bookpanda/mygraderlist-auth/src/app/service/oidc"
+	"github.com/bookpanda/mygraderlist-auth/src/mocks/oidc"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type OidcHandlerTest struct {
+	suite.Suite
+	ClientID    string
+	RedirectUri string
+	RequestID   string
+}
+
+func TestOidcHandler(t *testing.T) {
+	suite.Run(t, new(OidcHandlerTest))
+}
+
+func (t *OidcHandlerTest) SetupTest() {
+	t.ClientID = "mgl-forum"
+	t.RedirectUri = "https://forum.mygraderlist.bookpanda.dev/auth/callback"
+	t.RequestID = faker.Password()
+}
+
+func (t *OidcHandlerTest) TestAuthorizeRedirectsToGoogle() {
+	loginUrl := "https://accounts.google.com/o/oauth2/auth?state=" + url.QueryEscape(t.RequestID)
+
+	srv := &oidc.ServiceMock{}
+	srv.On("Authorize", &oidcDto.AuthorizeRequest{
+		ClientID:            t.ClientID,
+		RedirectUri:         t.RedirectUri,
+		ResponseType:        "code",
+		Scope:               "openid",
+		CodeChallenge:       "challenge",
+		CodeChallengeMethod: "S256",
+	}).Return(t.RequestID, loginUrl, nil)
+
+	query := url.Values{
+		"client_id":             {t.ClientID},
+		"redirect_uri":          {t.RedirectUri},
+		"response_type":         {"code"},
+		"scope":                 {"openid"},
+		"code_challenge":        {"challenge"},
+		"code_challenge_method": {"S256"},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/oidc/authorize?"+query.Encode(), nil)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Authorize(w, req)
+
+	cookies := w.Result().Cookies()
+
+	assert.Equal(t.T(), http.StatusFound, w.Code)
+	assert.Equal(t.T(), loginUrl, w.Header().Get("Location"))
+	assert.Len(t.T(), cookies, 1)
+	assert.Equal(t.T(), t.RequestID, cookies[0].Value)
+	assert.True(t.T(), cookies[0].HttpOnly)
+}
+
+func (t *OidcHandlerTest) TestAuthorizeErrorRedirectsToClient() {
+	srv := &oidc.ServiceMock{}
+	srv.On("Authorize", &oidcDto.AuthorizeRequest{
+		ClientID:     t.ClientID,
+		RedirectUri:  t.RedirectUri,
+		ResponseType: "token",
+		State:        "xyz",
+	}).Return("", "", &oidcSrv.Error{
+		Code:        "unsupported_response_type",
+		Description: "Only the code response type is supported",
+		RedirectUri: t.RedirectUri,
+		State:       "xyz",
+	})
+
+	query := url.Values{
+		"client_id":     {t.ClientID},
+		"redirect_uri":  {t.RedirectUri},
+		"response_type": {"token"},
+		"state":         {"xyz"},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/oidc/authorize?"+query.Encode(), nil)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Authorize(w, req)
+
+	location, _ := url.Parse(w.Header().Get("Location"))
+
+	assert.Equal(t.T(), http.StatusFound, w.Code)
+	assert.Equal(t.T(), "unsupported_response_type", location.Query().Get("error"))
+	assert.Equal(t.T(), "xyz", location.Query().Get("state"))
+}
+
+func (t *OidcHandlerTest) TestCallbackRejectsForeignBrowser() {
+	srv := &oidc.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodGet, "/oidc/callback?code=abc&state="+url.QueryEscape(t.RequestID), nil)
+	req.AddCookie(&http.Cookie{Name: requestCookie, Value: faker.Password()})
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Callback(w, req)
+
+	assert.Equal(t.T(), http.StatusBadRequest, w.Code)
+	srv.AssertNotCalled(t.T(), "Callback", t.RequestID, "abc")
+}
+
+func (t *OidcHandlerTest) TestCallbackRendersConsent() {
+	srv := &oidc.ServiceMock{}
+	srv.On("Callback", t.RequestID, "abc").Return(&oidcDto.ConsentPrompt{
+		RequestID:  t.RequestID,
+		ClientName: "<MyGraderList Forum>",
+		Scopes:     []string{"openid", "email"},
+	}, "", nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/oidc/callback?code=abc&state="+url.QueryEscape(t.RequestID), nil)
+	req.AddCookie(&http.Cookie{Name: requestCookie, Value: t.RequestID})
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Callback(w, req)
+
+	assert.Equal(t.T(), http.StatusOK, w.Code)
+	assert.Contains(t.T(), w.Body.String(), "&lt;MyGraderList Forum&gt;")
+	assert.Contains(t.T(), w.Body.String(), `action="/oidc/consent"`)
+}
+
+func (t *OidcHandlerTest) TestTokenInvalidClient() {
+	srv := &oidc.ServiceMock{}
+	srv.On("Token", &oidcDto.TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         "abc",
+		ClientID:     t.ClientID,
+		ClientSecret: "wrong",
+	}).Return(nil, &oidcSrv.Error{Code: "invalid_client", Description: "Invalid client credentials"})
+
+	form := url.Values{"grant_type": {"authorization_code"}, "code": {"abc"}}
+	req := httptest.NewRequest(http.MethodPost, "/oidc/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.ClientID, "wrong")
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).Token(w, req)
+
+	actual := &oauthDto.ErrorResponse{}
+	_ = json.NewDecoder(w.Body).Decode(actual)
+
+	assert.Equal(t.T(), http.StatusUnauthorized, w.Code)
+	assert.Equal(t.T(), "invalid_client", actual.Error)
+}
+
+func (t *OidcHandlerTest) TestUserInfoWithoutToken() {
+	srv := &oidc.ServiceMock{}
+
+	req := httptest.NewRequest(http.MethodGet, "/oidc/userinfo", nil)
+	w := httptest.NewRecorder()
+
+	NewHandler(srv).UserInfo(w, req)
+
+	assert.Equal(t.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(t.T(), w.Header().Get("WWW-Authenticate"), "Bearer")
+}
@@ -0,0 +1,161 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bookpanda/mygraderlist-auth/src/app/metrics"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type InterceptorTest struct {
+	suite.Suite
+	Info *grpc.UnaryServerInfo
+}
+
+func TestInterceptor(t *testing.T) {
+	suite.Run(t, new(InterceptorTest))
+}
+
+func (t *InterceptorTest) SetupTest() {
+	t.Info = &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/Validate"}
+}
+
+func (t *InterceptorTest) TestUnaryRecoveryTurnsPanicIntoInternal() {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		var payload map[string]interface{}
+		return payload["user_id"].(string), nil
+	}
+
+	actual, err := UnaryRecovery()(context.Background(), nil, t.Info, handler)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Internal, status.Code(err))
+}
+
+func (t *InterceptorTest) TestUnaryRecoveryKeepsHandlerError() {
+	want := status.Error(codes.Unauthenticated, "Invalid token")
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, want
+	}
+
+	_, err := UnaryRecovery()(context.Background(), nil, t.Info, handler)
+
+	assert.Equal(t.T(), want, err)
+}
+
+func (t *InterceptorTest) TestUnaryRequestIDFromMetadata() {
+	requestID := uuid.NewString()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDKey, requestID))
+
+	var actual string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		actual = RequestIDFromContext(ctx)
+		return nil, nil
+	}
+
+	_, _ = UnaryRequestID()(ctx, nil, t.Info, handler)
+
+	assert.Equal(t.T(), requestID, actual)
+}
+
+func (t *InterceptorTest) TestUnaryRequestIDGenerated() {
+	var actual string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		actual = RequestIDFromContext(ctx)
+		return nil, nil
+	}
+
+	_, _ = UnaryRequestID()(context.Background(), nil, t.Info, handler)
+
+	_, err := uuid.Parse(actual)
+	assert.Nilf(t.T(), err, "error: %v", err)
+}
+
+func (t *InterceptorTest) TestUnaryRequestIDLogger() {
+	requestID := uuid.NewString()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDKey, requestID))
+
+	var buf bytes.Buffer
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		logger := zerolog.Ctx(ctx).Output(&buf)
+		logger.Info().Msg("handled")
+		return nil, nil
+	}
+
+	_, _ = UnaryRequestID()(ctx, nil, t.Info, handler)
+
+	assert.Contains(t.T(), buf.String(), `"request_id":"`+requestID+`"`)
+}
+
+func (t *InterceptorTest) TestUnaryClientRequestIDForwarded() {
+	requestID := uuid.NewString()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDKey, requestID))
+
+	var actual []string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		actual = md.Get(RequestIDKey)
+		return nil
+	}
+
+	err := UnaryClientRequestID()(ctx, "/user.UserService/FindByEmail", nil, nil, nil, invoker)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), []string{requestID}, actual)
+}
+
+func (t *InterceptorTest) TestUnaryClientRequestIDWithoutIncomingCall() {
+	var actual []string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		actual = md.Get(RequestIDKey)
+		return nil
+	}
+
+	err := UnaryClientRequestID()(context.Background(), "/user.UserService/FindByEmail", nil, nil, nil, invoker)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Empty(t.T(), actual)
+}
+
+func (t *InterceptorTest) TestUnaryChainLogsRecoveredPanic() {
+	chain := func(ctx context.Context, handler grpc.UnaryHandler) error {
+		_, err := UnaryRequestID()(ctx, nil, t.Info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return UnaryAccessLog()(ctx, req, t.Info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return UnaryRecovery()(ctx, req, t.Info, handler)
+			})
+		})
+		return err
+	}
+
+	err := chain(context.Background(), func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic(errors.New("boom"))
+	})
+
+	assert.Equal(t.T(), codes.Internal, status.Code(err))
+}
+
+func (t *InterceptorTest) TestUnaryMetricsCountsStatusCode() {
+	counter := metrics.GrpcHandled.WithLabelValues("auth.AuthService", "Validate", codes.Unauthenticated.String())
+	before := testutil.ToFloat64(counter)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	_, _ = UnaryMetrics()(context.Background(), nil, t.Info, handler)
+
+	assert.Equal(t.T(), before+1, testutil.ToFloat64(counter))
+}
@@ -0,0 +1,57 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryAccessLog logs every call with its method, duration, status code and peer
+func UnaryAccessLog() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		res, err := handler(ctx, req)
+
+		accessLog(ctx, info.FullMethod, start, err)
+
+		return res, err
+	}
+}
+
+func StreamAccessLog() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+
+		err := handler(srv, ss)
+
+		accessLog(ss.Context(), info.FullMethod, start, err)
+
+		return err
+	}
+}
+
+func accessLog(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	event := loggerFromContext(ctx).Info()
+	switch code {
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented, codes.DeadlineExceeded:
+		event = loggerFromContext(ctx).Error().Err(err)
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		event = event.Str("peer", p.Addr.String())
+	}
+
+	event.
+		Str("service", "grpc").
+		Str("method", method).
+		Str("code", code.String()).
+		Dur("duration", time.Since(start)).
+		Msg("Handled call")
+}
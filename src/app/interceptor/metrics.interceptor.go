@@ -0,0 +1,53 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/bookpanda/mygraderlist-auth/src/app/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryMetrics records the status code and latency of every call
+func UnaryMetrics() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		res, err := handler(ctx, req)
+
+		observe(info.FullMethod, start, err)
+
+		return res, err
+	}
+}
+
+func StreamMetrics() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+
+		err := handler(srv, ss)
+
+		observe(info.FullMethod, start, err)
+
+		return err
+	}
+}
+
+func observe(fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+
+	metrics.GrpcHandled.WithLabelValues(service, method, status.Code(err).String()).Inc()
+	metrics.GrpcHandlingDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+}
+
+// splitMethod splits "/auth.AuthService/Validate" into the service and the method name
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", "unknown"
+	}
+
+	return service, method
+}
@@ -0,0 +1,99 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const RequestIDKey = "x-request-id"
+
+// maxRequestIDLength bounds the id taken from the caller, since it ends up in every log line of the call
+const maxRequestIDLength = 128
+
+// UnaryRequestID reads the request id from the metadata, or generates one, echoes it back in the
+// header and attaches it to the zerolog logger of the call context
+func UnaryRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+func StreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+	}
+}
+
+// UnaryClientRequestID forwards the request id of the incoming call to the calls made while serving it,
+// so the logs of the backend can be joined with ours
+func UnaryClientRequestID() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withOutgoingRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDKey); len(values) > 0 && values[0] != "" && len(values[0]) <= maxRequestIDLength {
+			return values[0]
+		}
+	}
+
+	return ""
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = uuid.NewString()
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		md = md.Copy()
+		md.Set(RequestIDKey, requestID)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, requestID))
+
+	logger := log.With().Str("request_id", requestID).Logger()
+
+	return logger.WithContext(ctx)
+}
+
+func withOutgoingRequestID(ctx context.Context) context.Context {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		return ctx
+	}
+
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(RequestIDKey)) > 0 {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, RequestIDKey, requestID)
+}
+
+// loggerFromContext returns the logger attached by the request id interceptor, or the global logger
+func loggerFromContext(ctx context.Context) *zerolog.Logger {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		return &log.Logger
+	}
+
+	return logger
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "mgl_auth"
+
+var (
+	// Logins counts sign in attempts by identity provider and outcome
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Sign in attempts by provider and outcome.",
+	}, []string{"provider", "outcome"})
+
+	// Validations counts access token validations by result, ok for a valid token and degraded for a
+	// token accepted from its claims alone while the cache is unreachable
+	Validations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validations_total",
+		Help:      "Access token validations by result.",
+	}, []string{"reason"})
+
+	// LocalValidations counts the lookups of the in-process validation cache by result, hit or miss
+	LocalValidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "local_validations_total",
+		Help:      "Lookups of the in-process validation cache by result.",
+	}, []string{"result"})
+
+	// RefreshRotations counts refresh token redemptions by outcome
+	RefreshRotations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refresh_rotations_total",
+		Help:      "Refresh token rotations by outcome.",
+	}, []string{"outcome"})
+
+	CacheDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cache_duration_seconds",
+		Help:      "Latency of cache operations by operation and result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "result"})
+
+	// LegacyCacheReads counts the reads served from a key of the schema before the namespaced keys,
+	// once it stays at zero the fallback can be turned off
+	LegacyCacheReads = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_legacy_reads_total",
+		Help:      "Cache reads served from a legacy key.",
+	})
+
+	// CacheBreakerOpen is 1 while the circuit breaker around the cache is open
+	CacheBreakerOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_breaker_open",
+		Help:      "Whether the cache circuit breaker is open.",
+	})
+
+	BackendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "backend_duration_seconds",
+		Help:      "Latency of calls to the backend service by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	GrpcHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "RPCs completed on the server by service, method and status code.",
+	}, []string{"grpc_service", "grpc_method", "grpc_code"})
+
+	GrpcHandlingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Latency of RPCs handled by the server.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"grpc_service", "grpc_method"})
+)
+
+// Outcome labels the result of a call from its grpc status
+func Outcome(err error) string {
+	switch status.Code(err) {
+	case codes.OK:
+		return "success"
+	case codes.InvalidArgument:
+		return "invalid"
+	case codes.Unauthenticated:
+		return "unauthenticated"
+	case codes.PermissionDenied:
+		return "denied"
+	case codes.NotFound:
+		return "not_found"
+	case codes.Unavailable:
+		return "unavailable"
+	default:
+		return "error"
+	}
+}
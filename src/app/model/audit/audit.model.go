@@ -0,0 +1,11 @@
+package audit
+
+import "github.com/bookpanda/mygraderlist-auth/src/app/model"
+
+type Audit struct {
+	model.Base
+	ActorID  string `json:"actor_id" gorm:"index"`
+	TargetID string `json:"target_id" gorm:"index"`
+	Action   string `json:"action"`
+	Detail   string `json:"detail"`
+}
@@ -1,10 +1,26 @@
 package auth
 
-import "github.com/bookpanda/mygraderlist-auth/src/app/model"
+import (
+	"time"
+
+	"github.com/bookpanda/mygraderlist-auth/src/app/model"
+)
 
 type Auth struct {
 	model.Base
-	UserID       string `json:"user_id" gorm:"index:,unique"`
-	Role         string `json:"role" gorm:"type:tinytext"`
+	UserID         string     `json:"user_id" gorm:"index:,unique"`
+	Role           string     `json:"role"`
+	RefreshToken   string     `json:"refresh_token" gorm:"index"`
+	Status         string     `json:"status"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+	StatusReason   string     `json:"status_reason"`
+}
+
+// DeviceSession is a sign in through the device authorization grant, it's kept apart from the session
+// of the account so the devices and the browser don't sign each other out
+type DeviceSession struct {
+	model.Base
+	UserID       string `json:"user_id" gorm:"index"`
+	ClientID     string `json:"client_id"`
 	RefreshToken string `json:"refresh_token" gorm:"index"`
 }
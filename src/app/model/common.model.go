@@ -8,9 +8,9 @@ import (
 
 type Base struct {
 	ID        uuid.UUID      `json:"id" gorm:"primary_key"`
-	CreatedAt time.Time      `json:"created_at" gorm:"type:timestamp;autoCreateTime:nano"`
-	UpdatedAt time.Time      `json:"updated_at" gorm:"type:timestamp;autoUpdateTime:nano"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index;type:timestamp"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime:nano"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime:nano"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
 func (b *Base) BeforeCreate(_ *gorm.DB) error {
@@ -0,0 +1,11 @@
+package consent
+
+import "github.com/bookpanda/mygraderlist-auth/src/app/model"
+
+// Consent records the scopes a user has allowed a client to access
+type Consent struct {
+	model.Base
+	UserID   string `json:"user_id" gorm:"index"`
+	ClientID string `json:"client_id" gorm:"index"`
+	Scopes   string `json:"scopes"`
+}
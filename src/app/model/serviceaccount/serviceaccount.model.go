@@ -0,0 +1,24 @@
+package serviceaccount
+
+import (
+	"time"
+
+	"github.com/bookpanda/mygraderlist-auth/src/app/model"
+)
+
+type ServiceAccount struct {
+	model.Base
+	Name        string `json:"name" gorm:"index:,unique"`
+	Description string `json:"description"`
+	Role        string `json:"role"`
+}
+
+type ApiKey struct {
+	model.Base
+	ServiceAccountID string     `json:"service_account_id" gorm:"index"`
+	Prefix           string     `json:"prefix" gorm:"index:,unique"`
+	Secret           string     `json:"secret"`
+	Scopes           string     `json:"scopes"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+}
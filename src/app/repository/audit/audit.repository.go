@@ -0,0 +1,20 @@
+package audit
+
+import (
+	"context"
+
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/audit"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(ctx context.Context, audit *model.Audit) error {
+	return r.db.WithContext(ctx).Create(&audit).Error
+}
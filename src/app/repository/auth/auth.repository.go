@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"context"
+
 	model "github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
 	"gorm.io/gorm"
 )
@@ -13,18 +15,81 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) FindByUserID(uid string, result *model.Auth) error {
-	return r.db.First(&result, "user_id = ?", uid).Error
+func (r *Repository) FindByUserID(ctx context.Context, uid string, result *model.Auth) error {
+	return r.db.WithContext(ctx).First(&result, "user_id = ?", uid).Error
+}
+
+func (r *Repository) FindByRefreshToken(ctx context.Context, refreshToken string, result *model.Auth) error {
+	return r.db.WithContext(ctx).First(&result, "refresh_token = ?", refreshToken).Error
+}
+
+func (r *Repository) Create(ctx context.Context, auth *model.Auth) error {
+	return r.db.WithContext(ctx).Create(&auth).Error
+}
+
+func (r *Repository) Update(ctx context.Context, id string, auth *model.Auth) error {
+	return r.db.WithContext(ctx).Where(id, "id = ?", id).Updates(&auth).First(&auth, "id = ?", id).Error
+}
+
+func (r *Repository) UpdateStatus(ctx context.Context, id string, auth *model.Auth) error {
+	err := r.db.WithContext(ctx).Model(&model.Auth{}).
+		Where("id = ?", id).
+		Select("status", "suspended_until", "status_reason", "refresh_token").
+		Updates(&auth).Error
+	if err != nil {
+		return err
+	}
+
+	return r.db.WithContext(ctx).First(&auth, "id = ?", id).Error
+}
+
+func (r *Repository) UpdateRole(ctx context.Context, id string, auth *model.Auth) error {
+	err := r.db.WithContext(ctx).Model(&model.Auth{}).
+		Where("id = ?", id).
+		Select("role").
+		Updates(&auth).Error
+	if err != nil {
+		return err
+	}
+
+	return r.db.WithContext(ctx).First(&auth, "id = ?", id).Error
+}
+
+func (r *Repository) ClearRefreshToken(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&model.Auth{}).Where("id = ?", id).Update("refresh_token", "").Error
+}
+
+func (r *Repository) CreateDeviceSession(ctx context.Context, session *model.DeviceSession) error {
+	return r.db.WithContext(ctx).Create(&session).Error
+}
+
+func (r *Repository) FindDeviceSession(ctx context.Context, id string, result *model.DeviceSession) error {
+	return r.db.WithContext(ctx).First(&result, "id = ?", id).Error
+}
+
+func (r *Repository) FindDeviceSessionByRefreshToken(ctx context.Context, refreshToken string, result *model.DeviceSession) error {
+	return r.db.WithContext(ctx).First(&result, "refresh_token = ?", refreshToken).Error
 }
 
-func (r *Repository) FindByRefreshToken(refreshToken string, result *model.Auth) error {
-	return r.db.First(&result, "refresh_token = ?", refreshToken).Error
+func (r *Repository) UpdateDeviceSessionRefreshToken(ctx context.Context, id string, refreshToken string) error {
+	return r.db.WithContext(ctx).Model(&model.DeviceSession{}).Where("id = ?", id).Update("refresh_token", refreshToken).Error
 }
 
-func (r *Repository) Create(auth *model.Auth) error {
-	return r.db.Create(&auth).Error
+func (r *Repository) DeleteDeviceSession(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.DeviceSession{}).Error
 }
 
-func (r *Repository) Update(id string, auth *model.Auth) error {
-	return r.db.Where(id, "id = ?", id).Updates(&auth).First(&auth, "id = ?", id).Error
+func (r *Repository) FindDeviceSessions(ctx context.Context, userID string, result *[]*model.DeviceSession) error {
+	return r.db.WithContext(ctx).Find(&result, "user_id = ?", userID).Error
+}
+
+// DeleteDeviceSessions deletes every device session of the user and returns them in the result
+func (r *Repository) DeleteDeviceSessions(ctx context.Context, userID string, result *[]*model.DeviceSession) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Find(&result, "user_id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", userID).Delete(&model.DeviceSession{}).Error
+	})
 }
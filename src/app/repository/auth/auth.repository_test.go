@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/database"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type AuthRepositoryTest struct {
+	suite.Suite
+	db   *gorm.DB
+	Repo *Repository
+	Auth *model.Auth
+}
+
+func TestAuthRepository(t *testing.T) {
+	suite.Run(t, new(AuthRepositoryTest))
+}
+
+func (t *AuthRepositoryTest) SetupTest() {
+	db, err := database.InitDatabase(&config.Database{Driver: database.SQLite, Name: ":memory:"})
+	t.Require().Nil(err)
+
+	m, err := database.NewMigrator(db)
+	t.Require().Nil(err)
+
+	_, err = m.Up()
+	t.Require().Nil(err)
+
+	t.db = db
+	t.Repo = NewRepository(db)
+	t.Auth = &model.Auth{
+		UserID:       faker.UUIDDigit(),
+		Role:         string(auth.USER),
+		RefreshToken: faker.Word(),
+		Status:       string(auth.ACTIVE),
+	}
+
+	t.Require().Nil(t.Repo.Create(context.Background(), t.Auth))
+}
+
+func (t *AuthRepositoryTest) TearDownTest() {
+	sqlDb, _ := t.db.DB()
+	_ = sqlDb.Close()
+}
+
+func (t *AuthRepositoryTest) TestFindByUserID() {
+	actual := model.Auth{}
+
+	err := t.Repo.FindByUserID(context.Background(), t.Auth.UserID, &actual)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Auth.ID, actual.ID)
+	assert.Equal(t.T(), t.Auth.RefreshToken, actual.RefreshToken)
+}
+
+func (t *AuthRepositoryTest) TestFindByUserIDNotFound() {
+	actual := model.Auth{}
+
+	err := t.Repo.FindByUserID(context.Background(), faker.UUIDDigit(), &actual)
+
+	assert.Equal(t.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (t *AuthRepositoryTest) TestCreateDuplicateUserID() {
+	err := t.Repo.Create(context.Background(), &model.Auth{UserID: t.Auth.UserID, Role: string(auth.USER)})
+
+	assert.NotNil(t.T(), err)
+}
+
+func (t *AuthRepositoryTest) TestFindByRefreshToken() {
+	actual := model.Auth{}
+
+	err := t.Repo.FindByRefreshToken(context.Background(), t.Auth.RefreshToken, &actual)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Auth.UserID, actual.UserID)
+}
+
+func (t *AuthRepositoryTest) TestUpdate() {
+	refreshToken := faker.Word()
+
+	in := &model.Auth{RefreshToken: refreshToken}
+	in.ID = t.Auth.ID
+
+	err := t.Repo.Update(context.Background(), t.Auth.ID.String(), in)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Auth.UserID, in.UserID)
+	assert.Equal(t.T(), refreshToken, in.RefreshToken)
+}
+
+func (t *AuthRepositoryTest) TestUpdateStatus() {
+	until := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	in := &model.Auth{
+		Status:         string(auth.SUSPENDED),
+		SuspendedUntil: &until,
+		StatusReason:   faker.Sentence(),
+	}
+
+	err := t.Repo.UpdateStatus(context.Background(), t.Auth.ID.String(), in)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), string(auth.SUSPENDED), in.Status)
+	assert.True(t.T(), until.Equal(*in.SuspendedUntil))
+	assert.Equal(t.T(), "", in.RefreshToken)
+	assert.Equal(t.T(), t.Auth.UserID, in.UserID)
+}
+
+func (t *AuthRepositoryTest) TestUpdateRole() {
+	in := &model.Auth{Role: string(auth.ADMIN)}
+
+	err := t.Repo.UpdateRole(context.Background(), t.Auth.ID.String(), in)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), string(auth.ADMIN), in.Role)
+	assert.Equal(t.T(), t.Auth.RefreshToken, in.RefreshToken)
+	assert.Equal(t.T(), t.Auth.UserID, in.UserID)
+}
+
+func (t *AuthRepositoryTest) TestClearRefreshToken() {
+	err := t.Repo.ClearRefreshToken(context.Background(), t.Auth.ID.String())
+	assert.Nil(t.T(), err)
+
+	actual := model.Auth{}
+	err = t.Repo.FindByRefreshToken(context.Background(), t.Auth.RefreshToken, &actual)
+
+	assert.Equal(t.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (t *AuthRepositoryTest) TestDeviceSessions() {
+	session := &model.DeviceSession{UserID: t.Auth.UserID, ClientID: faker.Word()}
+	t.Require().Nil(t.Repo.CreateDeviceSession(context.Background(), session))
+
+	refreshToken := faker.Word()
+	err := t.Repo.UpdateDeviceSessionRefreshToken(context.Background(), session.ID.String(), refreshToken)
+	assert.Nil(t.T(), err)
+
+	actual := model.DeviceSession{}
+	err = t.Repo.FindDeviceSessionByRefreshToken(context.Background(), refreshToken, &actual)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), session.ID, actual.ID)
+
+	// the session of the account is left untouched
+	auth := model.Auth{}
+	assert.Nil(t.T(), t.Repo.FindByRefreshToken(context.Background(), t.Auth.RefreshToken, &auth))
+
+	var found []*model.DeviceSession
+	assert.Nil(t.T(), t.Repo.FindDeviceSessions(context.Background(), t.Auth.UserID, &found))
+	assert.Len(t.T(), found, 1)
+
+	var deleted []*model.DeviceSession
+	err = t.Repo.DeleteDeviceSessions(context.Background(), t.Auth.UserID, &deleted)
+
+	assert.Nil(t.T(), err)
+	assert.Len(t.T(), deleted, 1)
+	assert.Equal(t.T(), gorm.ErrRecordNotFound, t.Repo.FindDeviceSessionByRefreshToken(context.Background(), refreshToken, &model.DeviceSession{}))
+}
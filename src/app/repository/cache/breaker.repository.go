@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bookpanda/mygraderlist-auth/src/app/metrics"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 10 * time.Second
+)
+
+var ErrCircuitOpen = errors.New("Cache circuit breaker is open")
+
+type IRepository interface {
+	SaveCache(context.Context, string, interface{}, int) error
+	GetCache(context.Context, string, interface{}) error
+	TakeCache(context.Context, string, interface{}) error
+	GetCaches(context.Context, []string, []interface{}) ([]error, error)
+	RemoveCache(context.Context, string) error
+}
+
+// BreakerRepository stops calling the cache after consecutive failures and returns ErrCircuitOpen
+// instead, so an outage fails fast rather than on every timeout. Once the cooldown has passed a
+// single call goes through, its success closes the circuit and its failure opens it again.
+type BreakerRepository struct {
+	cache     IRepository
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func NewBreakerRepository(cache IRepository, conf config.Cache) *BreakerRepository {
+	threshold := conf.BreakerThreshold
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+
+	cooldown := time.Duration(conf.BreakerCooldown) * time.Second
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+
+	return &BreakerRepository{
+		cache:     cache,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+func (r *BreakerRepository) SaveCache(ctx context.Context, key string, value interface{}, ttl int) error {
+	return r.call(ctx, func() error {
+		return r.cache.SaveCache(ctx, key, value, ttl)
+	})
+}
+
+func (r *BreakerRepository) GetCache(ctx context.Context, key string, value interface{}) error {
+	return r.call(ctx, func() error {
+		return r.cache.GetCache(ctx, key, value)
+	})
+}
+
+func (r *BreakerRepository) TakeCache(ctx context.Context, key string, value interface{}) error {
+	return r.call(ctx, func() error {
+		return r.cache.TakeCache(ctx, key, value)
+	})
+}
+
+func (r *BreakerRepository) GetCaches(ctx context.Context, keys []string, values []interface{}) (errs []error, err error) {
+	err = r.call(ctx, func() error {
+		errs, err = r.cache.GetCaches(ctx, keys, values)
+		return err
+	})
+
+	return
+}
+
+func (r *BreakerRepository) RemoveCache(ctx context.Context, key string) error {
+	return r.call(ctx, func() error {
+		return r.cache.RemoveCache(ctx, key)
+	})
+}
+
+func (r *BreakerRepository) call(ctx context.Context, fn func() error) error {
+	if !r.allow() {
+		return ErrCircuitOpen
+	}
+
+	err := fn()
+	// a call given up by the caller says nothing about the cache
+	if ctx.Err() != nil {
+		return err
+	}
+	r.record(err)
+
+	return err
+}
+
+func (r *BreakerRepository) allow() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures < r.threshold {
+		return true
+	}
+
+	now := r.now()
+	if now.Before(r.openUntil) {
+		return false
+	}
+
+	// let a single trial call through, the others keep failing fast until it returns
+	r.openUntil = now.Add(r.cooldown)
+	return true
+}
+
+// record counts the failures, a missing key means the cache answered
+func (r *BreakerRepository) record(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil || err == redis.Nil {
+		if r.failures >= r.threshold {
+			log.Info().
+				Str("service", "cache").
+				Msg("Cache circuit breaker closed")
+			metrics.CacheBreakerOpen.Set(0)
+		}
+		r.failures = 0
+		return
+	}
+
+	r.failures++
+	if r.failures == r.threshold {
+		r.openUntil = r.now().Add(r.cooldown)
+
+		log.Warn().
+			Err(err).
+			Str("service", "cache").
+			Msgf("Cache circuit breaker opened for %v", r.cooldown)
+		metrics.CacheBreakerOpen.Set(1)
+	}
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	mock "github.com/bookpanda/mygraderlist-auth/src/mocks/cache"
+	"github.com/bxcodec/faker/v3"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type BreakerRepositoryTest struct {
+	suite.Suite
+	Now  time.Time
+	Key  string
+	Conf config.Cache
+}
+
+func TestBreakerRepository(t *testing.T) {
+	suite.Run(t, new(BreakerRepositoryTest))
+}
+
+func (t *BreakerRepositoryTest) SetupTest() {
+	t.Now = time.Now()
+	t.Key = "session:" + faker.UUIDDigit()
+	t.Conf = config.Cache{BreakerThreshold: 2, BreakerCooldown: 10}
+}
+
+func (t *BreakerRepositoryTest) newRepository(cache IRepository) *BreakerRepository {
+	repo := NewBreakerRepository(cache, t.Conf)
+	repo.now = func() time.Time {
+		return t.Now
+	}
+
+	return repo
+}
+
+func (t *BreakerRepositoryTest) TestOpensAfterThreshold() {
+	cacheRepo := &mock.RepositoryMock{}
+	cacheRepo.On("GetCache", t.Key, &dto.CacheAuth{}).Return(nil, errors.New("Connection refused"))
+
+	repo := t.newRepository(cacheRepo)
+
+	for i := 0; i < t.Conf.BreakerThreshold; i++ {
+		assert.NotEqual(t.T(), ErrCircuitOpen, repo.GetCache(context.Background(), t.Key, &dto.CacheAuth{}))
+	}
+
+	assert.Equal(t.T(), ErrCircuitOpen, repo.GetCache(context.Background(), t.Key, &dto.CacheAuth{}))
+	cacheRepo.AssertNumberOfCalls(t.T(), "GetCache", t.Conf.BreakerThreshold)
+}
+
+func (t *BreakerRepositoryTest) TestMissDoesNotOpen() {
+	cacheRepo := &mock.RepositoryMock{}
+	cacheRepo.On("GetCache", t.Key, &dto.CacheAuth{}).Return(nil, redis.Nil)
+
+	repo := t.newRepository(cacheRepo)
+
+	for i := 0; i <= t.Conf.BreakerThreshold; i++ {
+		assert.Equal(t.T(), redis.Nil, repo.GetCache(context.Background(), t.Key, &dto.CacheAuth{}))
+	}
+}
+
+func (t *BreakerRepositoryTest) TestClosesAfterCooldown() {
+	cacheRepo := &mock.RepositoryMock{}
+	cacheRepo.On("GetCache", t.Key, &dto.CacheAuth{}).Return(nil, errors.New("Connection refused")).Times(t.Conf.BreakerThreshold)
+	cacheRepo.On("GetCache", t.Key, &dto.CacheAuth{}).Return(&dto.CacheAuth{}, nil)
+
+	repo := t.newRepository(cacheRepo)
+
+	for i := 0; i < t.Conf.BreakerThreshold; i++ {
+		_ = repo.GetCache(context.Background(), t.Key, &dto.CacheAuth{})
+	}
+	assert.Equal(t.T(), ErrCircuitOpen, repo.GetCache(context.Background(), t.Key, &dto.CacheAuth{}))
+
+	t.Now = t.Now.Add(11 * time.Second)
+
+	assert.Nil(t.T(), repo.GetCache(context.Background(), t.Key, &dto.CacheAuth{}))
+	assert.Nil(t.T(), repo.GetCache(context.Background(), t.Key, &dto.CacheAuth{}))
+}
+
+func (t *BreakerRepositoryTest) TestReopensWhenTrialFails() {
+	cacheRepo := &mock.RepositoryMock{}
+	cacheRepo.On("GetCache", t.Key, &dto.CacheAuth{}).Return(nil, errors.New("Connection refused"))
+
+	repo := t.newRepository(cacheRepo)
+
+	for i := 0; i < t.Conf.BreakerThreshold; i++ {
+		_ = repo.GetCache(context.Background(), t.Key, &dto.CacheAuth{})
+	}
+
+	t.Now = t.Now.Add(11 * time.Second)
+
+	assert.NotEqual(t.T(), ErrCircuitOpen, repo.GetCache(context.Background(), t.Key, &dto.CacheAuth{}))
+	assert.Equal(t.T(), ErrCircuitOpen, repo.GetCache(context.Background(), t.Key, &dto.CacheAuth{}))
+}
+
+func (t *BreakerRepositoryTest) TestBatchReadOpens() {
+	cacheRepo := &mock.RepositoryMock{}
+	cacheRepo.On("GetCaches", []string{t.Key}).Return(nil, nil, errors.New("Connection refused"))
+
+	repo := t.newRepository(cacheRepo)
+
+	for i := 0; i < t.Conf.BreakerThreshold; i++ {
+		_, err := repo.GetCaches(context.Background(), []string{t.Key}, []interface{}{&dto.CacheAuth{}})
+		assert.NotEqual(t.T(), ErrCircuitOpen, err)
+	}
+
+	errs, err := repo.GetCaches(context.Background(), []string{t.Key}, []interface{}{&dto.CacheAuth{}})
+
+	assert.Nil(t.T(), errs)
+	assert.Equal(t.T(), ErrCircuitOpen, err)
+	cacheRepo.AssertNumberOfCalls(t.T(), "GetCaches", t.Conf.BreakerThreshold)
+}
+
+func (t *BreakerRepositoryTest) TestCanceledCallDoesNotOpen() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cacheRepo := &mock.RepositoryMock{}
+	cacheRepo.On("GetCache", t.Key, &dto.CacheAuth{}).Return(nil, context.Canceled)
+
+	repo := t.newRepository(cacheRepo)
+
+	for i := 0; i <= t.Conf.BreakerThreshold; i++ {
+		assert.Equal(t.T(), context.Canceled, repo.GetCache(ctx, t.Key, &dto.CacheAuth{}))
+	}
+	cacheRepo.AssertNumberOfCalls(t.T(), "GetCache", t.Conf.BreakerThreshold+1)
+}
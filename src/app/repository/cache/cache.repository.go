@@ -3,21 +3,32 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"sync"
 	"time"
 
+	"github.com/bookpanda/mygraderlist-auth/src/app/metrics"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
 	"github.com/go-redis/redis/v8"
 )
 
+const scanCount = 1000
+
 type Repository struct {
-	client *redis.Client
+	client redis.UniversalClient
+	keys   *KeyBuilder
+	legacy bool
 }
 
-func NewRepository(client *redis.Client) *Repository {
-	return &Repository{client: client}
+func NewRepository(client redis.UniversalClient, conf config.Cache) *Repository {
+	return &Repository{
+		client: client,
+		keys:   NewKeyBuilder(conf),
+		legacy: !conf.SkipLegacyKeys,
+	}
 }
 
-func (r *Repository) SaveCache(key string, value interface{}, ttl int) (err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *Repository) SaveCache(ctx context.Context, key string, value interface{}, ttl int) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	v, err := json.Marshal(value)
@@ -25,17 +36,247 @@ func (r *Repository) SaveCache(key string, value interface{}, ttl int) (err erro
 		return
 	}
 
-	return r.client.Set(ctx, key, v, time.Duration(ttl)*time.Second).Err()
+	defer observe("set", time.Now(), &err)
+
+	return r.client.Set(ctx, r.keys.Key(key), v, time.Duration(ttl)*time.Second).Err()
+}
+
+// GetCache reads the namespaced key, then the legacy key while the fallback is on. A value saved since
+// the rollout always wins over the legacy one, which is left to expire.
+func (r *Repository) GetCache(ctx context.Context, key string, value interface{}) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	v, err := r.client.Get(ctx, r.keys.Key(key)).Result()
+	if err == redis.Nil && r.legacy {
+		v, err = r.client.Get(ctx, legacyKey(key)).Result()
+		if err == nil {
+			metrics.LegacyCacheReads.Inc()
+		}
+	}
+	observe("get", start, &err)
+	if err != nil {
+		return
+	}
+
+	return json.Unmarshal([]byte(v), value)
 }
 
-func (r *Repository) GetCache(key string, value interface{}) (err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// TakeCache reads and deletes the key in a single GETDEL, so a value is only ever taken once
+func (r *Repository) TakeCache(ctx context.Context, key string, value interface{}) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	v, err := r.client.Get(ctx, key).Result()
+	start := time.Now()
+	v, err := r.client.GetDel(ctx, r.keys.Key(key)).Result()
+	if err == redis.Nil && r.legacy {
+		v, err = r.client.GetDel(ctx, legacyKey(key)).Result()
+		if err == nil {
+			metrics.LegacyCacheReads.Inc()
+		}
+	}
+	observe("getdel", start, &err)
 	if err != nil {
 		return
 	}
 
 	return json.Unmarshal([]byte(v), value)
 }
+
+// GetCaches reads the keys in a single round trip and decodes them into the values at the same index.
+// The error of each key is redis.Nil when it's missing, the returned error is the failure of the whole
+// read. Missing keys are looked up again under their legacy name while the fallback is on.
+func (r *Repository) GetCaches(ctx context.Context, keys []string, values []interface{}) (errs []error, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	namespaced := make([]string, len(keys))
+	for i, key := range keys {
+		namespaced[i] = r.keys.Key(key)
+	}
+
+	start := time.Now()
+	found, err := r.mget(ctx, namespaced)
+	if err == nil && r.legacy {
+		var missing []int
+		var legacy []string
+		for i, v := range found {
+			if v == nil {
+				missing = append(missing, i)
+				legacy = append(legacy, legacyKey(keys[i]))
+			}
+		}
+
+		if len(legacy) > 0 {
+			var legacyFound []interface{}
+			legacyFound, err = r.mget(ctx, legacy)
+			for j, v := range legacyFound {
+				if v != nil {
+					found[missing[j]] = v
+					metrics.LegacyCacheReads.Inc()
+				}
+			}
+		}
+	}
+	observe("mget", start, &err)
+	if err != nil {
+		return nil, err
+	}
+
+	errs = make([]error, len(keys))
+	for i, v := range found {
+		s, ok := v.(string)
+		if !ok {
+			errs[i] = redis.Nil
+			continue
+		}
+
+		errs[i] = json.Unmarshal([]byte(s), values[i])
+	}
+
+	return errs, nil
+}
+
+// mget returns the values of the keys, nil for the missing ones. The keys of a cluster may be in
+// different slots so they are read with pipelined GETs instead, which the client groups by node.
+func (r *Repository) mget(ctx context.Context, keys []string) ([]interface{}, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	if _, ok := r.client.(*redis.ClusterClient); !ok {
+		return r.client.MGet(ctx, keys...).Result()
+	}
+
+	// Pipelined only returns the first error, a miss there could hide a failed node behind it
+	cmds, _ := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Get(ctx, key)
+		}
+		return nil
+	})
+
+	found := make([]interface{}, len(keys))
+	for i, cmd := range cmds {
+		v, err := cmd.(*redis.StringCmd).Result()
+		switch {
+		case err == nil:
+			found[i] = v
+		case err != redis.Nil:
+			return nil, err
+		}
+	}
+
+	return found, nil
+}
+
+// RemoveCache deletes the legacy key too, otherwise a revoked session would still be read from it
+func (r *Repository) RemoveCache(ctx context.Context, key string) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	defer observe("del", time.Now(), &err)
+
+	if !r.legacy {
+		return r.client.Del(ctx, r.keys.Key(key)).Err()
+	}
+
+	// the keys may be in different slots of a cluster, so they are deleted one by one
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.keys.Key(key))
+		pipe.Del(ctx, legacyKey(key))
+		return nil
+	})
+
+	return
+}
+
+// Keys lists the keys of the type, every key of the namespace when the type is empty
+func (r *Repository) Keys(ctx context.Context, keyType string) ([]string, error) {
+	var mu sync.Mutex
+	var keys []string
+
+	err := r.scan(ctx, r.keys.Pattern(keyType), func(batch []string) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		keys = append(keys, batch...)
+		return nil
+	})
+
+	return keys, err
+}
+
+// Purge deletes the keys of the type and returns how many were deleted
+func (r *Repository) Purge(ctx context.Context, keyType string) (int, error) {
+	var mu sync.Mutex
+	var purged int
+
+	err := r.scan(ctx, r.keys.Pattern(keyType), func(batch []string) error {
+		cmds, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range batch {
+				pipe.Unlink(ctx, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, cmd := range cmds {
+			purged += int(cmd.(*redis.IntCmd).Val())
+		}
+		return nil
+	})
+
+	return purged, err
+}
+
+// scan walks the keys matching the pattern in batches, on every master of a cluster
+func (r *Repository) scan(ctx context.Context, pattern string, fn func([]string) error) error {
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return scanNode(ctx, client, pattern, fn)
+		})
+	}
+
+	return scanNode(ctx, r.client, pattern, fn)
+}
+
+func scanNode(ctx context.Context, client redis.UniversalClient, pattern string, fn func([]string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// observe records the latency of a redis call, a missing key is a miss rather than an error
+func observe(operation string, start time.Time, err *error) {
+	result := "ok"
+	switch {
+	case *err == redis.Nil:
+		result = "miss"
+	case *err != nil:
+		result = "error"
+	}
+
+	metrics.CacheDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"github.com/bxcodec/faker/v3"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RepositoryTest struct {
+	suite.Suite
+	Server *miniredis.Miniredis
+	Client *redis.Client
+	UserID string
+	Value  *dto.CacheAuth
+}
+
+func TestRepository(t *testing.T) {
+	suite.Run(t, new(RepositoryTest))
+}
+
+func (t *RepositoryTest) SetupTest() {
+	t.Server = miniredis.RunT(t.T())
+	t.Client = redis.NewClient(&redis.Options{Addr: t.Server.Addr()})
+	t.UserID = faker.UUIDDigit()
+	t.Value = &dto.CacheAuth{
+		Token: faker.Word(),
+		Role:  role.USER,
+	}
+}
+
+func (t *RepositoryTest) TearDownTest() {
+	_ = t.Client.Close()
+}
+
+func (t *RepositoryTest) saveLegacy(key string) {
+	v, _ := json.Marshal(t.Value)
+	assert.Nil(t.T(), t.Server.Set(key, string(v)))
+}
+
+func (t *RepositoryTest) TestSaveNamespacedKey() {
+	repo := NewRepository(t.Client, config.Cache{KeyPrefix: "mgl:test", KeyVersion: 3})
+
+	err := repo.SaveCache(context.Background(), "session:"+t.UserID, t.Value, 3600)
+
+	assert.Nil(t.T(), err)
+	assert.True(t.T(), t.Server.Exists("mgl:test:v3:session:"+t.UserID))
+	assert.False(t.T(), t.Server.Exists("session:"+t.UserID))
+}
+
+func (t *RepositoryTest) TestGetFallsBackToLegacyKey() {
+	t.saveLegacy(t.UserID)
+	repo := NewRepository(t.Client, config.Cache{})
+
+	actual := &dto.CacheAuth{}
+	err := repo.GetCache(context.Background(), "session:"+t.UserID, actual)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Value, actual)
+}
+
+func (t *RepositoryTest) TestGetSkipLegacyKeys() {
+	t.saveLegacy(t.UserID)
+	repo := NewRepository(t.Client, config.Cache{SkipLegacyKeys: true})
+
+	err := repo.GetCache(context.Background(), "session:"+t.UserID, &dto.CacheAuth{})
+
+	assert.Equal(t.T(), redis.Nil, err)
+}
+
+func (t *RepositoryTest) TestGetPrefersNamespacedKey() {
+	t.saveLegacy(t.UserID)
+	repo := NewRepository(t.Client, config.Cache{})
+
+	banned := &dto.CacheAuth{Role: role.USER, Status: role.BANNED}
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), "session:"+t.UserID, banned, 3600))
+
+	actual := &dto.CacheAuth{}
+	err := repo.GetCache(context.Background(), "session:"+t.UserID, actual)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), banned, actual)
+}
+
+func (t *RepositoryTest) TestRemoveDeletesLegacyKey() {
+	t.saveLegacy(t.UserID)
+	repo := NewRepository(t.Client, config.Cache{})
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), "session:"+t.UserID, t.Value, 3600))
+
+	err := repo.RemoveCache(context.Background(), "session:"+t.UserID)
+
+	assert.Nil(t.T(), err)
+	assert.Empty(t.T(), t.Server.Keys())
+}
+
+func (t *RepositoryTest) TestKeysAndPurgeByType() {
+	repo := NewRepository(t.Client, config.Cache{})
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), "session:"+t.UserID, t.Value, 3600))
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), "device:"+faker.Word(), t.Value, 3600))
+	// keys of other applications sharing redis are left alone
+	assert.Nil(t.T(), t.Server.Set("session:other", "1"))
+
+	keys, err := repo.Keys(context.Background(), "session")
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), []string{"mgl:auth:v2:session:" + t.UserID}, keys)
+
+	purged, err := repo.Purge(context.Background(), "session")
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), 1, purged)
+	assert.Len(t.T(), t.Server.Keys(), 2)
+}
+
+func (t *RepositoryTest) TestTakeCache() {
+	repo := NewRepository(t.Client, config.Cache{})
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), "session:"+t.UserID, t.Value, 3600))
+
+	actual := &dto.CacheAuth{}
+	err := repo.TakeCache(context.Background(), "session:"+t.UserID, actual)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Value, actual)
+	assert.Equal(t.T(), redis.Nil, repo.TakeCache(context.Background(), "session:"+t.UserID, &dto.CacheAuth{}))
+}
+
+func (t *RepositoryTest) TestGetCaches() {
+	legacyUserID := faker.UUIDDigit()
+	t.saveLegacy(legacyUserID)
+	repo := NewRepository(t.Client, config.Cache{})
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), "session:"+t.UserID, t.Value, 3600))
+
+	keys := []string{"session:" + t.UserID, "session:" + faker.UUIDDigit(), "session:" + legacyUserID}
+	actual := []*dto.CacheAuth{{}, {}, {}}
+
+	errs, err := repo.GetCaches(context.Background(), keys, []interface{}{actual[0], actual[1], actual[2]})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), []error{nil, redis.Nil, nil}, errs)
+	assert.Equal(t.T(), t.Value, actual[0])
+	assert.Equal(t.T(), t.Value, actual[2])
+}
+
+func (t *RepositoryTest) TestGetCachesSkipLegacyKeys() {
+	t.saveLegacy(t.UserID)
+	repo := NewRepository(t.Client, config.Cache{SkipLegacyKeys: true})
+
+	errs, err := repo.GetCaches(context.Background(), []string{"session:" + t.UserID}, []interface{}{&dto.CacheAuth{}})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), []error{redis.Nil}, errs)
+}
+
+func (t *RepositoryTest) TestGetCachesCacheDown() {
+	repo := NewRepository(t.Client, config.Cache{})
+	t.Server.Close()
+
+	errs, err := repo.GetCaches(context.Background(), []string{"session:" + t.UserID}, []interface{}{&dto.CacheAuth{}})
+
+	assert.NotNil(t.T(), err)
+	assert.Nil(t.T(), errs)
+}
+
+func (t *RepositoryTest) TestGetWithCanceledContext() {
+	repo := NewRepository(t.Client, config.Cache{})
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), t.UserID, t.Value, 3600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := repo.GetCache(ctx, t.UserID, &dto.CacheAuth{})
+
+	assert.ErrorIs(t.T(), err, context.Canceled)
+}
+
+func (t *RepositoryTest) TestGetCachesClusterFailsOnAnyError() {
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{t.Server.Addr()}})
+	defer client.Close()
+
+	repo := NewRepository(client, config.Cache{})
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), t.UserID, t.Value, 3600))
+	t.Server.HSet("broken", "field", "value")
+
+	errs, err := repo.GetCaches(context.Background(), []string{"missing", "broken", t.UserID}, []interface{}{&dto.CacheAuth{}, &dto.CacheAuth{}, &dto.CacheAuth{}})
+
+	assert.Nil(t.T(), errs)
+	assert.NotNil(t.T(), err)
+}
+
+func (t *RepositoryTest) TestGetCachesClusterMiss() {
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{t.Server.Addr()}})
+	defer client.Close()
+
+	repo := NewRepository(client, config.Cache{})
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), t.UserID, t.Value, 3600))
+
+	actual := &dto.CacheAuth{}
+	errs, err := repo.GetCaches(context.Background(), []string{"missing", t.UserID}, []interface{}{&dto.CacheAuth{}, actual})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), []error{redis.Nil, nil}, errs)
+	assert.Equal(t.T(), t.Value, actual)
+}
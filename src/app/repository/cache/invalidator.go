@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+)
+
+const resubscribeBackoff = time.Second
+
+type IInvalidationHandler interface {
+	// Invalidate drops what is kept in process for the key
+	Invalidate(key string)
+	// Subscribed is false while the subscription is down, the invalidations are missed until it's back
+	Subscribed(bool)
+}
+
+// Invalidator broadcasts the keys that changed to every replica over redis pub/sub, so the copies kept
+// in process are dropped when a session is revoked elsewhere
+type Invalidator struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+func NewInvalidator(client redis.UniversalClient, conf config.Cache) *Invalidator {
+	return &Invalidator{
+		client:  client,
+		channel: NewKeyBuilder(conf).Key("invalidations"),
+	}
+}
+
+func (i *Invalidator) Publish(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return i.client.Publish(ctx, i.channel, key).Err()
+}
+
+// Subscribe passes the published keys to the handler until the context is done. The subscription is
+// reconnected on failure and the handler is told while it's down.
+func (i *Invalidator) Subscribe(ctx context.Context, handler IInvalidationHandler) {
+	pubsub := i.client.Subscribe(ctx, i.channel)
+
+	// receiving doesn't watch the context, closing the subscription is what interrupts it
+	go func() {
+		<-ctx.Done()
+		_ = pubsub.Close()
+	}()
+
+	subscribed := false
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if subscribed {
+				handler.Subscribed(false)
+				subscribed = false
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Warn().
+				Err(err).
+				Str("service", "cache").
+				Msg("Lost the invalidation subscription, retrying")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(resubscribeBackoff):
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			// sent again after every reconnection
+			if msg.Kind == "subscribe" && !subscribed {
+				handler.Subscribed(true)
+				subscribed = true
+			}
+		case *redis.Message:
+			handler.Invalidate(msg.Payload)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/bxcodec/faker/v3"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type InvalidatorTest struct {
+	suite.Suite
+	Server *miniredis.Miniredis
+	Client *redis.Client
+}
+
+type invalidationRecorder struct {
+	mu         sync.Mutex
+	keys       []string
+	subscribed []bool
+}
+
+func (r *invalidationRecorder) Invalidate(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys = append(r.keys, key)
+}
+
+func (r *invalidationRecorder) Subscribed(ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subscribed = append(r.subscribed, ok)
+}
+
+func (r *invalidationRecorder) received() ([]string, []bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string{}, r.keys...), append([]bool{}, r.subscribed...)
+}
+
+func TestInvalidator(t *testing.T) {
+	suite.Run(t, new(InvalidatorTest))
+}
+
+func (t *InvalidatorTest) SetupTest() {
+	t.Server = miniredis.RunT(t.T())
+	t.Client = redis.NewClient(&redis.Options{Addr: t.Server.Addr()})
+}
+
+func (t *InvalidatorTest) TearDownTest() {
+	_ = t.Client.Close()
+}
+
+func (t *InvalidatorTest) TestPublishReachesSubscriber() {
+	key := "session:" + faker.UUIDDigit()
+	invalidator := NewInvalidator(t.Client, config.Cache{})
+	handler := &invalidationRecorder{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		invalidator.Subscribe(ctx, handler)
+		close(done)
+	}()
+
+	assert.Eventually(t.T(), func() bool {
+		_, subscribed := handler.received()
+		return len(subscribed) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t.T(), invalidator.Publish(context.Background(), key))
+
+	assert.Eventually(t.T(), func() bool {
+		keys, _ := handler.received()
+		return len(keys) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	keys, subscribed := handler.received()
+	assert.Equal(t.T(), []string{key}, keys)
+	assert.Equal(t.T(), []bool{true, false}, subscribed)
+}
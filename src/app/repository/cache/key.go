@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+)
+
+const (
+	DefaultKeyPrefix  = "mgl:auth"
+	DefaultKeyVersion = 2
+)
+
+// KeyTypes are the kinds of keys the services write, as the first segment of the key they pass in,
+// e.g. session:<user id>. Update it along with the services so the cache command knows every type.
+var KeyTypes = []string{
+	"session",
+	"impersonation",
+	"oidc_request",
+	"oidc_code",
+	"oidc_access",
+	"device",
+	"device_user_code",
+	"device_session",
+}
+
+// KeyBuilder namespaces the keys of the services as <prefix>:v<version>:<type>:<id>, so they don't
+// collide with other applications sharing redis and a format change only needs a version bump
+type KeyBuilder struct {
+	namespace string
+}
+
+func NewKeyBuilder(conf config.Cache) *KeyBuilder {
+	prefix := conf.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultKeyPrefix
+	}
+
+	version := conf.KeyVersion
+	if version <= 0 {
+		version = DefaultKeyVersion
+	}
+
+	return &KeyBuilder{namespace: prefix + ":v" + strconv.Itoa(version) + ":"}
+}
+
+func (b *KeyBuilder) Key(key string) string {
+	return b.namespace + key
+}
+
+// Pattern matches every key of the type, or every key of the namespace when the type is empty
+func (b *KeyBuilder) Pattern(keyType string) string {
+	if keyType == "" {
+		return b.namespace + "*"
+	}
+
+	return b.namespace + keyType + ":*"
+}
+
+// IsKeyType reports whether the type is one of KeyTypes
+func IsKeyType(keyType string) bool {
+	for _, t := range KeyTypes {
+		if t == keyType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// legacyKey is the key written before the namespaced schema, the sessions were keyed by the raw user id
+// and the other types already had their type as a prefix
+func legacyKey(key string) string {
+	if id, ok := strings.CutPrefix(key, "session:"); ok {
+		return id
+	}
+
+	return key
+}
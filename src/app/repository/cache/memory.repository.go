@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+var ErrValueTooLarge = errors.New("Value is larger than the cache")
+
+// MemoryRepository keeps the cache in process for single node deployments and tests. Values are
+// stored as json like on redis, so readers get a copy, and a missing or expired key returns redis.Nil.
+// When a limit is reached the least recently used entries are evicted.
+type MemoryRepository struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List
+	maxEntries int
+	maxBytes   int
+	size       int
+	now        func() time.Time
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemoryRepository(conf config.Cache) *MemoryRepository {
+	return &MemoryRepository{
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+		maxEntries: conf.MaxEntries,
+		maxBytes:   conf.MaxBytes,
+		now:        time.Now,
+	}
+}
+
+func (r *MemoryRepository) SaveCache(_ context.Context, key string, value interface{}, ttl int) error {
+	v, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	if r.maxBytes > 0 && len(v) > r.maxBytes {
+		return ErrValueTooLarge
+	}
+
+	entry := &memoryEntry{key: key, value: v}
+	if ttl > 0 {
+		entry.expiresAt = r.now().Add(time.Duration(ttl) * time.Second)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if element, ok := r.entries[key]; ok {
+		r.remove(element)
+	}
+
+	r.entries[key] = r.lru.PushFront(entry)
+	r.size += len(v)
+
+	r.evict()
+
+	return nil
+}
+
+func (r *MemoryRepository) GetCache(_ context.Context, key string, value interface{}) error {
+	r.mu.Lock()
+	element, ok := r.entries[key]
+	if !ok {
+		r.mu.Unlock()
+		return redis.Nil
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if r.expired(entry) {
+		r.remove(element)
+		r.mu.Unlock()
+		return redis.Nil
+	}
+
+	r.lru.MoveToFront(element)
+	v := entry.value
+	r.mu.Unlock()
+
+	return json.Unmarshal(v, value)
+}
+
+func (r *MemoryRepository) TakeCache(_ context.Context, key string, value interface{}) error {
+	r.mu.Lock()
+	element, ok := r.entries[key]
+	if !ok {
+		r.mu.Unlock()
+		return redis.Nil
+	}
+
+	entry := element.Value.(*memoryEntry)
+	expired := r.expired(entry)
+	r.remove(element)
+	r.mu.Unlock()
+
+	if expired {
+		return redis.Nil
+	}
+
+	return json.Unmarshal(entry.value, value)
+}
+
+func (r *MemoryRepository) GetCaches(ctx context.Context, keys []string, values []interface{}) ([]error, error) {
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		errs[i] = r.GetCache(ctx, key, values[i])
+	}
+
+	return errs, nil
+}
+
+func (r *MemoryRepository) RemoveCache(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if element, ok := r.entries[key]; ok {
+		r.remove(element)
+	}
+
+	return nil
+}
+
+// evict drops the expired entries first, then the least recently used ones until the limits are met
+func (r *MemoryRepository) evict() {
+	if !r.full() {
+		return
+	}
+
+	for element := r.lru.Back(); element != nil; {
+		prev := element.Prev()
+		if r.expired(element.Value.(*memoryEntry)) {
+			r.remove(element)
+		}
+		element = prev
+	}
+
+	for r.full() {
+		r.remove(r.lru.Back())
+	}
+}
+
+func (r *MemoryRepository) full() bool {
+	return (r.maxEntries > 0 && r.lru.Len() > r.maxEntries) || (r.maxBytes > 0 && r.size > r.maxBytes)
+}
+
+func (r *MemoryRepository) expired(entry *memoryEntry) bool {
+	return !entry.expiresAt.IsZero() && !r.now().Before(entry.expiresAt)
+}
+
+func (r *MemoryRepository) remove(element *list.Element) {
+	entry := r.lru.Remove(element).(*memoryEntry)
+	delete(r.entries, entry.key)
+	r.size -= len(entry.value)
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"github.com/bxcodec/faker/v3"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryRepositoryTest struct {
+	suite.Suite
+	Now   time.Time
+	Value *dto.CacheAuth
+}
+
+func TestMemoryRepository(t *testing.T) {
+	suite.Run(t, new(MemoryRepositoryTest))
+}
+
+func (t *MemoryRepositoryTest) SetupTest() {
+	t.Now = time.Now()
+	t.Value = &dto.CacheAuth{
+		Token: faker.Word(),
+		Role:  role.USER,
+	}
+}
+
+func (t *MemoryRepositoryTest) newRepository(conf config.Cache) *MemoryRepository {
+	repo := NewMemoryRepository(conf)
+	repo.now = func() time.Time {
+		return t.Now
+	}
+
+	return repo
+}
+
+func (t *MemoryRepositoryTest) TestSaveAndGet() {
+	repo := t.newRepository(config.Cache{})
+
+	err := repo.SaveCache(context.Background(), "user", t.Value, 3600)
+	assert.Nil(t.T(), err)
+
+	actual := &dto.CacheAuth{}
+	err = repo.GetCache(context.Background(), "user", actual)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Value, actual)
+}
+
+func (t *MemoryRepositoryTest) TestGetMissing() {
+	repo := t.newRepository(config.Cache{})
+
+	err := repo.GetCache(context.Background(), "user", &dto.CacheAuth{})
+
+	assert.Equal(t.T(), redis.Nil, err)
+}
+
+func (t *MemoryRepositoryTest) TestTakeCache() {
+	repo := t.newRepository(config.Cache{})
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), "user", t.Value, 3600))
+
+	actual := &dto.CacheAuth{}
+	err := repo.TakeCache(context.Background(), "user", actual)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Value, actual)
+	assert.Equal(t.T(), redis.Nil, repo.TakeCache(context.Background(), "user", &dto.CacheAuth{}))
+}
+
+func (t *MemoryRepositoryTest) TestGetExpired() {
+	repo := t.newRepository(config.Cache{})
+
+	_ = repo.SaveCache(context.Background(), "user", t.Value, 60)
+	t.Now = t.Now.Add(time.Minute)
+
+	err := repo.GetCache(context.Background(), "user", &dto.CacheAuth{})
+
+	assert.Equal(t.T(), redis.Nil, err)
+	assert.Equal(t.T(), 0, repo.lru.Len())
+}
+
+func (t *MemoryRepositoryTest) TestNoExpiry() {
+	repo := t.newRepository(config.Cache{})
+
+	_ = repo.SaveCache(context.Background(), "user", t.Value, 0)
+	t.Now = t.Now.Add(365 * 24 * time.Hour)
+
+	err := repo.GetCache(context.Background(), "user", &dto.CacheAuth{})
+
+	assert.Nil(t.T(), err)
+}
+
+func (t *MemoryRepositoryTest) TestRemove() {
+	repo := t.newRepository(config.Cache{})
+
+	_ = repo.SaveCache(context.Background(), "user", t.Value, 3600)
+
+	err := repo.RemoveCache(context.Background(), "user")
+	assert.Nil(t.T(), err)
+
+	err = repo.GetCache(context.Background(), "user", &dto.CacheAuth{})
+	assert.Equal(t.T(), redis.Nil, err)
+}
+
+func (t *MemoryRepositoryTest) TestEvictLeastRecentlyUsed() {
+	repo := t.newRepository(config.Cache{MaxEntries: 2})
+
+	_ = repo.SaveCache(context.Background(), "first", t.Value, 3600)
+	_ = repo.SaveCache(context.Background(), "second", t.Value, 3600)
+	_ = repo.GetCache(context.Background(), "first", &dto.CacheAuth{})
+	_ = repo.SaveCache(context.Background(), "third", t.Value, 3600)
+
+	assert.Nil(t.T(), repo.GetCache(context.Background(), "first", &dto.CacheAuth{}))
+	assert.Equal(t.T(), redis.Nil, repo.GetCache(context.Background(), "second", &dto.CacheAuth{}))
+	assert.Nil(t.T(), repo.GetCache(context.Background(), "third", &dto.CacheAuth{}))
+}
+
+func (t *MemoryRepositoryTest) TestEvictExpiredFirst() {
+	repo := t.newRepository(config.Cache{MaxEntries: 2})
+
+	_ = repo.SaveCache(context.Background(), "first", t.Value, 3600)
+	_ = repo.SaveCache(context.Background(), "second", t.Value, 60)
+	t.Now = t.Now.Add(time.Minute)
+	_ = repo.SaveCache(context.Background(), "third", t.Value, 3600)
+
+	assert.Nil(t.T(), repo.GetCache(context.Background(), "first", &dto.CacheAuth{}))
+	assert.Nil(t.T(), repo.GetCache(context.Background(), "third", &dto.CacheAuth{}))
+}
+
+func (t *MemoryRepositoryTest) TestMaxBytes() {
+	repo := t.newRepository(config.Cache{MaxBytes: 8})
+
+	err := repo.SaveCache(context.Background(), "user", t.Value, 3600)
+
+	assert.Equal(t.T(), ErrValueTooLarge, err)
+}
+
+func (t *MemoryRepositoryTest) TestGetCaches() {
+	repo := t.newRepository(config.Cache{})
+	assert.Nil(t.T(), repo.SaveCache(context.Background(), "user", t.Value, 3600))
+
+	actual := &dto.CacheAuth{}
+	errs, err := repo.GetCaches(context.Background(), []string{"user", "missing"}, []interface{}{actual, &dto.CacheAuth{}})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), []error{nil, redis.Nil}, errs)
+	assert.Equal(t.T(), t.Value, actual)
+}
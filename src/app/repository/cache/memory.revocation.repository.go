@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+)
+
+// MemoryRevocationRepository keeps the revocation events in process for the memory cache driver, with
+// the same offsets and trimming as the redis stream
+type MemoryRevocationRepository struct {
+	mu      sync.Mutex
+	events  []*dto.RevocationEvent
+	maxLen  int
+	lastMs  uint64
+	lastSeq uint64
+	// trimmed is set once the oldest events have been dropped
+	trimmed bool
+	// changed is closed and replaced on every append to wake up the readers
+	changed chan struct{}
+	now     func() time.Time
+}
+
+func NewMemoryRevocationRepository(conf config.Cache) *MemoryRevocationRepository {
+	maxLen := int(conf.RevocationStreamLength)
+	if maxLen <= 0 {
+		maxLen = defaultRevocationStreamLength
+	}
+
+	return &MemoryRevocationRepository{
+		maxLen:  maxLen,
+		changed: make(chan struct{}),
+		now:     time.Now,
+	}
+}
+
+func (r *MemoryRevocationRepository) Append(_ context.Context, event *dto.RevocationEvent) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// like redis the offsets keep increasing when the clock goes back
+	ms := uint64(r.now().UnixMilli())
+	if ms > r.lastMs {
+		r.lastMs, r.lastSeq = ms, 0
+	} else {
+		r.lastSeq++
+	}
+
+	appended := *event
+	appended.Offset = strconv.FormatUint(r.lastMs, 10) + "-" + strconv.FormatUint(r.lastSeq, 10)
+
+	r.events = append(r.events, &appended)
+	if len(r.events) > r.maxLen {
+		r.trimmed = true
+		r.events = append([]*dto.RevocationEvent{}, r.events[len(r.events)-r.maxLen:]...)
+	}
+
+	close(r.changed)
+	r.changed = make(chan struct{})
+
+	return appended.Offset, nil
+}
+
+func (r *MemoryRevocationRepository) Seek(_ context.Context, offset string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if offset == "" {
+		if len(r.events) == 0 {
+			return FirstOffset, nil
+		}
+
+		return r.events[len(r.events)-1].Offset, nil
+	}
+
+	if _, err := parseOffset(offset); err != nil {
+		return "", err
+	}
+
+	if r.trimmed && offset != FirstOffset && compareOffsets(offset, r.events[0].Offset) < 0 {
+		return "", ErrOffsetExpired
+	}
+
+	return offset, nil
+}
+
+func (r *MemoryRevocationRepository) Read(ctx context.Context, offset string, count int64) ([]*dto.RevocationEvent, error) {
+	timeout := time.NewTimer(revocationReadBlock)
+	defer timeout.Stop()
+
+	for {
+		r.mu.Lock()
+		i := sort.Search(len(r.events), func(i int) bool {
+			return compareOffsets(r.events[i].Offset, offset) > 0
+		})
+		events := r.events[i:]
+		changed := r.changed
+		r.mu.Unlock()
+
+		if len(events) > 0 {
+			if count > 0 && int64(len(events)) > count {
+				events = events[:count]
+			}
+
+			read := make([]*dto.RevocationEvent, len(events))
+			for j, event := range events {
+				copied := *event
+				read[j] = &copied
+			}
+
+			return read, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout.C:
+			return nil, nil
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+const (
+	// FirstOffset replays every retained event
+	FirstOffset = "0-0"
+
+	defaultRevocationStreamLength = 10000
+	revocationReadBlock           = 5 * time.Second
+)
+
+var (
+	ErrInvalidOffset = errors.New("Invalid revocation offset")
+	ErrOffsetExpired = errors.New("Revocation offset is older than the retained events")
+)
+
+// RevocationRepository keeps the revocation events in a redis stream rather than on a pub/sub channel,
+// so a client reconnecting with the offset of its last event gets the ones it missed. The stream is
+// trimmed to about the configured length.
+type RevocationRepository struct {
+	client redis.UniversalClient
+	stream string
+	maxLen int64
+}
+
+func NewRevocationRepository(client redis.UniversalClient, conf config.Cache) *RevocationRepository {
+	maxLen := conf.RevocationStreamLength
+	if maxLen <= 0 {
+		maxLen = defaultRevocationStreamLength
+	}
+
+	return &RevocationRepository{
+		client: client,
+		stream: NewKeyBuilder(conf).Key("revocations"),
+		maxLen: maxLen,
+	}
+}
+
+func (r *RevocationRepository) Append(ctx context.Context, event *dto.RevocationEvent) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	v, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+
+	return r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: r.stream,
+		MaxLen: r.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": v},
+	}).Result()
+}
+
+// Seek resolves the offset a client starts reading after, an empty offset starts with the next event
+func (r *RevocationRepository) Seek(ctx context.Context, offset string) (string, error) {
+	if offset == "" {
+		latest, err := r.client.XRevRangeN(ctx, r.stream, "+", "-", 1).Result()
+		if err != nil || len(latest) == 0 {
+			return FirstOffset, err
+		}
+
+		return latest[0].ID, nil
+	}
+
+	if _, err := parseOffset(offset); err != nil {
+		return "", err
+	}
+
+	if offset == FirstOffset {
+		return offset, nil
+	}
+
+	retained, err := r.retainedFrom(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if retained != "" && compareOffsets(offset, retained) < 0 {
+		return "", ErrOffsetExpired
+	}
+
+	return offset, nil
+}
+
+// retainedFrom returns the first entry of the stream once it has been trimmed, and empty while every
+// event ever appended is still there
+func (r *RevocationRepository) retainedFrom(ctx context.Context) (string, error) {
+	reply, err := r.client.Do(ctx, "XINFO", "STREAM", r.stream).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	if first, ok := parseRetainedFrom(reply); ok {
+		return first, nil
+	}
+
+	// the server doesn't report the first entry, take the oldest one left as trimmed
+	oldest, err := r.client.XRangeN(ctx, r.stream, "-", "+", 1).Result()
+	if err != nil || len(oldest) == 0 {
+		return "", err
+	}
+
+	return oldest[0].ID, nil
+}
+
+// parseRetainedFrom reads the XINFO STREAM reply, redis 7 also reports entries-added which tells
+// whether anything was trimmed at all
+func parseRetainedFrom(reply interface{}) (string, bool) {
+	fields, _ := reply.([]interface{})
+
+	info := make(map[string]interface{}, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		if key, ok := fields[i].(string); ok {
+			info[key] = fields[i+1]
+		}
+	}
+
+	entry, ok := info["first-entry"]
+	if !ok {
+		return "", false
+	}
+
+	first, _ := entry.([]interface{})
+	if len(first) == 0 {
+		return "", true
+	}
+
+	length, _ := info["length"].(int64)
+	if added, ok := info["entries-added"].(int64); ok && added <= length {
+		return "", true
+	}
+
+	id, _ := first[0].(string)
+
+	return id, true
+}
+
+// Read waits for the events after the offset, it returns none when nothing came in for a while so the
+// caller can check whether it should stop
+func (r *RevocationRepository) Read(ctx context.Context, offset string, count int64) ([]*dto.RevocationEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	streams, err := r.client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{r.stream, offset},
+		Count:   count,
+		Block:   revocationReadBlock,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*dto.RevocationEvent
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			v, _ := message.Values["event"].(string)
+
+			event := &dto.RevocationEvent{}
+			if err := json.Unmarshal([]byte(v), event); err != nil {
+				return nil, errors.Wrapf(err, "Cannot decode revocation event %v", message.ID)
+			}
+			event.Offset = message.ID
+
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// parseOffset splits a stream id, <unix ms>-<sequence>
+func parseOffset(offset string) ([2]uint64, error) {
+	ms, seq, ok := strings.Cut(offset, "-")
+	if !ok {
+		return [2]uint64{}, ErrInvalidOffset
+	}
+
+	msPart, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil {
+		return [2]uint64{}, ErrInvalidOffset
+	}
+
+	seqPart, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return [2]uint64{}, ErrInvalidOffset
+	}
+
+	return [2]uint64{msPart, seqPart}, nil
+}
+
+// compareOffsets orders two valid offsets
+func compareOffsets(a string, b string) int {
+	x, _ := parseOffset(a)
+	y, _ := parseOffset(b)
+
+	for i := range x {
+		switch {
+		case x[i] < y[i]:
+			return -1
+		case x[i] > y[i]:
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"github.com/bxcodec/faker/v3"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RevocationRepositoryTest struct {
+	suite.Suite
+	Server *miniredis.Miniredis
+	Client *redis.Client
+	Event  *dto.RevocationEvent
+}
+
+func TestRevocationRepository(t *testing.T) {
+	suite.Run(t, new(RevocationRepositoryTest))
+}
+
+func (t *RevocationRepositoryTest) SetupTest() {
+	t.Server = miniredis.RunT(t.T())
+	t.Client = redis.NewClient(&redis.Options{Addr: t.Server.Addr()})
+	t.Event = &dto.RevocationEvent{
+		Type:       role.ACCOUNT_SUSPENDED,
+		UserID:     faker.UUIDDigit(),
+		Role:       string(role.USER),
+		Status:     string(role.SUSPENDED),
+		OccurredAt: time.Now().Unix(),
+	}
+}
+
+func (t *RevocationRepositoryTest) TearDownTest() {
+	_ = t.Client.Close()
+}
+
+func (t *RevocationRepositoryTest) TestReadAfterOffset() {
+	repo := NewRevocationRepository(t.Client, config.Cache{})
+
+	first, err := repo.Append(context.Background(), &dto.RevocationEvent{Type: role.SESSION_REVOKED, UserID: faker.UUIDDigit()})
+	assert.Nil(t.T(), err)
+
+	second, err := repo.Append(context.Background(), t.Event)
+	assert.Nil(t.T(), err)
+
+	offset, err := repo.Seek(context.Background(), first)
+	assert.Nil(t.T(), err)
+
+	events, err := repo.Read(context.Background(), offset, 10)
+
+	want := *t.Event
+	want.Offset = second
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), []*dto.RevocationEvent{&want}, events)
+	assert.True(t.T(), t.Server.Exists("mgl:auth:v2:revocations"))
+}
+
+func (t *RevocationRepositoryTest) TestSeekLatest() {
+	repo := NewRevocationRepository(t.Client, config.Cache{})
+
+	offset, err := repo.Seek(context.Background(), "")
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), FirstOffset, offset)
+
+	latest, _ := repo.Append(context.Background(), t.Event)
+
+	offset, err = repo.Seek(context.Background(), "")
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), latest, offset)
+}
+
+func (t *RevocationRepositoryTest) TestSeekExpiredOffset() {
+	repo := NewRevocationRepository(t.Client, config.Cache{RevocationStreamLength: 1})
+
+	expired, _ := repo.Append(context.Background(), t.Event)
+	_, _ = repo.Append(context.Background(), t.Event)
+
+	_, err := repo.Seek(context.Background(), expired)
+
+	assert.Equal(t.T(), ErrOffsetExpired, err)
+}
+
+func (t *RevocationRepositoryTest) TestSeekInvalidOffset() {
+	repo := NewRevocationRepository(t.Client, config.Cache{})
+
+	_, err := repo.Seek(context.Background(), faker.Word())
+
+	assert.Equal(t.T(), ErrInvalidOffset, err)
+}
+
+func (t *RevocationRepositoryTest) TestMemoryReadAfterOffset() {
+	repo := NewMemoryRevocationRepository(config.Cache{})
+
+	first, _ := repo.Append(context.Background(), &dto.RevocationEvent{Type: role.SESSION_REVOKED, UserID: faker.UUIDDigit()})
+	second, _ := repo.Append(context.Background(), t.Event)
+
+	events, err := repo.Read(context.Background(), first, 10)
+
+	want := *t.Event
+	want.Offset = second
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), []*dto.RevocationEvent{&want}, events)
+	assert.Equal(t.T(), 1, compareOffsets(second, first))
+}
+
+func (t *RevocationRepositoryTest) TestMemoryReadWaitsForAppend() {
+	repo := NewMemoryRevocationRepository(config.Cache{})
+
+	offset, _ := repo.Seek(context.Background(), "")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = repo.Append(context.Background(), t.Event)
+	}()
+
+	events, err := repo.Read(context.Background(), offset, 10)
+
+	assert.Nil(t.T(), err)
+	assert.Len(t.T(), events, 1)
+	assert.Equal(t.T(), t.Event.UserID, events[0].UserID)
+}
+
+func (t *RevocationRepositoryTest) TestMemorySeekExpiredOffset() {
+	repo := NewMemoryRevocationRepository(config.Cache{RevocationStreamLength: 1})
+
+	expired, _ := repo.Append(context.Background(), t.Event)
+	_, _ = repo.Append(context.Background(), t.Event)
+
+	_, err := repo.Seek(context.Background(), expired)
+
+	assert.Equal(t.T(), ErrOffsetExpired, err)
+}
+
+func (t *RevocationRepositoryTest) TestSeekMissingStream() {
+	repo := NewRevocationRepository(t.Client, config.Cache{})
+
+	offset, err := repo.Seek(context.Background(), "1-0")
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), "1-0", offset)
+}
+
+func (t *RevocationRepositoryTest) TestParseRetainedFromTrimmed() {
+	reply := []interface{}{
+		"length", int64(2),
+		"entries-added", int64(3),
+		"first-entry", []interface{}{"2-0", []interface{}{"event", "{}"}},
+	}
+
+	first, ok := parseRetainedFrom(reply)
+
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), "2-0", first)
+}
+
+func (t *RevocationRepositoryTest) TestParseRetainedFromUntrimmed() {
+	reply := []interface{}{
+		"length", int64(2),
+		"entries-added", int64(2),
+		"first-entry", []interface{}{"2-0", []interface{}{"event", "{}"}},
+	}
+
+	first, ok := parseRetainedFrom(reply)
+
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), "", first)
+}
+
+func (t *RevocationRepositoryTest) TestMemorySeekUntrimmedOffset() {
+	repo := NewMemoryRevocationRepository(config.Cache{})
+
+	_, _ = repo.Append(context.Background(), t.Event)
+
+	offset, err := repo.Seek(context.Background(), "1-0")
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), "1-0", offset)
+}
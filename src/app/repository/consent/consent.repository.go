@@ -0,0 +1,28 @@
+package consent
+
+import (
+	"context"
+
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/consent"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) FindByUserIDAndClientID(ctx context.Context, userID string, clientID string, result *model.Consent) error {
+	return r.db.WithContext(ctx).First(&result, "user_id = ? AND client_id = ?", userID, clientID).Error
+}
+
+func (r *Repository) Create(ctx context.Context, consent *model.Consent) error {
+	return r.db.WithContext(ctx).Create(&consent).Error
+}
+
+func (r *Repository) Update(ctx context.Context, id string, consent *model.Consent) error {
+	return r.db.WithContext(ctx).Where(id, "id = ?", id).Updates(&consent).First(&consent, "id = ?", id).Error
+}
@@ -0,0 +1,76 @@
+package serviceaccount
+
+import (
+	"context"
+	"time"
+
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/serviceaccount"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) FindAll(ctx context.Context, result *[]*model.ServiceAccount) error {
+	return r.db.WithContext(ctx).Find(&result).Error
+}
+
+func (r *Repository) FindOne(ctx context.Context, id string, result *model.ServiceAccount) error {
+	return r.db.WithContext(ctx).First(&result, "id = ?", id).Error
+}
+
+func (r *Repository) Create(ctx context.Context, in *model.ServiceAccount) error {
+	return r.db.WithContext(ctx).Create(&in).Error
+}
+
+func (r *Repository) Update(ctx context.Context, id string, in *model.ServiceAccount) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Updates(&in).First(&in, "id = ?", id).Error
+}
+
+// Delete removes the rows for good rather than soft deleting them, a soft deleted row would keep its
+// name in the unique index
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("service_account_id = ?", id).Delete(&model.ApiKey{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Unscoped().Where("id = ?", id).Delete(&model.ServiceAccount{})
+		if res.Error == nil && res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return res.Error
+	})
+}
+
+func (r *Repository) FindAllApiKey(ctx context.Context, serviceAccountID string, result *[]*model.ApiKey) error {
+	return r.db.WithContext(ctx).Find(&result, "service_account_id = ?", serviceAccountID).Error
+}
+
+func (r *Repository) FindApiKeyByPrefix(ctx context.Context, prefix string, result *model.ApiKey) error {
+	return r.db.WithContext(ctx).First(&result, "prefix = ?", prefix).Error
+}
+
+func (r *Repository) CreateApiKey(ctx context.Context, in *model.ApiKey) error {
+	return r.db.WithContext(ctx).Create(&in).Error
+}
+
+func (r *Repository) DeleteApiKey(ctx context.Context, id string, result *model.ApiKey) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&result, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Where("id = ?", id).Delete(&model.ApiKey{}).Error
+	})
+}
+
+func (r *Repository) UpdateApiKeyLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.ApiKey{}).Where("id = ?", id).Update("last_used_at", lastUsedAt).Error
+}
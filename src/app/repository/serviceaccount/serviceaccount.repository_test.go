@@ -0,0 +1,117 @@
+package serviceaccount
+
+import (
+	"context"
+	"testing"
+
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/serviceaccount"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/database"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type ServiceAccountRepositoryTest struct {
+	suite.Suite
+	db             *gorm.DB
+	Repo           *Repository
+	ServiceAccount *model.ServiceAccount
+	ApiKey         *model.ApiKey
+}
+
+func TestServiceAccountRepository(t *testing.T) {
+	suite.Run(t, new(ServiceAccountRepositoryTest))
+}
+
+func (t *ServiceAccountRepositoryTest) SetupTest() {
+	db, err := database.InitDatabase(&config.Database{Driver: database.SQLite, Name: ":memory:"})
+	t.Require().Nil(err)
+
+	m, err := database.NewMigrator(db)
+	t.Require().Nil(err)
+
+	_, err = m.Up()
+	t.Require().Nil(err)
+
+	t.db = db
+	t.Repo = NewRepository(db)
+	t.ServiceAccount = &model.ServiceAccount{
+		Name: faker.Username(),
+		Role: string(auth.SERVICE),
+	}
+	t.Require().Nil(t.Repo.Create(context.Background(), t.ServiceAccount))
+
+	t.ApiKey = &model.ApiKey{
+		ServiceAccountID: t.ServiceAccount.ID.String(),
+		Prefix:           faker.Word(),
+		Secret:           faker.Password(),
+	}
+	t.Require().Nil(t.Repo.CreateApiKey(context.Background(), t.ApiKey))
+}
+
+func (t *ServiceAccountRepositoryTest) TearDownTest() {
+	sqlDb, _ := t.db.DB()
+	_ = sqlDb.Close()
+}
+
+func (t *ServiceAccountRepositoryTest) TestDelete() {
+	err := t.Repo.Delete(context.Background(), t.ServiceAccount.ID.String())
+	assert.Nil(t.T(), err)
+
+	var keys []*model.ApiKey
+	err = t.Repo.FindAllApiKey(context.Background(), t.ServiceAccount.ID.String(), &keys)
+
+	assert.Nil(t.T(), err)
+	assert.Empty(t.T(), keys)
+}
+
+func (t *ServiceAccountRepositoryTest) TestCreateDuplicatedName() {
+	err := t.Repo.Create(context.Background(), &model.ServiceAccount{Name: t.ServiceAccount.Name, Role: string(auth.SERVICE)})
+
+	assert.Equal(t.T(), gorm.ErrDuplicatedKey, err)
+}
+
+func (t *ServiceAccountRepositoryTest) TestCreateAfterDelete() {
+	t.Require().Nil(t.Repo.Delete(context.Background(), t.ServiceAccount.ID.String()))
+
+	err := t.Repo.Create(context.Background(), &model.ServiceAccount{Name: t.ServiceAccount.Name, Role: string(auth.SERVICE)})
+	assert.Nil(t.T(), err)
+
+	err = t.Repo.CreateApiKey(context.Background(), &model.ApiKey{Prefix: t.ApiKey.Prefix, Secret: faker.Password()})
+	assert.Nil(t.T(), err)
+}
+
+func (t *ServiceAccountRepositoryTest) TestUpdateNotFound() {
+	err := t.Repo.Update(context.Background(), faker.UUIDHyphenated(), &model.ServiceAccount{Name: faker.Username()})
+
+	assert.Equal(t.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (t *ServiceAccountRepositoryTest) TestDeleteNotFound() {
+	err := t.Repo.Delete(context.Background(), faker.UUIDHyphenated())
+
+	assert.Equal(t.T(), gorm.ErrRecordNotFound, err)
+}
+
+func (t *ServiceAccountRepositoryTest) TestDeleteApiKey() {
+	key := model.ApiKey{}
+	err := t.Repo.DeleteApiKey(context.Background(), t.ApiKey.ID.String(), &key)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.ServiceAccount.ID.String(), key.ServiceAccountID)
+
+	var keys []*model.ApiKey
+	err = t.Repo.FindAllApiKey(context.Background(), t.ServiceAccount.ID.String(), &keys)
+
+	assert.Nil(t.T(), err)
+	assert.Empty(t.T(), keys)
+}
+
+func (t *ServiceAccountRepositoryTest) TestDeleteApiKeyNotFound() {
+	err := t.Repo.DeleteApiKey(context.Background(), faker.UUIDHyphenated(), &model.ApiKey{})
+
+	assert.Equal(t.T(), gorm.ErrRecordNotFound, err)
+}
@@ -3,51 +3,123 @@ package auth
 import (
 	"context"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	oidcDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oidc"
+	"github.com/bookpanda/mygraderlist-auth/src/app/metrics"
+	auditModel "github.com/bookpanda/mygraderlist-auth/src/app/model/audit"
 	model "github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/app/repository/cache"
+	sa "github.com/bookpanda/mygraderlist-auth/src/app/service/serviceaccount"
+	ts "github.com/bookpanda/mygraderlist-auth/src/app/service/token"
 	"github.com/bookpanda/mygraderlist-auth/src/app/utils"
 	"github.com/bookpanda/mygraderlist-auth/src/client"
 	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/bookpanda/mygraderlist-auth/src/constant/audit"
 	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
-	auth_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
 	user_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/backend/user"
-	"github.com/rs/zerolog/log"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 	"golang.org/x/oauth2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const (
+	revocationBatch = 100
+	// maxValidateBatch keeps a single call from holding the cache for long
+	maxValidateBatch = 100
 )
 
 type Service struct {
 	repo              IRepository
+	auditRepo         IAuditRepository
+	revocationRepo    IRevocationRepository
 	tokenService      ITokenService
+	apiKeyService     IApiKeyService
+	clientService     IClientService
 	userService       IUserService
 	conf              config.App
 	oauthConfig       *oauth2.Config
 	googleOauthClient *client.GoogleOauthClient
+	// shutdown ends the revocation streams, which would otherwise hold the graceful stop forever
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
 }
 
 type IRepository interface {
-	FindByRefreshToken(string, *model.Auth) error
-	FindByUserID(string, *model.Auth) error
-	Create(*model.Auth) error
-	Update(string, *model.Auth) error
+	FindByRefreshToken(context.Context, string, *model.Auth) error
+	FindByUserID(context.Context, string, *model.Auth) error
+	Create(context.Context, *model.Auth) error
+	Update(context.Context, string, *model.Auth) error
+	UpdateStatus(context.Context, string, *model.Auth) error
+	UpdateRole(context.Context, string, *model.Auth) error
+	ClearRefreshToken(context.Context, string) error
+	CreateDeviceSession(context.Context, *model.DeviceSession) error
+	FindDeviceSession(context.Context, string, *model.DeviceSession) error
+	FindDeviceSessionByRefreshToken(context.Context, string, *model.DeviceSession) error
+	UpdateDeviceSessionRefreshToken(context.Context, string, string) error
+	DeleteDeviceSession(context.Context, string) error
+	FindDeviceSessions(context.Context, string, *[]*model.DeviceSession) error
+	DeleteDeviceSessions(context.Context, string, *[]*model.DeviceSession) error
+}
+
+type IAuditRepository interface {
+	Create(context.Context, *auditModel.Audit) error
+}
+
+type IRevocationRepository interface {
+	Append(context.Context, *dto.RevocationEvent) (string, error)
+	Seek(context.Context, string) (string, error)
+	Read(context.Context, string, int64) ([]*dto.RevocationEvent, error)
+}
+
+type IApiKeyService interface {
+	ValidateApiKey(context.Context, string) (*dto.UserCredential, error)
+}
+
+type IClientService interface {
+	Authenticate(string, string) (*config.Client, error)
+	Identify(string, string) (*config.Client, error)
 }
 
 type IUserService interface {
-	FindByEmail(string) (*user_proto.User, error)
-	Create(*user_proto.User) (*user_proto.User, error)
+	FindByEmail(context.Context, string) (*user_proto.User, error)
+	Create(context.Context, *user_proto.User) (*user_proto.User, error)
 }
 
 type ITokenService interface {
-	CreateCredentials(*model.Auth, string) (*auth_proto.Credential, error)
-	Validate(string) (*dto.UserCredential, error)
+	CreateCredentials(context.Context, *model.Auth, string) (*auth_proto.Credential, error)
+	Validate(context.Context, string) (*dto.UserCredential, error)
+	ValidateBatch(context.Context, []string) ([]*dto.UserCredential, []error)
+	RevokeCredentials(context.Context, *model.Auth) error
+	RemoveCredentials(context.Context, string) error
+	CreateDeviceCredentials(context.Context, *model.Auth, string) (*auth_proto.Credential, error)
+	RemoveDeviceCredentials(context.Context, string) error
+	CreateImpersonationCredentials(context.Context, *model.Auth, string) (*auth_proto.Credential, error)
+	ImpersonatedUserID(context.Context, string) (string, error)
+	RemoveImpersonationCredentials(context.Context, string) error
+	RevokeImpersonations(context.Context, string) ([]string, error)
+	CreateServiceCredentials(string, []string, []string) (*auth_proto.Credential, error)
+	ValidateOidcAccessToken(context.Context, string) (*oidcDto.CacheAccessToken, error)
+	RemoveOidcAccessToken(context.Context, string) error
+	RevokeOidcAccessTokens(context.Context, string) error
 }
 
 func NewService(
 	repo IRepository,
+	auditRepo IAuditRepository,
+	revocationRepo IRevocationRepository,
 	tokenService ITokenService,
+	apiKeyService IApiKeyService,
+	clientService IClientService,
 	userService IUserService,
 	conf config.App,
 	oauthConfig *oauth2.Config,
@@ -55,16 +127,62 @@ func NewService(
 ) *Service {
 	return &Service{
 		repo:              repo,
+		auditRepo:         auditRepo,
+		revocationRepo:    revocationRepo,
 		tokenService:      tokenService,
+		apiKeyService:     apiKeyService,
+		clientService:     clientService,
 		userService:       userService,
 		conf:              conf,
 		oauthConfig:       oauthConfig,
 		googleOauthClient: googleOauthClient,
+		shutdown:          make(chan struct{}),
+	}
+}
+
+func (s *Service) Validate(ctx context.Context, req *auth_proto.ValidateRequest) (res *auth_proto.ValidateResponse, err error) {
+	if sa.IsApiKey(req.Token) {
+		return s.validateApiKey(ctx, req.Token)
+	}
+
+	credential, err := s.tokenService.Validate(ctx, req.Token)
+
+	return validateResponse(credential, err, req.Audience)
+}
+
+// ValidateBatch validates every token of the request like Validate does, the user tokens with a
+// single cache read. A token failing doesn't fail the others, its result has the error instead.
+func (s *Service) ValidateBatch(ctx context.Context, req *auth_proto.ValidateBatchRequest) (*auth_proto.ValidateBatchResponse, error) {
+	if len(req.Requests) > maxValidateBatch {
+		return nil, status.Errorf(codes.InvalidArgument, "At most %d tokens can be validated at once", maxValidateBatch)
+	}
+
+	results := make([]*auth_proto.ValidateResult, len(req.Requests))
+
+	var tokens []string
+	var indexes []int
+	for i, r := range req.Requests {
+		if sa.IsApiKey(r.Token) {
+			results[i] = validateResult(s.validateApiKey(ctx, r.Token))
+			continue
+		}
+
+		tokens = append(tokens, r.Token)
+		indexes = append(indexes, i)
+	}
+
+	if len(tokens) > 0 {
+		credentials, errs := s.tokenService.ValidateBatch(ctx, tokens)
+		for j, i := range indexes {
+			results[i] = validateResult(validateResponse(credentials[j], errs[j], req.Requests[i].Audience))
+		}
 	}
+
+	return &auth_proto.ValidateBatchResponse{Results: results}, nil
 }
 
-func (s *Service) Validate(_ context.Context, req *auth_proto.ValidateRequest) (res *auth_proto.ValidateResponse, err error) {
-	credential, err := s.tokenService.Validate(req.Token)
+func (s *Service) validateApiKey(ctx context.Context, key string) (*auth_proto.ValidateResponse, error) {
+	credential, err := s.apiKeyService.ValidateApiKey(ctx, key)
 	if err != nil {
 		return nil, status.Error(codes.Unauthenticated, err.Error())
 	}
@@ -72,20 +190,80 @@ func (s *Service) Validate(_ context.Context, req *auth_proto.ValidateRequest) (
 	return &auth_proto.ValidateResponse{
 		UserId: credential.UserId,
 		Role:   string(credential.Role),
+		Scopes: credential.Scopes,
+	}, nil
+}
+
+// validateResponse maps the result of validating a token to the response or the status of Validate
+func validateResponse(credential *dto.UserCredential, err error, audience string) (*auth_proto.ValidateResponse, error) {
+	if err != nil {
+		var statusErr *ts.AccountStatusError
+		switch {
+		case errors.As(err, &statusErr):
+			return nil, accountStatusError(statusErr.Status, statusErr.Until, statusErr.Reason)
+		case errors.Is(err, ts.ErrAccountSuspended):
+			return nil, accountStatusError(role.SUSPENDED, nil, "")
+		case errors.Is(err, ts.ErrAccountBanned):
+			return nil, accountStatusError(role.BANNED, nil, "")
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	// a service token is only good for the audience it was issued for, the caller has to name it
+	if credential.Role == role.SERVICE && (audience == "" || !slices.Contains(credential.Audience, audience)) {
+		return nil, status.Error(codes.Unauthenticated, "Invalid audience")
+	}
+
+	return &auth_proto.ValidateResponse{
+		UserId:  credential.UserId,
+		Role:    string(credential.Role),
+		ActorId: credential.ActorId,
+		Scopes:  credential.Scopes,
 	}, nil
 }
 
-func (s *Service) RefreshToken(_ context.Context, req *auth_proto.RefreshTokenRequest) (res *auth_proto.RefreshTokenResponse, err error) {
+// validateResult puts the status Validate would have failed with in the result of a batch
+func validateResult(res *auth_proto.ValidateResponse, err error) *auth_proto.ValidateResult {
+	if err == nil {
+		return &auth_proto.ValidateResult{Response: res}
+	}
+
+	st := status.Convert(err)
+	result := &auth_proto.ValidateResult{Error: &auth_proto.ValidateError{
+		Code:    int32(st.Code()),
+		Message: st.Message(),
+	}}
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			result.Error.Reason = info.Reason
+		}
+	}
+
+	return result
+}
+
+func (s *Service) RefreshToken(ctx context.Context, req *auth_proto.RefreshTokenRequest) (res *auth_proto.RefreshTokenResponse, err error) {
+	defer func() {
+		metrics.RefreshRotations.WithLabelValues(metrics.Outcome(err)).Inc()
+	}()
+
 	auth := model.Auth{}
 
-	err = s.repo.FindByRefreshToken(utils.Hash([]byte(req.RefreshToken)), &auth)
+	refreshToken := utils.Hash([]byte(req.RefreshToken))
+
+	err = s.repo.FindByRefreshToken(ctx, refreshToken, &auth)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid refresh token")
+		return s.refreshDeviceSession(ctx, refreshToken)
+	}
+
+	if err := checkAccountStatus(&auth); err != nil {
+		return nil, err
 	}
 
-	credentials, err := s.CreateNewCredential(&auth)
+	credentials, err := s.CreateNewCredential(ctx, &auth)
 	if err != nil {
-		log.Error().Err(err).
+		zerolog.Ctx(ctx).Error().Err(err).
 			Str("service", "auth").
 			Str("module", "refresh token").
 			Msg("Error while create new token")
@@ -95,8 +273,8 @@ func (s *Service) RefreshToken(_ context.Context, req *auth_proto.RefreshTokenRe
 	return &auth_proto.RefreshTokenResponse{Credential: credentials}, nil
 }
 
-func (s *Service) CreateNewCredential(auth *model.Auth) (*auth_proto.Credential, error) {
-	credentials, err := s.tokenService.CreateCredentials(auth, s.conf.Secret)
+func (s *Service) CreateNewCredential(ctx context.Context, auth *model.Auth) (*auth_proto.Credential, error) {
+	credentials, err := s.tokenService.CreateCredentials(ctx, auth, s.conf.Secret)
 
 	if err != nil {
 		return nil, err
@@ -104,7 +282,95 @@ func (s *Service) CreateNewCredential(auth *model.Auth) (*auth_proto.Credential,
 
 	auth.RefreshToken = utils.Hash([]byte(credentials.RefreshToken))
 
-	err = s.repo.Update(auth.ID.String(), auth)
+	err = s.repo.Update(ctx, auth.ID.String(), auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// refreshDeviceSession rotates the refresh token of a device session, the refresh token is hashed
+func (s *Service) refreshDeviceSession(ctx context.Context, refreshToken string) (*auth_proto.RefreshTokenResponse, error) {
+	session := model.DeviceSession{}
+
+	err := s.repo.FindDeviceSessionByRefreshToken(ctx, refreshToken, &session)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid refresh token")
+	}
+
+	auth := model.Auth{}
+
+	err = s.repo.FindByUserID(ctx, session.UserID, &auth)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid refresh token")
+	}
+
+	if err := checkAccountStatus(&auth); err != nil {
+		return nil, err
+	}
+
+	credentials, err := s.createDeviceCredential(ctx, &auth, &session)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "refresh token").
+			Msg("Error while create new device token")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &auth_proto.RefreshTokenResponse{Credential: credentials}, nil
+}
+
+// IssueDeviceCredential signs the user in on a device through the device authorization grant. The
+// device gets a session of its own, so it doesn't sign the user out of the browser or the other way round.
+func (s *Service) IssueDeviceCredential(ctx context.Context, userID string, clientID string) (*auth_proto.Credential, error) {
+	auth := model.Auth{}
+
+	err := s.repo.FindByUserID(ctx, userID, &auth)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	if err := checkAccountStatus(&auth); err != nil {
+		return nil, err
+	}
+
+	session := &model.DeviceSession{
+		UserID:   auth.UserID,
+		ClientID: clientID,
+	}
+
+	err = s.repo.CreateDeviceSession(ctx, session)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "issue device credential").
+			Msg("Error while creating the device session")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	credentials, err := s.createDeviceCredential(ctx, &auth, session)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "issue device credential").
+			Msg("Error while create new token")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return credentials, nil
+}
+
+func (s *Service) createDeviceCredential(ctx context.Context, auth *model.Auth, session *model.DeviceSession) (*auth_proto.Credential, error) {
+	credentials, err := s.tokenService.CreateDeviceCredentials(ctx, auth, session.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	session.RefreshToken = utils.Hash([]byte(credentials.RefreshToken))
+
+	err = s.repo.UpdateDeviceSessionRefreshToken(ctx, session.ID.String(), session.RefreshToken)
 	if err != nil {
 		return nil, err
 	}
@@ -112,10 +378,10 @@ func (s *Service) CreateNewCredential(auth *model.Auth) (*auth_proto.Credential,
 	return credentials, nil
 }
 
-func (s *Service) GetGoogleLoginUrl(context.Context, *auth_proto.GetGoogleLoginUrlRequest) (*auth_proto.GetGoogleLoginUrlResponse, error) {
+func (s *Service) GetGoogleLoginUrl(ctx context.Context, _ *auth_proto.GetGoogleLoginUrlRequest) (*auth_proto.GetGoogleLoginUrlResponse, error) {
 	URL, err := url.Parse(s.oauthConfig.Endpoint.AuthURL)
 	if err != nil {
-		log.Error().Err(err).Msg("unable to parse url")
+		zerolog.Ctx(ctx).Error().Err(err).Msg("unable to parse url")
 		return nil, status.Error(codes.Internal, "Internal server error")
 	}
 	parameters := url.Values{}
@@ -131,27 +397,52 @@ func (s *Service) GetGoogleLoginUrl(context.Context, *auth_proto.GetGoogleLoginU
 	}, nil
 }
 
-func (s *Service) VerifyGoogleLogin(ctx context.Context, req *auth_proto.VerifyGoogleLoginRequest) (*auth_proto.VerifyGoogleLoginResponse, error) {
+func (s *Service) VerifyGoogleLogin(ctx context.Context, req *auth_proto.VerifyGoogleLoginRequest) (res *auth_proto.VerifyGoogleLoginResponse, err error) {
+	defer func() {
+		metrics.Logins.WithLabelValues("google", metrics.Outcome(err)).Inc()
+	}()
+
 	code := req.GetCode()
-	auth := model.Auth{}
 
 	if code == "" {
 		return nil, status.Error(codes.InvalidArgument, "No code is provided")
 	}
 
-	response, err := s.googleOauthClient.GetUserEmail(code)
+	response, err := s.googleOauthClient.GetUserEmail(ctx, code)
 	if err != nil {
 		switch err.Error() {
 		case "Invalid code":
 			return nil, status.Error(codes.InvalidArgument, "Invalid code")
 		default:
-			log.Error().Err(err).Msg("Unable to get user info")
+			zerolog.Ctx(ctx).Error().Err(err).Msg("Unable to get user info")
 			return nil, status.Error(codes.Internal, "Internal server error")
 		}
 	}
 
+	auth, err := s.FindOrCreateGoogleUser(ctx, response)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := s.CreateNewCredential(ctx, auth)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	zerolog.Ctx(ctx).Info().
+		Str("service", "auth").
+		Msg("User login to the service")
+
+	return &auth_proto.VerifyGoogleLoginResponse{Credential: credentials}, err
+}
+
+// FindOrCreateGoogleUser returns the account linked to the google profile, registering the user on their first login
+func (s *Service) FindOrCreateGoogleUser(ctx context.Context, response *client.GoogleUserEmailResponse) (*model.Auth, error) {
+	auth := model.Auth{}
+
 	email := response.Email
-	user, err := s.userService.FindByEmail(email)
+	user, err := s.userService.FindByEmail(ctx, email)
 	if err != nil {
 		st, ok := status.FromError(err)
 		if ok {
@@ -162,7 +453,7 @@ func (s *Service) VerifyGoogleLogin(ctx context.Context, req *auth_proto.VerifyG
 					Username: response.Firstname,
 				}
 
-				user, err = s.userService.Create(in)
+				user, err = s.userService.Create(ctx, in)
 				if err != nil {
 					return nil, status.Error(codes.InvalidArgument, st.Message())
 				}
@@ -172,9 +463,9 @@ func (s *Service) VerifyGoogleLogin(ctx context.Context, req *auth_proto.VerifyG
 					UserID: user.Id,
 				}
 
-				err = s.repo.Create(&auth)
+				err = s.repo.Create(ctx, &auth)
 				if err != nil {
-					log.Error().
+					zerolog.Ctx(ctx).Error().
 						Err(err).
 						Str("service", "auth").
 						Str("module", "google").
@@ -183,7 +474,7 @@ func (s *Service) VerifyGoogleLogin(ctx context.Context, req *auth_proto.VerifyG
 				}
 
 			default:
-				log.Error().
+				zerolog.Ctx(ctx).Error().
 					Err(err).
 					Str("service", "auth").
 					Str("module", "google").
@@ -191,7 +482,7 @@ func (s *Service) VerifyGoogleLogin(ctx context.Context, req *auth_proto.VerifyG
 				return nil, status.Error(codes.Unavailable, st.Message())
 			}
 		} else {
-			log.Error().
+			zerolog.Ctx(ctx).Error().
 				Err(err).
 				Str("service", "auth").
 				Str("module", "google").
@@ -199,21 +490,931 @@ func (s *Service) VerifyGoogleLogin(ctx context.Context, req *auth_proto.VerifyG
 			return nil, status.Error(codes.Unavailable, "Service is down")
 		}
 	} else {
-		err := s.repo.FindByUserID(user.Id, &auth)
+		err := s.repo.FindByUserID(ctx, user.Id, &auth)
 		if err != nil {
 			return nil, status.Error(codes.NotFound, "not found user")
 		}
+
+		if err := checkAccountStatus(&auth); err != nil {
+			return nil, err
+		}
+	}
+
+	return &auth, nil
+}
+
+// FindActiveAccount returns the account of the user, the suspended and banned ones fail with their status
+func (s *Service) FindActiveAccount(ctx context.Context, userID string) (*model.Auth, error) {
+	auth := model.Auth{}
+
+	err := s.repo.FindByUserID(ctx, userID, &auth)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "not found user")
+		}
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "account status").
+			Msg("Error while finding the account")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	if err := checkAccountStatus(&auth); err != nil {
+		return nil, err
+	}
+
+	return &auth, nil
+}
+
+func (s *Service) SuspendAccount(ctx context.Context, req *auth_proto.SuspendAccountRequest) (*auth_proto.SuspendAccountResponse, error) {
+	if _, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN); err != nil {
+		return nil, err
+	}
+
+	until := time.Unix(req.Until, 0)
+	if req.UserId == "" || !until.After(time.Now()) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid suspension")
+	}
+
+	auth, err := s.changeAccountStatus(ctx, req.UserId, role.SUSPENDED, &until, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth_proto.SuspendAccountResponse{Account: rawToAccountDto(auth)}, nil
+}
+
+func (s *Service) BanAccount(ctx context.Context, req *auth_proto.BanAccountRequest) (*auth_proto.BanAccountResponse, error) {
+	if _, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN); err != nil {
+		return nil, err
+	}
+
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "No user id is provided")
+	}
+
+	auth, err := s.changeAccountStatus(ctx, req.UserId, role.BANNED, nil, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth_proto.BanAccountResponse{Account: rawToAccountDto(auth)}, nil
+}
+
+func (s *Service) ReinstateAccount(ctx context.Context, req *auth_proto.ReinstateAccountRequest) (*auth_proto.ReinstateAccountResponse, error) {
+	if _, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN); err != nil {
+		return nil, err
+	}
+
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "No user id is provided")
+	}
+
+	auth, err := s.changeAccountStatus(ctx, req.UserId, role.ACTIVE, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth_proto.ReinstateAccountResponse{Account: rawToAccountDto(auth)}, nil
+}
+
+// ChangeRole promotes or demotes the user. The cached sessions are dropped, so the clients refresh
+// them with the new role, and the impersonations of the user end.
+func (s *Service) ChangeRole(ctx context.Context, req *auth_proto.ChangeRoleRequest) (*auth_proto.ChangeRoleResponse, error) {
+	actor, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.UserId == "" || (req.Role != string(role.USER) && req.Role != string(role.ADMIN)) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid role")
+	}
+
+	if req.UserId == actor.UserId {
+		return nil, status.Error(codes.InvalidArgument, "Cannot change your own role")
+	}
+
+	auth := model.Auth{}
+
+	err = s.repo.FindByUserID(ctx, req.UserId, &auth)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	if auth.Role == req.Role {
+		return &auth_proto.ChangeRoleResponse{Account: rawToAccountDto(&auth)}, nil
+	}
+
+	auth.Role = req.Role
+
+	err = s.repo.UpdateRole(ctx, auth.ID.String(), &auth)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "role").
+			Msg("Error while updating the role")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	if err := s.dropSessions(ctx, auth.UserID); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	s.publishRevocation(ctx, &dto.RevocationEvent{
+		Type:   role.ROLE_CHANGED,
+		UserID: auth.UserID,
+		Role:   auth.Role,
+	})
+
+	zerolog.Ctx(ctx).Info().
+		Str("service", "auth").
+		Str("module", "role").
+		Str("actor_id", actor.UserId).
+		Str("user_id", auth.UserID).
+		Str("role", auth.Role).
+		Msg("Role changed")
+
+	return &auth_proto.ChangeRoleResponse{Account: rawToAccountDto(&auth)}, nil
+}
+
+// dropSessions removes the cached sessions of the user on the browser and every device, their refresh
+// tokens are kept
+func (s *Service) dropSessions(ctx context.Context, userID string) error {
+	if err := s.tokenService.RemoveCredentials(ctx, userID); err != nil {
+		return err
+	}
+
+	var sessions []*model.DeviceSession
+
+	err := s.repo.FindDeviceSessions(ctx, userID, &sessions)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "role").
+			Msg("Error while finding the device sessions")
+		return errors.New("Internal service error")
+	}
+
+	for _, session := range sessions {
+		if err := s.tokenService.RemoveDeviceCredentials(ctx, session.ID.String()); err != nil {
+			return err
+		}
+	}
+
+	return s.endImpersonations(ctx, userID)
+}
+
+func (s *Service) Impersonate(ctx context.Context, req *auth_proto.ImpersonateRequest) (*auth_proto.ImpersonateResponse, error) {
+	actor, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN)
+	if err != nil {
+		return nil, err
 	}
 
-	credentials, err := s.CreateNewCredential(&auth)
+	if req.TargetUserId == "" || req.TargetUserId == actor.UserId {
+		return nil, status.Error(codes.InvalidArgument, "Invalid impersonation target")
+	}
+
+	target := model.Auth{}
+
+	err = s.repo.FindByUserID(ctx, req.TargetUserId, &target)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	if target.Role == string(role.ADMIN) {
+		return nil, status.Error(codes.PermissionDenied, "Cannot impersonate an admin")
+	}
+
+	if err := checkAccountStatus(&target); err != nil {
+		return nil, err
+	}
+
+	// the new impersonation replaces the one the actor may still hold
+	replaced, err := s.tokenService.ImpersonatedUserID(ctx, actor.UserId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	credentials, err := s.tokenService.CreateImpersonationCredentials(ctx, &target, actor.UserId)
 	if err != nil {
-		log.Error().Err(err)
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	log.Info().
+	if replaced != "" {
+		s.publishRevocation(ctx, &dto.RevocationEvent{
+			Type:    role.SESSION_REVOKED,
+			UserID:  replaced,
+			ActorID: actor.UserId,
+		})
+
+		if err := s.writeAudit(ctx, actor.UserId, replaced, audit.IMPERSONATION_END); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.writeAudit(ctx, actor.UserId, target.UserID, audit.IMPERSONATION_START); err != nil {
+		return nil, err
+	}
+
+	zerolog.Ctx(ctx).Info().
 		Str("service", "auth").
-		Msg("User login to the service")
+		Str("module", "impersonation").
+		Str("actor_id", actor.UserId).
+		Str("user_id", target.UserID).
+		Msg("Impersonation started")
 
-	return &auth_proto.VerifyGoogleLoginResponse{Credential: credentials}, err
+	return &auth_proto.ImpersonateResponse{Credential: credentials}, nil
+}
+
+func (s *Service) EndImpersonation(ctx context.Context, req *auth_proto.EndImpersonationRequest) (*auth_proto.EndImpersonationResponse, error) {
+	credential, err := s.tokenService.Validate(ctx, req.Token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if credential.ActorId == "" {
+		return nil, status.Error(codes.InvalidArgument, "Not an impersonation token")
+	}
+
+	err = s.tokenService.RemoveImpersonationCredentials(ctx, credential.ActorId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.publishRevocation(ctx, &dto.RevocationEvent{
+		Type:    role.SESSION_REVOKED,
+		UserID:  credential.UserId,
+		ActorID: credential.ActorId,
+	})
+
+	if err := s.writeAudit(ctx, credential.ActorId, credential.UserId, audit.IMPERSONATION_END); err != nil {
+		return nil, err
+	}
+
+	zerolog.Ctx(ctx).Info().
+		Str("service", "auth").
+		Str("module", "impersonation").
+		Str("actor_id", credential.ActorId).
+		Str("user_id", credential.UserId).
+		Msg("Impersonation ended")
+
+	return &auth_proto.EndImpersonationResponse{Success: true}, nil
+}
+
+func (s *Service) IssueServiceToken(ctx context.Context, req *auth_proto.IssueServiceTokenRequest) (*auth_proto.IssueServiceTokenResponse, error) {
+	client, err := s.clientService.Authenticate(req.ClientId, req.ClientSecret)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if req.Audience == "" || !slices.Contains(client.Audiences, req.Audience) {
+		return nil, status.Error(codes.PermissionDenied, "Audience is not allowed for this client")
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return nil, status.Errorf(codes.PermissionDenied, "Scope %v is not allowed for this client", scope)
+		}
+	}
+
+	credentials, err := s.tokenService.CreateServiceCredentials(client.ClientID, []string{req.Audience}, scopes)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "service token").
+			Str("client_id", client.ClientID).
+			Msg("Error while creating the service token")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	return &auth_proto.IssueServiceTokenResponse{Credential: credentials}, nil
+}
+
+// Introspect reports whether a token is currently active following RFC 7662. Any failure to
+// validate the token is reported as an inactive token rather than an error.
+func (s *Service) Introspect(ctx context.Context, req *auth_proto.IntrospectRequest) (*auth_proto.IntrospectResponse, error) {
+	if _, err := s.clientService.Authenticate(req.ClientId, req.ClientSecret); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if req.Token == "" {
+		return &auth_proto.IntrospectResponse{Active: false}, nil
+	}
+
+	if req.TokenTypeHint == "refresh_token" {
+		if res := s.introspectRefreshToken(ctx, req.Token); res != nil {
+			return res, nil
+		}
+	}
+
+	var credential *dto.UserCredential
+	var err error
+	if sa.IsApiKey(req.Token) {
+		credential, err = s.apiKeyService.ValidateApiKey(ctx, req.Token)
+	} else {
+		credential, err = s.tokenService.Validate(ctx, req.Token)
+	}
+	if err != nil {
+		if !sa.IsApiKey(req.Token) {
+			if res := s.introspectOidcAccessToken(ctx, req.Token); res != nil {
+				return res, nil
+			}
+		}
+		if req.TokenTypeHint != "refresh_token" {
+			if res := s.introspectRefreshToken(ctx, req.Token); res != nil {
+				return res, nil
+			}
+		}
+		return &auth_proto.IntrospectResponse{Active: false}, nil
+	}
+
+	// like Validate, a service token is only active for the audience it was issued for, which has to be
+	// the client introspecting it
+	if !sa.IsApiKey(req.Token) {
+		if _, err := validateResponse(credential, nil, req.ClientId); err != nil {
+			return &auth_proto.IntrospectResponse{Active: false}, nil
+		}
+	}
+
+	res := &auth_proto.IntrospectResponse{
+		Active:  true,
+		Sub:     credential.UserId,
+		Exp:     credential.ExpiresAt,
+		Iat:     credential.IssuedAt,
+		Scope:   strings.Join(credential.Scopes, " "),
+		Role:    string(credential.Role),
+		ActorId: credential.ActorId,
+	}
+	if credential.Role == role.SERVICE && len(credential.Audience) > 0 {
+		res.ClientId = credential.UserId
+	}
+
+	return res, nil
+}
+
+// Revoke invalidates an access or refresh token following RFC 7009. Unknown or already invalid
+// tokens are not an error, so the caller can't use this endpoint to probe for valid tokens. A client
+// can only revoke the tokens issued to it (section 2.1), the service tokens of its own, the device
+// sessions it started and the access tokens the OIDC provider issued to it, the others are left alone.
+// The sessions of the browser are ended by logging out.
+func (s *Service) Revoke(ctx context.Context, req *auth_proto.RevokeRequest) (*auth_proto.RevokeResponse, error) {
+	if _, err := s.clientService.Identify(req.ClientId, req.ClientSecret); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if req.Token == "" || sa.IsApiKey(req.Token) {
+		return &auth_proto.RevokeResponse{}, nil
+	}
+
+	if req.TokenTypeHint == "refresh_token" {
+		revoked, err := s.revokeRefreshToken(ctx, req.Token, req.ClientId)
+		if err != nil || revoked {
+			return &auth_proto.RevokeResponse{}, err
+		}
+	}
+
+	revoked, err := s.revokeAccessToken(ctx, req.Token, req.ClientId)
+	if err != nil || revoked {
+		return &auth_proto.RevokeResponse{}, err
+	}
+
+	if req.TokenTypeHint != "refresh_token" {
+		if _, err := s.revokeRefreshToken(ctx, req.Token, req.ClientId); err != nil {
+			return nil, err
+		}
+	}
+
+	return &auth_proto.RevokeResponse{}, nil
+}
+
+// Logout ends the browser session on the server, the refresh token is cleared and the access token
+// removed from the cache before the gateway drops the cookies. Like Revoke, tokens that are already
+// invalid are not an error.
+func (s *Service) Logout(ctx context.Context, req *auth_proto.LogoutRequest) (*auth_proto.LogoutResponse, error) {
+	if req.RefreshToken != "" {
+		if _, err := s.revokeRefreshToken(ctx, req.RefreshToken, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.AccessToken != "" {
+		if _, err := s.revokeAccessToken(ctx, req.AccessToken, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return &auth_proto.LogoutResponse{}, nil
+}
+
+// revokeAccessToken removes the session of the token when it was issued to the client, it's reported
+// as revoked either way since the token is known
+func (s *Service) revokeAccessToken(ctx context.Context, token string, clientID string) (bool, error) {
+	credential, err := s.tokenService.Validate(ctx, token)
+	if err != nil {
+		if clientID == "" {
+			return false, nil
+		}
+		return s.revokeOidcAccessToken(ctx, token, clientID)
+	}
+
+	if issuedTo, err := s.issuedTo(ctx, credential); err != nil || issuedTo != clientID {
+		logRevokeMismatch(ctx, credential.UserId, clientID)
+		return true, nil
+	}
+
+	switch {
+	case credential.ActorId != "":
+		err = s.tokenService.RemoveImpersonationCredentials(ctx, credential.ActorId)
+	case credential.Role == role.SERVICE:
+		// service tokens are stateless and short-lived, there is no session to remove
+		return true, nil
+	case credential.SessionId != "":
+		err = s.tokenService.RemoveDeviceCredentials(ctx, credential.SessionId)
+	default:
+		err = s.tokenService.RemoveCredentials(ctx, credential.UserId)
+	}
+	if err != nil {
+		return false, status.Error(codes.Unavailable, err.Error())
+	}
+	s.publishRevocation(ctx, &dto.RevocationEvent{
+		Type:    role.SESSION_REVOKED,
+		UserID:  credential.UserId,
+		ActorID: credential.ActorId,
+	})
+
+	zerolog.Ctx(ctx).Info().
+		Str("service", "auth").
+		Str("module", "revoke").
+		Str("user_id", credential.UserId).
+		Msg("Access token revoked")
+
+	return true, nil
+}
+
+// revokeOidcAccessToken removes the opaque access token of the OIDC provider when it was issued to the client
+func (s *Service) revokeOidcAccessToken(ctx context.Context, token string, clientID string) (bool, error) {
+	grant, err := s.tokenService.ValidateOidcAccessToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, ts.ErrInvalidAccessToken) {
+			return false, nil
+		}
+		return false, status.Error(codes.Unavailable, err.Error())
+	}
+
+	if grant.ClientID != clientID {
+		logRevokeMismatch(ctx, grant.UserID, clientID)
+		return true, nil
+	}
+
+	if err := s.tokenService.RemoveOidcAccessToken(ctx, token); err != nil {
+		return false, status.Error(codes.Unavailable, err.Error())
+	}
+
+	zerolog.Ctx(ctx).Info().
+		Str("service", "auth").
+		Str("module", "revoke").
+		Str("user_id", grant.UserID).
+		Str("client_id", grant.ClientID).
+		Msg("OIDC access token revoked")
+
+	return true, nil
+}
+
+// revokeRefreshToken removes the refresh token and, as suggested by RFC 7009 section 2.1,
+// the access token issued alongside it. The refresh token of the account isn't issued to a client,
+// only the first party revokes it with an empty client id.
+func (s *Service) revokeRefreshToken(ctx context.Context, refreshToken string, clientID string) (bool, error) {
+	auth := model.Auth{}
+
+	err := s.repo.FindByRefreshToken(ctx, utils.Hash([]byte(refreshToken)), &auth)
+	if err != nil {
+		return s.revokeDeviceSession(ctx, utils.Hash([]byte(refreshToken)), clientID)
+	}
+
+	if clientID != "" {
+		logRevokeMismatch(ctx, auth.UserID, clientID)
+		return true, nil
+	}
+
+	if err := s.repo.ClearRefreshToken(ctx, auth.ID.String()); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "revoke").
+			Msg("Error while clearing the refresh token")
+		return false, status.Error(codes.Unavailable, "Service is unavailable")
+	}
+
+	if err := s.tokenService.RemoveCredentials(ctx, auth.UserID); err != nil {
+		return false, status.Error(codes.Unavailable, err.Error())
+	}
+	s.publishRevocation(ctx, &dto.RevocationEvent{
+		Type:   role.SESSION_REVOKED,
+		UserID: auth.UserID,
+	})
+
+	zerolog.Ctx(ctx).Info().
+		Str("service", "auth").
+		Str("module", "revoke").
+		Str("user_id", auth.UserID).
+		Msg("Refresh token revoked")
+
+	return true, nil
+}
+
+// revokeDeviceSession deletes the device session of the refresh token along with its access token
+func (s *Service) revokeDeviceSession(ctx context.Context, refreshToken string, clientID string) (bool, error) {
+	session := model.DeviceSession{}
+
+	err := s.repo.FindDeviceSessionByRefreshToken(ctx, refreshToken, &session)
+	if err != nil {
+		return false, nil
+	}
+
+	if session.ClientID != clientID {
+		logRevokeMismatch(ctx, session.UserID, clientID)
+		return true, nil
+	}
+
+	if err := s.repo.DeleteDeviceSession(ctx, session.ID.String()); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "revoke").
+			Msg("Error while deleting the device session")
+		return false, status.Error(codes.Unavailable, "Service is unavailable")
+	}
+
+	if err := s.tokenService.RemoveDeviceCredentials(ctx, session.ID.String()); err != nil {
+		return false, status.Error(codes.Unavailable, err.Error())
+	}
+	s.publishRevocation(ctx, &dto.RevocationEvent{
+		Type:   role.SESSION_REVOKED,
+		UserID: session.UserID,
+	})
+
+	zerolog.Ctx(ctx).Info().
+		Str("service", "auth").
+		Str("module", "revoke").
+		Str("user_id", session.UserID).
+		Msg("Device session revoked")
+
+	return true, nil
+}
+
+// issuedTo returns the client the credential was issued to, empty for the sessions of the first party
+func (s *Service) issuedTo(ctx context.Context, credential *dto.UserCredential) (string, error) {
+	switch {
+	case credential.ActorId != "":
+		return "", nil
+	case credential.Role == role.SERVICE:
+		return credential.UserId, nil
+	case credential.SessionId != "":
+		session := model.DeviceSession{}
+		if err := s.repo.FindDeviceSession(ctx, credential.SessionId, &session); err != nil {
+			return "", err
+		}
+		return session.ClientID, nil
+	default:
+		return "", nil
+	}
+}
+
+func logRevokeMismatch(ctx context.Context, userID string, clientID string) {
+	zerolog.Ctx(ctx).Warn().
+		Str("service", "auth").
+		Str("module", "revoke").
+		Str("user_id", userID).
+		Str("client_id", clientID).
+		Msg("Token was not issued to the client, skipped the revocation")
+}
+
+// introspectOidcAccessToken reports the opaque access token of the OIDC provider, nil when the token isn't one
+func (s *Service) introspectOidcAccessToken(ctx context.Context, token string) *auth_proto.IntrospectResponse {
+	grant, err := s.tokenService.ValidateOidcAccessToken(ctx, token)
+	if err != nil {
+		return nil
+	}
+
+	return &auth_proto.IntrospectResponse{
+		Active:   true,
+		Sub:      grant.UserID,
+		Exp:      grant.ExpiresAt,
+		Iat:      grant.IssuedAt,
+		Scope:    strings.Join(grant.Scopes, " "),
+		ClientId: grant.ClientID,
+	}
+}
+
+func (s *Service) introspectRefreshToken(ctx context.Context, refreshToken string) *auth_proto.IntrospectResponse {
+	auth := model.Auth{}
+
+	err := s.repo.FindByRefreshToken(ctx, utils.Hash([]byte(refreshToken)), &auth)
+	if err != nil {
+		session := model.DeviceSession{}
+		if err := s.repo.FindDeviceSessionByRefreshToken(ctx, utils.Hash([]byte(refreshToken)), &session); err != nil {
+			return nil
+		}
+
+		if err := s.repo.FindByUserID(ctx, session.UserID, &auth); err != nil {
+			return nil
+		}
+	}
+
+	if checkAccountStatus(&auth) != nil {
+		return &auth_proto.IntrospectResponse{Active: false}
+	}
+
+	return &auth_proto.IntrospectResponse{
+		Active: true,
+		Sub:    auth.UserID,
+		Role:   auth.Role,
+	}
+}
+
+func (s *Service) writeAudit(ctx context.Context, actorID string, targetID string, action audit.Action) error {
+	err := s.auditRepo.Create(ctx, &auditModel.Audit{
+		ActorID:  actorID,
+		TargetID: targetID,
+		Action:   string(action),
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "audit").
+			Str("action", string(action)).
+			Msg("Error while writing the audit log")
+		return status.Error(codes.Internal, "Internal server error")
+	}
+
+	return nil
+}
+
+func (s *Service) changeAccountStatus(ctx context.Context, userID string, accountStatus role.Status, until *time.Time, reason string) (*model.Auth, error) {
+	auth := model.Auth{}
+
+	err := s.repo.FindByUserID(ctx, userID, &auth)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	auth.Status = string(accountStatus)
+	auth.SuspendedUntil = until
+	auth.StatusReason = reason
+	if accountStatus != role.ACTIVE {
+		auth.RefreshToken = ""
+	}
+
+	err = s.repo.UpdateStatus(ctx, auth.ID.String(), &auth)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "account status").
+			Msg("Error while updating the account status")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	if accountStatus == role.ACTIVE {
+		err = s.tokenService.RemoveCredentials(ctx, auth.UserID)
+	} else {
+		err = s.tokenService.RevokeCredentials(ctx, &auth)
+		if err == nil {
+			err = s.removeDeviceSessions(ctx, auth.UserID)
+		}
+		if err == nil {
+			err = s.tokenService.RevokeOidcAccessTokens(ctx, auth.UserID)
+		}
+		if err == nil {
+			err = s.endImpersonations(ctx, auth.UserID)
+		}
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	s.publishRevocation(ctx, &dto.RevocationEvent{
+		Type:   accountRevocations[accountStatus],
+		UserID: auth.UserID,
+		Role:   auth.Role,
+		Status: auth.Status,
+	})
+
+	zerolog.Ctx(ctx).Info().
+		Str("service", "auth").
+		Str("module", "account status").
+		Str("user_id", auth.UserID).
+		Str("status", auth.Status).
+		Msg("Account status changed")
+
+	return &auth, nil
+}
+
+// endImpersonations ends the impersonations of the user, as if their actors ended them
+func (s *Service) endImpersonations(ctx context.Context, userID string) error {
+	actorIDs, err := s.tokenService.RevokeImpersonations(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, actorID := range actorIDs {
+		s.publishRevocation(ctx, &dto.RevocationEvent{
+			Type:    role.SESSION_REVOKED,
+			UserID:  userID,
+			ActorID: actorID,
+		})
+
+		if err := s.writeAudit(ctx, actorID, userID, audit.IMPERSONATION_END); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeDeviceSessions signs the user out of every device, along with their refresh tokens
+func (s *Service) removeDeviceSessions(ctx context.Context, userID string) error {
+	var sessions []*model.DeviceSession
+
+	err := s.repo.DeleteDeviceSessions(ctx, userID, &sessions)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "account status").
+			Msg("Error while deleting the device sessions")
+		return errors.New("Internal service error")
+	}
+
+	for _, session := range sessions {
+		if err := s.tokenService.RemoveDeviceCredentials(ctx, session.ID.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var accountRevocations = map[role.Status]role.Revocation{
+	role.ACTIVE:    role.ACCOUNT_REINSTATED,
+	role.SUSPENDED: role.ACCOUNT_SUSPENDED,
+	role.BANNED:    role.ACCOUNT_BANNED,
+}
+
+// WatchRevocations streams the revocation events following the offset of the request, the last one
+// the client received, or the events to come without one. An offset that has already been trimmed
+// fails with OutOfRange, the client then has to drop everything it cached and watch without an offset.
+func (s *Service) WatchRevocations(req *auth_proto.WatchRevocationsRequest, stream auth_proto.AuthService_WatchRevocationsServer) error {
+	if _, err := utils.Authorize(stream.Context(), s.tokenService.Validate, role.SERVICE, role.ADMIN); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	offset, err := s.revocationRepo.Seek(ctx, req.Offset)
+	if err != nil {
+		switch {
+		case errors.Is(err, cache.ErrInvalidOffset):
+			return status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, cache.ErrOffsetExpired):
+			return status.Error(codes.OutOfRange, err.Error())
+		}
+		return revocationStreamError(stream.Context(), err)
+	}
+
+	for {
+		events, err := s.revocationRepo.Read(ctx, offset, revocationBatch)
+		if err != nil {
+			return revocationStreamError(stream.Context(), err)
+		}
+
+		for _, event := range events {
+			if err := stream.Send(rawToRevocationEventDto(event)); err != nil {
+				return err
+			}
+			offset = event.Offset
+		}
+	}
+}
+
+// Shutdown ends the revocation streams, the clients reconnect to another replica with their offset
+func (s *Service) Shutdown() {
+	s.shutdownOnce.Do(func() {
+		close(s.shutdown)
+	})
+}
+
+// publishRevocation announces the change once it's done, a failure is only logged since the services
+// caching validations keep them for a short time anyway
+func (s *Service) publishRevocation(ctx context.Context, event *dto.RevocationEvent) {
+	event.OccurredAt = time.Now().Unix()
+
+	// the change is already made, the event goes out even if the caller has given up meanwhile
+	if _, err := s.revocationRepo.Append(context.WithoutCancel(ctx), event); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "auth").
+			Str("module", "revocation").
+			Str("user_id", event.UserID).
+			Str("type", string(event.Type)).
+			Msg("Error while publishing the revocation event")
+	}
+}
+
+func revocationStreamError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Unavailable, "Server is shutting down")
+	}
+
+	zerolog.Ctx(ctx).Error().Err(err).
+		Str("service", "auth").
+		Str("module", "revocation").
+		Msg("Error while reading the revocation events")
+	return status.Error(codes.Unavailable, "Revocation events are unavailable")
+}
+
+func checkAccountStatus(auth *model.Auth) error {
+	switch role.Status(auth.Status) {
+	case role.SUSPENDED:
+		if auth.SuspendedUntil != nil && !auth.SuspendedUntil.After(time.Now()) {
+			return nil
+		}
+		return accountStatusError(role.SUSPENDED, auth.SuspendedUntil, auth.StatusReason)
+	case role.BANNED:
+		return accountStatusError(role.BANNED, nil, auth.StatusReason)
+	}
+
+	return nil
+}
+
+func accountStatusError(accountStatus role.Status, until *time.Time, reason string) error {
+	info := &errdetails.ErrorInfo{
+		Domain:   role.ERROR_DOMAIN,
+		Metadata: map[string]string{},
+	}
+	if reason != "" {
+		info.Metadata["reason"] = reason
+	}
+
+	var st *status.Status
+	switch accountStatus {
+	case role.SUSPENDED:
+		st = status.New(codes.PermissionDenied, ts.ErrAccountSuspended.Error())
+		info.Reason = "ACCOUNT_SUSPENDED"
+		if until != nil {
+			info.Metadata["until"] = until.UTC().Format(time.RFC3339)
+		}
+	default:
+		st = status.New(codes.PermissionDenied, ts.ErrAccountBanned.Error())
+		info.Reason = "ACCOUNT_BANNED"
+	}
+
+	detailed, err := st.WithDetails(info)
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+func rawToRevocationEventDto(event *dto.RevocationEvent) *auth_proto.RevocationEvent {
+	return &auth_proto.RevocationEvent{
+		Offset:     event.Offset,
+		Type:       string(event.Type),
+		UserId:     event.UserID,
+		ActorId:    event.ActorID,
+		Role:       event.Role,
+		Status:     event.Status,
+		OccurredAt: event.OccurredAt,
+	}
+}
+
+func rawToAccountDto(auth *model.Auth) *auth_proto.Account {
+	accountStatus := auth.Status
+	if accountStatus == "" {
+		accountStatus = string(role.ACTIVE)
+	}
+
+	var until int64
+	if auth.SuspendedUntil != nil {
+		until = auth.SuspendedUntil.Unix()
+	}
+
+	return &auth_proto.Account{
+		UserId:         auth.UserID,
+		Role:           auth.Role,
+		Status:         accountStatus,
+		SuspendedUntil: until,
+		Reason:         auth.StatusReason,
+	}
 }
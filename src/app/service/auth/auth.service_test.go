@@ -6,24 +6,34 @@ import (
 	"time"
 
 	"github.com/bookpanda/mygraderlist-auth/src/client"
+	"github.com/bookpanda/mygraderlist-auth/src/mocks/audit"
 	mock "github.com/bookpanda/mygraderlist-auth/src/mocks/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/mocks/cache"
 	"golang.org/x/oauth2"
 
 	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	oidcDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oidc"
 	"github.com/bookpanda/mygraderlist-auth/src/app/model"
+	auditModel "github.com/bookpanda/mygraderlist-auth/src/app/model/audit"
 	"github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
+	cacheRepo "github.com/bookpanda/mygraderlist-auth/src/app/repository/cache"
+	ts "github.com/bookpanda/mygraderlist-auth/src/app/service/token"
 	"github.com/bookpanda/mygraderlist-auth/src/app/utils"
 	"github.com/bookpanda/mygraderlist-auth/src/config"
+	action "github.com/bookpanda/mygraderlist-auth/src/constant/audit"
 	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
-	auth_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
 	user_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/backend/user"
 	"github.com/bxcodec/faker/v3"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	testifyMock "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
 )
@@ -33,16 +43,24 @@ type AuthServiceTest struct {
 	Auth              *auth.Auth
 	UserDto           *user_proto.User
 	Credential        *auth_proto.Credential
+	DeviceSession     *auth.DeviceSession
 	Payload           *dto.TokenPayloadAuth
 	UserCredential    *dto.UserCredential
 	conf              config.App
 	oauthConf         oauth2.Config
 	googleOauthClient *client.GoogleOauthClient
+	RevocationRepo    *cache.RevocationRepositoryMock
 	UnauthorizedErr   error
 	NotFoundErr       error
 	ServiceDownErr    error
 }
 
+func revocation(revocationType role.Revocation, userID string) interface{} {
+	return testifyMock.MatchedBy(func(event *dto.RevocationEvent) bool {
+		return event.Type == revocationType && event.UserID == userID && event.OccurredAt > 0
+	})
+}
+
 func TestAuthService(t *testing.T) {
 	suite.Run(t, new(AuthServiceTest))
 }
@@ -72,6 +90,14 @@ func (t *AuthServiceTest) SetupTest() {
 		ExpiresIn:    3600,
 	}
 
+	t.DeviceSession = &auth.DeviceSession{
+		Base: model.Base{
+			ID: uuid.New(),
+		},
+		UserID:   t.Auth.UserID,
+		ClientID: "mgl-cli",
+	}
+
 	t.Payload = &dto.TokenPayloadAuth{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    faker.Word(),
@@ -97,6 +123,9 @@ func (t *AuthServiceTest) SetupTest() {
 	}
 
 	t.oauthConf = oauth2.Config{}
+
+	t.RevocationRepo = &cache.RevocationRepositoryMock{}
+	t.RevocationRepo.On("Append", testifyMock.Anything).Return("1-0", nil)
 }
 
 func (t *AuthServiceTest) TestValidateSuccess() {
@@ -113,7 +142,7 @@ func (t *AuthServiceTest) TestValidateSuccess() {
 	tokenService := &mock.TokenServiceMock{}
 	tokenService.On("Validate", token).Return(t.UserCredential, nil)
 
-	srv := NewService(repo, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
 
 	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
 
@@ -131,7 +160,7 @@ func (t *AuthServiceTest) TestValidateInvalidToken() {
 	tokenService := &mock.TokenServiceMock{}
 	tokenService.On("Validate", token).Return(nil, errors.New("Invalid token"))
 
-	srv := NewService(repo, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
 
 	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
 
@@ -158,7 +187,7 @@ func (t *AuthServiceTest) TestRedeemRefreshTokenSuccess() {
 	tokenService.On("CreateRefreshToken").Return(token)
 	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(t.Credential, nil)
 
-	srv := NewService(repo, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
 
 	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
 
@@ -172,6 +201,7 @@ func (t *AuthServiceTest) TestRedeemRefreshTokenInvalidToken() {
 
 	repo := &mock.RepositoryMock{}
 	repo.On("FindByRefreshToken", t.Credential.RefreshToken, &auth.Auth{}).Return(nil, errors.New("Not found token"))
+	repo.On("FindDeviceSessionByRefreshToken", t.Credential.RefreshToken, &auth.DeviceSession{}).Return(nil, errors.New("Not found token"))
 	repo.On("Update", t.Auth).Return(t.Auth, nil)
 
 	userService := &mock.UserServiceMock{}
@@ -180,7 +210,7 @@ func (t *AuthServiceTest) TestRedeemRefreshTokenInvalidToken() {
 	tokenService.On("CreateRefreshToken").Return(token)
 	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(t.Credential, nil)
 
-	srv := NewService(repo, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
 
 	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
 
@@ -191,6 +221,63 @@ func (t *AuthServiceTest) TestRedeemRefreshTokenInvalidToken() {
 	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
 }
 
+func (t *AuthServiceTest) TestRedeemRefreshTokenDeviceSession() {
+	token := faker.Word()
+
+	want := &auth_proto.RefreshTokenResponse{Credential: t.Credential}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByRefreshToken", utils.Hash([]byte(token)), &auth.Auth{}).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("FindDeviceSessionByRefreshToken", utils.Hash([]byte(token)), &auth.DeviceSession{}).Return(t.DeviceSession, nil)
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("UpdateDeviceSessionRefreshToken", t.DeviceSession.ID.String(), utils.Hash([]byte(t.Credential.RefreshToken))).Return(nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateDeviceCredentials", t.Auth, t.DeviceSession.ID.String()).Return(t.Credential, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	repo.AssertNotCalled(t.T(), "Update", testifyMock.Anything)
+}
+
+func (t *AuthServiceTest) TestIssueDeviceCredentialSuccess() {
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("CreateDeviceSession", &auth.DeviceSession{UserID: t.Auth.UserID, ClientID: t.DeviceSession.ClientID}).Return(t.DeviceSession, nil)
+	repo.On("UpdateDeviceSessionRefreshToken", t.DeviceSession.ID.String(), utils.Hash([]byte(t.Credential.RefreshToken))).Return(nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateDeviceCredentials", t.Auth, t.DeviceSession.ID.String()).Return(t.Credential, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.IssueDeviceCredential(context.Background(), t.Auth.UserID, t.DeviceSession.ClientID)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), t.Credential, actual)
+	// the session and the refresh token of the account are left alone
+	tokenService.AssertNotCalled(t.T(), "CreateCredentials", testifyMock.Anything, testifyMock.Anything)
+	repo.AssertNotCalled(t.T(), "Update", testifyMock.Anything)
+}
+
+func (t *AuthServiceTest) TestIssueDeviceCredentialSuspended() {
+	t.Auth.Status = string(role.SUSPENDED)
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, &mock.TokenServiceMock{}, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.IssueDeviceCredential(context.Background(), t.Auth.UserID, t.DeviceSession.ClientID)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, status.Code(err))
+}
+
 func (t *AuthServiceTest) TestRedeemRefreshTokenInternalErr() {
 	token := faker.Word()
 	t.Credential.RefreshToken = utils.Hash([]byte(token))
@@ -204,7 +291,7 @@ func (t *AuthServiceTest) TestRedeemRefreshTokenInternalErr() {
 	tokenService.On("CreateRefreshToken").Return(token)
 	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(nil, errors.New("Invalid secret key"))
 
-	srv := NewService(repo, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
 
 	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
 
@@ -230,9 +317,9 @@ func (t *AuthServiceTest) TestCreateCredentialsSuccess() {
 	tokenService.On("CreateRefreshToken").Return(token)
 	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(t.Credential, nil)
 
-	srv := NewService(repo, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
 
-	credentials, err := srv.CreateNewCredential(t.Auth)
+	credentials, err := srv.CreateNewCredential(context.Background(), t.Auth)
 
 	assert.Nilf(t.T(), err, "error: %v", err)
 	assert.Equal(t.T(), want, credentials)
@@ -253,10 +340,1264 @@ func (t *AuthServiceTest) TestCreateCredentialsInternalErr() {
 	tokenService.On("CreateRefreshToken").Return(token)
 	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(nil, errors.New("Invalid secret key"))
 
-	srv := NewService(repo, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
 
-	credentials, err := srv.CreateNewCredential(t.Auth)
+	credentials, err := srv.CreateNewCredential(context.Background(), t.Auth)
 
 	assert.Nil(t.T(), credentials)
 	assert.Equal(t.T(), want.Error(), err.Error())
 }
+
+func (t *AuthServiceTest) TestValidateSuspendedAccount() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(nil, ts.ErrAccountSuspended)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	assert.Equal(t.T(), "ACCOUNT_SUSPENDED", st.Details()[0].(*errdetails.ErrorInfo).Reason)
+}
+
+func (t *AuthServiceTest) TestValidateSuspendedAccountMetadata() {
+	token := faker.Word()
+	until := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	reason := faker.Sentence()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(nil, &ts.AccountStatusError{Status: role.SUSPENDED, Until: &until, Reason: reason})
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
+
+	st, ok := status.FromError(err)
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	assert.Equal(t.T(), "ACCOUNT_SUSPENDED", info.Reason)
+	assert.Equal(t.T(), map[string]string{"reason": reason, "until": until.UTC().Format(time.RFC3339)}, info.Metadata)
+}
+
+func (t *AuthServiceTest) TestRedeemRefreshTokenBannedAccount() {
+	token := faker.Word()
+	t.Auth.Status = string(role.BANNED)
+	t.Auth.StatusReason = faker.Sentence()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByRefreshToken", utils.Hash([]byte(token)), &auth.Auth{}).Return(t.Auth, nil)
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+
+	st, ok := status.FromError(err)
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	assert.Equal(t.T(), "ACCOUNT_BANNED", info.Reason)
+	assert.Equal(t.T(), t.Auth.StatusReason, info.Metadata["reason"])
+	tokenService.AssertNotCalled(t.T(), "CreateCredentials", t.Auth, t.conf.Secret)
+}
+
+func (t *AuthServiceTest) TestRedeemRefreshTokenSuspensionExpired() {
+	token := faker.Word()
+	until := time.Now().Add(-time.Hour)
+	t.Auth.RefreshToken = utils.Hash([]byte(t.Credential.RefreshToken))
+	t.Auth.Status = string(role.SUSPENDED)
+	t.Auth.SuspendedUntil = &until
+
+	want := &auth_proto.RefreshTokenResponse{Credential: t.Credential}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByRefreshToken", utils.Hash([]byte(token)), &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("Update", t.Auth).Return(t.Auth, nil)
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(t.Credential, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestSuspendAccountSuccess() {
+	adminToken := faker.Word()
+	actorID := faker.UUIDDigit()
+	until := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	reason := faker.Sentence()
+
+	suspended := *t.Auth
+	suspended.Status = string(role.SUSPENDED)
+	suspended.SuspendedUntil = &until
+	suspended.StatusReason = reason
+	suspended.RefreshToken = ""
+
+	want := &auth_proto.SuspendAccountResponse{Account: &auth_proto.Account{
+		UserId:         t.Auth.UserID,
+		Role:           t.Auth.Role,
+		Status:         string(role.SUSPENDED),
+		SuspendedUntil: until.Unix(),
+		Reason:         reason,
+	}}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("UpdateStatus", t.Auth.ID.String(), &suspended).Return(&suspended, nil)
+	repo.On("DeleteDeviceSessions", t.Auth.UserID, testifyMock.Anything).Return([]*auth.DeviceSession{t.DeviceSession}, nil)
+
+	auditRepo := &audit.RepositoryMock{}
+	auditRepo.On("Create", &auditModel.Audit{
+		ActorID:  actorID,
+		TargetID: t.Auth.UserID,
+		Action:   string(action.IMPERSONATION_END),
+	}).Return(nil)
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", adminToken).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.ADMIN}, nil)
+	tokenService.On("RevokeCredentials", &suspended).Return(nil)
+	tokenService.On("RemoveDeviceCredentials", t.DeviceSession.ID.String()).Return(nil)
+	tokenService.On("RevokeOidcAccessTokens", t.Auth.UserID).Return(nil)
+	tokenService.On("RevokeImpersonations", t.Auth.UserID).Return([]string{actorID}, nil)
+
+	srv := NewService(repo, auditRepo, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+adminToken))
+	actual, err := srv.SuspendAccount(ctx, &auth_proto.SuspendAccountRequest{
+		UserId: t.Auth.UserID,
+		Until:  until.Unix(),
+		Reason: reason,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	tokenService.AssertCalled(t.T(), "RevokeCredentials", &suspended)
+	tokenService.AssertCalled(t.T(), "RemoveDeviceCredentials", t.DeviceSession.ID.String())
+	tokenService.AssertCalled(t.T(), "RevokeOidcAccessTokens", t.Auth.UserID)
+	t.RevocationRepo.AssertCalled(t.T(), "Append", revocation(role.ACCOUNT_SUSPENDED, t.Auth.UserID))
+	t.RevocationRepo.AssertCalled(t.T(), "Append", testifyMock.MatchedBy(func(event *dto.RevocationEvent) bool {
+		return event.Type == role.SESSION_REVOKED && event.UserID == t.Auth.UserID && event.ActorID == actorID
+	}))
+	auditRepo.AssertNumberOfCalls(t.T(), "Create", 1)
+}
+
+func (t *AuthServiceTest) TestSuspendAccountForbidden() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	actual, err := srv.SuspendAccount(ctx, &auth_proto.SuspendAccountRequest{
+		UserId: t.Auth.UserID,
+		Until:  time.Now().Add(time.Hour).Unix(),
+	})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+}
+
+func (t *AuthServiceTest) TestReinstateAccountSuccess() {
+	adminToken := faker.Word()
+	t.Auth.Status = string(role.BANNED)
+	t.Auth.StatusReason = faker.Sentence()
+
+	reinstated := *t.Auth
+	reinstated.Status = string(role.ACTIVE)
+	reinstated.StatusReason = ""
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("UpdateStatus", t.Auth.ID.String(), &reinstated).Return(&reinstated, nil)
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", adminToken).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.ADMIN}, nil)
+	tokenService.On("RemoveCredentials", t.Auth.UserID).Return(nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+adminToken))
+	actual, err := srv.ReinstateAccount(ctx, &auth_proto.ReinstateAccountRequest{UserId: t.Auth.UserID})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), string(role.ACTIVE), actual.Account.Status)
+	tokenService.AssertCalled(t.T(), "RemoveCredentials", t.Auth.UserID)
+}
+
+func (t *AuthServiceTest) TestImpersonateSuccess() {
+	adminToken := faker.Word()
+	adminID := faker.UUIDDigit()
+	t.Credential.RefreshToken = ""
+
+	want := &auth_proto.ImpersonateResponse{Credential: t.Credential}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+
+	auditRepo := &audit.RepositoryMock{}
+	auditRepo.On("Create", &auditModel.Audit{
+		ActorID:  adminID,
+		TargetID: t.Auth.UserID,
+		Action:   string(action.IMPERSONATION_START),
+	}).Return(nil)
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", adminToken).Return(&dto.UserCredential{UserId: adminID, Role: role.ADMIN}, nil)
+	tokenService.On("ImpersonatedUserID", adminID).Return("", nil)
+	tokenService.On("CreateImpersonationCredentials", t.Auth, adminID).Return(t.Credential, nil)
+
+	srv := NewService(repo, auditRepo, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+adminToken))
+	actual, err := srv.Impersonate(ctx, &auth_proto.ImpersonateRequest{TargetUserId: t.Auth.UserID})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	auditRepo.AssertNumberOfCalls(t.T(), "Create", 1)
+}
+
+func (t *AuthServiceTest) TestImpersonateReplacesPrevious() {
+	adminToken := faker.Word()
+	adminID := faker.UUIDDigit()
+	previousID := faker.UUIDDigit()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+
+	auditRepo := &audit.RepositoryMock{}
+	auditRepo.On("Create", &auditModel.Audit{
+		ActorID:  adminID,
+		TargetID: previousID,
+		Action:   string(action.IMPERSONATION_END),
+	}).Return(nil)
+	auditRepo.On("Create", &auditModel.Audit{
+		ActorID:  adminID,
+		TargetID: t.Auth.UserID,
+		Action:   string(action.IMPERSONATION_START),
+	}).Return(nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", adminToken).Return(&dto.UserCredential{UserId: adminID, Role: role.ADMIN}, nil)
+	tokenService.On("ImpersonatedUserID", adminID).Return(previousID, nil)
+	tokenService.On("CreateImpersonationCredentials", t.Auth, adminID).Return(t.Credential, nil)
+
+	srv := NewService(repo, auditRepo, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+adminToken))
+	_, err := srv.Impersonate(ctx, &auth_proto.ImpersonateRequest{TargetUserId: t.Auth.UserID})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	auditRepo.AssertNumberOfCalls(t.T(), "Create", 2)
+	t.RevocationRepo.AssertCalled(t.T(), "Append", revocation(role.SESSION_REVOKED, previousID))
+}
+
+func (t *AuthServiceTest) TestImpersonateIssueFailure() {
+	adminToken := faker.Word()
+	adminID := faker.UUIDDigit()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+
+	auditRepo := &audit.RepositoryMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", adminToken).Return(&dto.UserCredential{UserId: adminID, Role: role.ADMIN}, nil)
+	tokenService.On("ImpersonatedUserID", adminID).Return("", nil)
+	tokenService.On("CreateImpersonationCredentials", t.Auth, adminID).Return(nil, errors.New("Internal service error"))
+
+	srv := NewService(repo, auditRepo, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+adminToken))
+	actual, err := srv.Impersonate(ctx, &auth_proto.ImpersonateRequest{TargetUserId: t.Auth.UserID})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Internal, st.Code())
+	auditRepo.AssertNotCalled(t.T(), "Create", testifyMock.Anything)
+}
+
+func (t *AuthServiceTest) TestChangeRoleSuccess() {
+	adminToken := faker.Word()
+
+	demoted := *t.Auth
+	demoted.Role = string(role.USER)
+	t.Auth.Role = string(role.ADMIN)
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("UpdateRole", t.Auth.ID.String(), &demoted).Return(&demoted, nil)
+	repo.On("FindDeviceSessions", t.Auth.UserID, testifyMock.Anything).Return([]*auth.DeviceSession{t.DeviceSession}, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", adminToken).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.ADMIN}, nil)
+	tokenService.On("RemoveCredentials", t.Auth.UserID).Return(nil)
+	tokenService.On("RemoveDeviceCredentials", t.DeviceSession.ID.String()).Return(nil)
+	tokenService.On("RevokeImpersonations", t.Auth.UserID).Return(nil, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+adminToken))
+	actual, err := srv.ChangeRole(ctx, &auth_proto.ChangeRoleRequest{UserId: t.Auth.UserID, Role: string(role.USER)})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), string(role.USER), actual.Account.Role)
+	tokenService.AssertCalled(t.T(), "RemoveCredentials", t.Auth.UserID)
+	tokenService.AssertCalled(t.T(), "RemoveDeviceCredentials", t.DeviceSession.ID.String())
+	repo.AssertNotCalled(t.T(), "DeleteDeviceSessions", testifyMock.Anything, testifyMock.Anything)
+	t.RevocationRepo.AssertCalled(t.T(), "Append", revocation(role.ROLE_CHANGED, t.Auth.UserID))
+}
+
+func (t *AuthServiceTest) TestChangeRoleOwnRole() {
+	adminToken := faker.Word()
+	adminID := faker.UUIDDigit()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", adminToken).Return(&dto.UserCredential{UserId: adminID, Role: role.ADMIN}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+adminToken))
+	actual, err := srv.ChangeRole(ctx, &auth_proto.ChangeRoleRequest{UserId: adminID, Role: string(role.USER)})
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, status.Code(err))
+	t.RevocationRepo.AssertNotCalled(t.T(), "Append", testifyMock.Anything)
+}
+
+func (t *AuthServiceTest) TestImpersonateAdminForbidden() {
+	adminToken := faker.Word()
+	t.Auth.Role = string(role.ADMIN)
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+
+	auditRepo := &audit.RepositoryMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", adminToken).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.ADMIN}, nil)
+
+	srv := NewService(repo, auditRepo, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+adminToken))
+	actual, err := srv.Impersonate(ctx, &auth_proto.ImpersonateRequest{TargetUserId: t.Auth.UserID})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	auditRepo.AssertNotCalled(t.T(), "Create")
+}
+
+func (t *AuthServiceTest) TestEndImpersonationSuccess() {
+	token := faker.Word()
+	adminID := faker.UUIDDigit()
+
+	repo := &mock.RepositoryMock{}
+
+	auditRepo := &audit.RepositoryMock{}
+	auditRepo.On("Create", &auditModel.Audit{
+		ActorID:  adminID,
+		TargetID: t.Auth.UserID,
+		Action:   string(action.IMPERSONATION_END),
+	}).Return(nil)
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{UserId: t.Auth.UserID, Role: role.USER, ActorId: adminID}, nil)
+	tokenService.On("RemoveImpersonationCredentials", adminID).Return(nil)
+
+	srv := NewService(repo, auditRepo, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.EndImpersonation(context.Background(), &auth_proto.EndImpersonationRequest{Token: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.True(t.T(), actual.Success)
+	auditRepo.AssertNumberOfCalls(t.T(), "Create", 1)
+	t.RevocationRepo.AssertCalled(t.T(), "Append", revocation(role.SESSION_REVOKED, t.Auth.UserID))
+}
+
+func (t *AuthServiceTest) TestValidateApiKeySuccess() {
+	key := "mgl_" + faker.Word()
+	want := &auth_proto.ValidateResponse{
+		UserId: t.UserDto.Id,
+		Role:   string(role.SERVICE),
+		Scopes: []string{"rating:read"},
+	}
+
+	repo := &mock.RepositoryMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+
+	apiKeyService := &mock.ApiKeyServiceMock{}
+	apiKeyService.On("ValidateApiKey", key).Return(&dto.UserCredential{
+		UserId: t.UserDto.Id,
+		Role:   role.SERVICE,
+		Scopes: []string{"rating:read"},
+	}, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, apiKeyService, &mock.ClientServiceMock{}, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: key})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	tokenService.AssertNotCalled(t.T(), "Validate", key)
+}
+
+func (t *AuthServiceTest) TestIssueServiceTokenSuccess() {
+	client := &config.Client{
+		ClientID:     faker.Word(),
+		ClientSecret: faker.Password(),
+		Audiences:    []string{"mgl-auth"},
+		Scopes:       []string{"auth:validate", "user:read"},
+	}
+	t.Credential.RefreshToken = ""
+
+	want := &auth_proto.IssueServiceTokenResponse{Credential: t.Credential}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Authenticate", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateServiceCredentials", client.ClientID, []string{"mgl-auth"}, client.Scopes).Return(t.Credential, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.IssueServiceToken(context.Background(), &auth_proto.IssueServiceTokenRequest{
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+		Audience:     "mgl-auth",
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestIssueServiceTokenScopeNotAllowed() {
+	client := &config.Client{
+		ClientID:     faker.Word(),
+		ClientSecret: faker.Password(),
+		Audiences:    []string{"mgl-auth"},
+		Scopes:       []string{"auth:validate"},
+	}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Authenticate", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.IssueServiceToken(context.Background(), &auth_proto.IssueServiceTokenRequest{
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+		Audience:     "mgl-auth",
+		Scopes:       []string{"user:write"},
+	})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+}
+
+func (t *AuthServiceTest) TestValidateServiceTokenInvalidAudience() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{
+		UserId:   faker.Word(),
+		Role:     role.SERVICE,
+		Audience: []string{"mgl-backend"},
+	}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token, Audience: "mgl-auth"})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthServiceTest) TestValidateServiceTokenMissingAudience() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{
+		UserId:   faker.Word(),
+		Role:     role.SERVICE,
+		Audience: []string{"mgl-backend"},
+	}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthServiceTest) TestIntrospectAccessToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	want := &auth_proto.IntrospectResponse{
+		Active: true,
+		Sub:    t.Auth.UserID,
+		Exp:    1700000000,
+		Iat:    1699996400,
+		Role:   t.Auth.Role,
+	}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Authenticate", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{
+		UserId:    t.Auth.UserID,
+		Role:      role.USER,
+		ExpiresAt: want.Exp,
+		IssuedAt:  want.Iat,
+	}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Introspect(context.Background(), &auth_proto.IntrospectRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestIntrospectServiceToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+	issuer := faker.Word()
+
+	want := &auth_proto.IntrospectResponse{
+		Active:   true,
+		Sub:      issuer,
+		Exp:      1700000000,
+		Iat:      1699996400,
+		Scope:    "rating:read",
+		Role:     string(role.SERVICE),
+		ClientId: issuer,
+	}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Authenticate", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{
+		UserId:    issuer,
+		Role:      role.SERVICE,
+		Audience:  []string{client.ClientID},
+		Scopes:    []string{"rating:read"},
+		ExpiresAt: want.Exp,
+		IssuedAt:  want.Iat,
+	}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Introspect(context.Background(), &auth_proto.IntrospectRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestIntrospectServiceTokenOfAnotherAudience() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Authenticate", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{
+		UserId:   faker.Word(),
+		Role:     role.SERVICE,
+		Audience: []string{faker.Word() + "-api"},
+	}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Introspect(context.Background(), &auth_proto.IntrospectRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.IntrospectResponse{Active: false}, actual)
+}
+
+func (t *AuthServiceTest) TestIntrospectRefreshToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	want := &auth_proto.IntrospectResponse{
+		Active: true,
+		Sub:    t.Auth.UserID,
+		Role:   t.Auth.Role,
+	}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByRefreshToken", utils.Hash([]byte(token)), &auth.Auth{}).Return(t.Auth, nil)
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Authenticate", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Introspect(context.Background(), &auth_proto.IntrospectRequest{
+		Token:         token,
+		TokenTypeHint: "refresh_token",
+		ClientId:      client.ClientID,
+		ClientSecret:  client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	tokenService.AssertNotCalled(t.T(), "Validate", token)
+}
+
+func (t *AuthServiceTest) TestIntrospectInactiveToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByRefreshToken", utils.Hash([]byte(token)), &auth.Auth{}).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("FindDeviceSessionByRefreshToken", utils.Hash([]byte(token)), &auth.DeviceSession{}).Return(nil, gorm.ErrRecordNotFound)
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Authenticate", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(nil, errors.New("Invalid token"))
+	tokenService.On("ValidateOidcAccessToken", token).Return(nil, ts.ErrInvalidAccessToken)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Introspect(context.Background(), &auth_proto.IntrospectRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.IntrospectResponse{Active: false}, actual)
+}
+
+func (t *AuthServiceTest) TestIntrospectOidcAccessToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	grant := &oidcDto.CacheAccessToken{
+		ClientID:  faker.Word(),
+		Scopes:    []string{"openid", "email"},
+		UserID:    t.Auth.UserID,
+		IssuedAt:  1699996400,
+		ExpiresAt: 1700000000,
+	}
+
+	want := &auth_proto.IntrospectResponse{
+		Active:   true,
+		Sub:      t.Auth.UserID,
+		Exp:      grant.ExpiresAt,
+		Iat:      grant.IssuedAt,
+		Scope:    "openid email",
+		ClientId: grant.ClientID,
+	}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Authenticate", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(nil, errors.New("Invalid token"))
+	tokenService.On("ValidateOidcAccessToken", token).Return(grant, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Introspect(context.Background(), &auth_proto.IntrospectRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestIntrospectInvalidClient() {
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Authenticate", "", "").Return(nil, errors.New("Invalid client credentials"))
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, &mock.TokenServiceMock{}, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Introspect(context.Background(), &auth_proto.IntrospectRequest{Token: faker.Word()})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthServiceTest) TestRevokeAccessToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: t.DeviceSession.ClientID, ClientSecret: faker.Password()}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Identify", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{
+		UserId:    t.Auth.UserID,
+		Role:      role.USER,
+		SessionId: t.DeviceSession.ID.String(),
+	}, nil)
+	tokenService.On("RemoveDeviceCredentials", t.DeviceSession.ID.String()).Return(nil)
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindDeviceSession", t.DeviceSession.ID.String(), &auth.DeviceSession{}).Return(t.DeviceSession, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Revoke(context.Background(), &auth_proto.RevokeRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.RevokeResponse{}, actual)
+	tokenService.AssertCalled(t.T(), "RemoveDeviceCredentials", t.DeviceSession.ID.String())
+	repo.AssertNotCalled(t.T(), "DeleteDeviceSession", t.DeviceSession.ID.String())
+	t.RevocationRepo.AssertCalled(t.T(), "Append", revocation(role.SESSION_REVOKED, t.Auth.UserID))
+}
+
+func (t *AuthServiceTest) TestRevokeAccessTokenOfAnotherClient() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Identify", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{
+		UserId:    t.Auth.UserID,
+		Role:      role.USER,
+		SessionId: t.DeviceSession.ID.String(),
+	}, nil)
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindDeviceSession", t.DeviceSession.ID.String(), &auth.DeviceSession{}).Return(t.DeviceSession, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Revoke(context.Background(), &auth_proto.RevokeRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.RevokeResponse{}, actual)
+	tokenService.AssertNotCalled(t.T(), "RemoveDeviceCredentials", testifyMock.Anything)
+	t.RevocationRepo.AssertNotCalled(t.T(), "Append", testifyMock.Anything)
+}
+
+func (t *AuthServiceTest) TestRevokeBrowserSessionToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Identify", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{
+		UserId: t.Auth.UserID,
+		Role:   role.USER,
+	}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Revoke(context.Background(), &auth_proto.RevokeRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.RevokeResponse{}, actual)
+	tokenService.AssertNotCalled(t.T(), "RemoveCredentials", t.Auth.UserID)
+}
+
+func (t *AuthServiceTest) TestRevokeRefreshToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: t.DeviceSession.ClientID, ClientSecret: faker.Password()}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Identify", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByRefreshToken", utils.Hash([]byte(token)), &auth.Auth{}).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("FindDeviceSessionByRefreshToken", utils.Hash([]byte(token)), &auth.DeviceSession{}).Return(t.DeviceSession, nil)
+	repo.On("DeleteDeviceSession", t.DeviceSession.ID.String()).Return(nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("RemoveDeviceCredentials", t.DeviceSession.ID.String()).Return(nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Revoke(context.Background(), &auth_proto.RevokeRequest{
+		Token:         token,
+		TokenTypeHint: "refresh_token",
+		ClientId:      client.ClientID,
+		ClientSecret:  client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.RevokeResponse{}, actual)
+	repo.AssertCalled(t.T(), "DeleteDeviceSession", t.DeviceSession.ID.String())
+	tokenService.AssertNotCalled(t.T(), "Validate", token)
+	t.RevocationRepo.AssertCalled(t.T(), "Append", revocation(role.SESSION_REVOKED, t.Auth.UserID))
+}
+
+func (t *AuthServiceTest) TestRevokeBrowserRefreshToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Identify", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByRefreshToken", utils.Hash([]byte(token)), &auth.Auth{}).Return(t.Auth, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, &mock.TokenServiceMock{}, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Revoke(context.Background(), &auth_proto.RevokeRequest{
+		Token:         token,
+		TokenTypeHint: "refresh_token",
+		ClientId:      client.ClientID,
+		ClientSecret:  client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.RevokeResponse{}, actual)
+	repo.AssertNotCalled(t.T(), "ClearRefreshToken", t.Auth.ID.String())
+}
+
+func (t *AuthServiceTest) TestRevokeUnknownToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Identify", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByRefreshToken", utils.Hash([]byte(token)), &auth.Auth{}).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("FindDeviceSessionByRefreshToken", utils.Hash([]byte(token)), &auth.DeviceSession{}).Return(nil, gorm.ErrRecordNotFound)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(nil, errors.New("Invalid token"))
+	tokenService.On("ValidateOidcAccessToken", token).Return(nil, ts.ErrInvalidAccessToken)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Revoke(context.Background(), &auth_proto.RevokeRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.RevokeResponse{}, actual)
+}
+
+func (t *AuthServiceTest) TestRevokeOidcAccessToken() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Identify", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(nil, errors.New("Invalid token"))
+	tokenService.On("ValidateOidcAccessToken", token).Return(&oidcDto.CacheAccessToken{ClientID: client.ClientID, UserID: t.Auth.UserID}, nil)
+	tokenService.On("RemoveOidcAccessToken", token).Return(nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Revoke(context.Background(), &auth_proto.RevokeRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.RevokeResponse{}, actual)
+	tokenService.AssertCalled(t.T(), "RemoveOidcAccessToken", token)
+}
+
+func (t *AuthServiceTest) TestRevokeOidcAccessTokenOfAnotherClient() {
+	token := faker.Word()
+	client := &config.Client{ClientID: faker.Word(), ClientSecret: faker.Password()}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Identify", client.ClientID, client.ClientSecret).Return(client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(nil, errors.New("Invalid token"))
+	tokenService.On("ValidateOidcAccessToken", token).Return(&oidcDto.CacheAccessToken{ClientID: faker.Word(), UserID: t.Auth.UserID}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Revoke(context.Background(), &auth_proto.RevokeRequest{
+		Token:        token,
+		ClientId:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.RevokeResponse{}, actual)
+	tokenService.AssertNotCalled(t.T(), "RemoveOidcAccessToken", token)
+}
+
+func (t *AuthServiceTest) TestRevokeInvalidClient() {
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("Identify", "", "").Return(nil, errors.New("Invalid client credentials"))
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, &mock.TokenServiceMock{}, &mock.ApiKeyServiceMock{}, clientService, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Revoke(context.Background(), &auth_proto.RevokeRequest{Token: faker.Word()})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthServiceTest) TestLogout() {
+	accessToken := faker.Word()
+	refreshToken := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByRefreshToken", utils.Hash([]byte(refreshToken)), &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("ClearRefreshToken", t.Auth.ID.String()).Return(nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("RemoveCredentials", t.Auth.UserID).Return(nil)
+	tokenService.On("Validate", accessToken).Return(nil, errors.New("Invalid token"))
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Logout(context.Background(), &auth_proto.LogoutRequest{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.LogoutResponse{}, actual)
+	repo.AssertCalled(t.T(), "ClearRefreshToken", t.Auth.ID.String())
+	tokenService.AssertCalled(t.T(), "RemoveCredentials", t.Auth.UserID)
+	t.RevocationRepo.AssertCalled(t.T(), "Append", revocation(role.SESSION_REVOKED, t.Auth.UserID))
+}
+
+func (t *AuthServiceTest) TestLogoutWithoutRefreshToken() {
+	accessToken := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", accessToken).Return(t.UserCredential, nil)
+	tokenService.On("RemoveCredentials", t.Auth.UserID).Return(nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Logout(context.Background(), &auth_proto.LogoutRequest{AccessToken: accessToken})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.LogoutResponse{}, actual)
+	tokenService.AssertCalled(t.T(), "RemoveCredentials", t.Auth.UserID)
+}
+
+func (t *AuthServiceTest) TestLogoutUnavailable() {
+	refreshToken := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByRefreshToken", utils.Hash([]byte(refreshToken)), &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("ClearRefreshToken", t.Auth.ID.String()).Return(errors.New("Connection lost"))
+
+	srv := NewService(repo, &audit.RepositoryMock{}, t.RevocationRepo, &mock.TokenServiceMock{}, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	actual, err := srv.Logout(context.Background(), &auth_proto.LogoutRequest{RefreshToken: refreshToken})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unavailable, st.Code())
+}
+
+func (t *AuthServiceTest) TestWatchRevocationsResumeFromOffset() {
+	token := faker.Word()
+
+	revocationRepo := cacheRepo.NewMemoryRevocationRepository(config.Cache{})
+	seen, _ := revocationRepo.Append(context.Background(), &dto.RevocationEvent{Type: role.SESSION_REVOKED, UserID: faker.UUIDDigit()})
+	_, _ = revocationRepo.Append(context.Background(), &dto.RevocationEvent{Type: role.ACCOUNT_BANNED, UserID: t.Auth.UserID, Status: string(role.BANNED)})
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.SERVICE}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, revocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token)))
+	defer cancel()
+
+	stream := &mock.RevocationStreamMock{Ctx: ctx, Cancel: cancel, Expect: 2}
+
+	go func() {
+		_, _ = revocationRepo.Append(context.Background(), &dto.RevocationEvent{Type: role.ACCOUNT_REINSTATED, UserID: t.Auth.UserID})
+	}()
+
+	err := srv.WatchRevocations(&auth_proto.WatchRevocationsRequest{Offset: seen}, stream)
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), codes.Canceled, st.Code())
+	assert.Len(t.T(), stream.Events, 2)
+	assert.Equal(t.T(), string(role.ACCOUNT_BANNED), stream.Events[0].Type)
+	assert.Equal(t.T(), string(role.BANNED), stream.Events[0].Status)
+	assert.Equal(t.T(), string(role.ACCOUNT_REINSTATED), stream.Events[1].Type)
+	assert.Equal(t.T(), t.Auth.UserID, stream.Events[1].UserId)
+}
+
+func (t *AuthServiceTest) TestWatchRevocationsRoleChanged() {
+	adminToken := faker.Word()
+	token := faker.Word()
+
+	promoted := *t.Auth
+	promoted.Role = string(role.ADMIN)
+
+	revocationRepo := cacheRepo.NewMemoryRevocationRepository(config.Cache{})
+	seen, _ := revocationRepo.Append(context.Background(), &dto.RevocationEvent{Type: role.SESSION_REVOKED, UserID: faker.UUIDDigit()})
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("UpdateRole", t.Auth.ID.String(), &promoted).Return(&promoted, nil)
+	repo.On("FindDeviceSessions", t.Auth.UserID, testifyMock.Anything).Return([]*auth.DeviceSession{}, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", adminToken).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.ADMIN}, nil)
+	tokenService.On("Validate", token).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.SERVICE}, nil)
+	tokenService.On("RemoveCredentials", t.Auth.UserID).Return(nil)
+	tokenService.On("RevokeImpersonations", t.Auth.UserID).Return(nil, nil)
+
+	srv := NewService(repo, &audit.RepositoryMock{}, revocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	adminCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+adminToken))
+	_, err := srv.ChangeRole(adminCtx, &auth_proto.ChangeRoleRequest{UserId: t.Auth.UserID, Role: string(role.ADMIN)})
+	assert.Nilf(t.T(), err, "error: %v", err)
+
+	ctx, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token)))
+	defer cancel()
+
+	stream := &mock.RevocationStreamMock{Ctx: ctx, Cancel: cancel, Expect: 1}
+
+	err = srv.WatchRevocations(&auth_proto.WatchRevocationsRequest{Offset: seen}, stream)
+
+	assert.Equal(t.T(), codes.Canceled, status.Code(err))
+	assert.Len(t.T(), stream.Events, 1)
+	assert.Equal(t.T(), string(role.ROLE_CHANGED), stream.Events[0].Type)
+	assert.Equal(t.T(), t.Auth.UserID, stream.Events[0].UserId)
+	assert.Equal(t.T(), string(role.ADMIN), stream.Events[0].Role)
+}
+
+func (t *AuthServiceTest) TestWatchRevocationsExpiredOffset() {
+	token := faker.Word()
+
+	revocationRepo := cacheRepo.NewMemoryRevocationRepository(config.Cache{RevocationStreamLength: 1})
+	expired, _ := revocationRepo.Append(context.Background(), &dto.RevocationEvent{Type: role.SESSION_REVOKED, UserID: faker.UUIDDigit()})
+	_, _ = revocationRepo.Append(context.Background(), &dto.RevocationEvent{Type: role.SESSION_REVOKED, UserID: faker.UUIDDigit()})
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.SERVICE}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, revocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token)))
+	defer cancel()
+
+	err := srv.WatchRevocations(&auth_proto.WatchRevocationsRequest{Offset: expired}, &mock.RevocationStreamMock{Ctx: ctx, Cancel: cancel})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), codes.OutOfRange, st.Code())
+}
+
+func (t *AuthServiceTest) TestWatchRevocationsForbidden() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	ctx, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token)))
+	defer cancel()
+
+	err := srv.WatchRevocations(&auth_proto.WatchRevocationsRequest{}, &mock.RevocationStreamMock{Ctx: ctx, Cancel: cancel})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	t.RevocationRepo.AssertNotCalled(t.T(), "Seek", testifyMock.Anything)
+}
+
+func (t *AuthServiceTest) TestWatchRevocationsShutdown() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.ADMIN}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, cacheRepo.NewMemoryRevocationRepository(config.Cache{}), tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv.Shutdown()
+
+	ctx, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token)))
+	defer cancel()
+
+	err := srv.WatchRevocations(&auth_proto.WatchRevocationsRequest{}, &mock.RevocationStreamMock{Ctx: ctx, Cancel: cancel})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), codes.Unavailable, st.Code())
+}
+
+func (t *AuthServiceTest) TestValidateBatchSuccess() {
+	key := "mgl_" + faker.Word()
+	token := faker.Word()
+	suspendedToken := faker.Word()
+
+	apiKeyService := &mock.ApiKeyServiceMock{}
+	apiKeyService.On("ValidateApiKey", key).Return(&dto.UserCredential{
+		UserId: t.UserDto.Id,
+		Role:   role.SERVICE,
+		Scopes: []string{"rating:read"},
+	}, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("ValidateBatch", []string{token, suspendedToken}).Return(
+		[]*dto.UserCredential{t.UserCredential, nil},
+		[]error{nil, ts.ErrAccountSuspended},
+	)
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, apiKeyService, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	want := &auth_proto.ValidateBatchResponse{Results: []*auth_proto.ValidateResult{
+		{Response: &auth_proto.ValidateResponse{UserId: t.UserDto.Id, Role: t.Auth.Role}},
+		{Response: &auth_proto.ValidateResponse{UserId: t.UserDto.Id, Role: string(role.SERVICE), Scopes: []string{"rating:read"}}},
+		{Error: &auth_proto.ValidateError{
+			Code:    int32(codes.PermissionDenied),
+			Message: ts.ErrAccountSuspended.Error(),
+			Reason:  "ACCOUNT_SUSPENDED",
+		}},
+	}}
+
+	actual, err := srv.ValidateBatch(context.Background(), &auth_proto.ValidateBatchRequest{Requests: []*auth_proto.ValidateRequest{
+		{Token: token},
+		{Token: key},
+		{Token: suspendedToken},
+	}})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	tokenService.AssertNumberOfCalls(t.T(), "ValidateBatch", 1)
+	tokenService.AssertNotCalled(t.T(), "Validate", testifyMock.Anything)
+}
+
+func (t *AuthServiceTest) TestValidateBatchTooLarge() {
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(&mock.RepositoryMock{}, &audit.RepositoryMock{}, t.RevocationRepo, tokenService, &mock.ApiKeyServiceMock{}, &mock.ClientServiceMock{}, &mock.UserServiceMock{}, t.conf, &t.oauthConf, t.googleOauthClient)
+
+	requests := make([]*auth_proto.ValidateRequest, maxValidateBatch+1)
+	for i := range requests {
+		requests[i] = &auth_proto.ValidateRequest{Token: faker.Word()}
+	}
+
+	actual, err := srv.ValidateBatch(context.Background(), &auth_proto.ValidateBatchRequest{Requests: requests})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+	tokenService.AssertNotCalled(t.T(), "ValidateBatch", testifyMock.Anything)
+}
@@ -0,0 +1,335 @@
+package device
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	deviceDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/device"
+	"github.com/bookpanda/mygraderlist-auth/src/app/utils"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/constant/device"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// user codes avoid vowels and look-alike characters so they are easy to type and never spell words
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+
+	// slowDownIncrement is added to the polling interval every time a client polls too fast (RFC 8628 section 3.5)
+	slowDownIncrement = 5
+)
+
+type Service struct {
+	cacheRepository   ICacheRepository
+	tokenService      ITokenService
+	credentialService ICredentialService
+	clientService     IClientService
+	conf              config.Device
+}
+
+type ICacheRepository interface {
+	SaveCache(context.Context, string, interface{}, int) error
+	GetCache(context.Context, string, interface{}) error
+	TakeCache(context.Context, string, interface{}) error
+	RemoveCache(context.Context, string) error
+}
+
+type ITokenService interface {
+	Validate(context.Context, string) (*dto.UserCredential, error)
+}
+
+type ICredentialService interface {
+	IssueDeviceCredential(context.Context, string, string) (*auth_proto.Credential, error)
+}
+
+type IClientService interface {
+	FindByClientID(string) (*config.Client, error)
+}
+
+func NewService(
+	cacheRepository ICacheRepository,
+	tokenService ITokenService,
+	credentialService ICredentialService,
+	clientService IClientService,
+	conf config.Device,
+) *Service {
+	return &Service{
+		cacheRepository:   cacheRepository,
+		tokenService:      tokenService,
+		credentialService: credentialService,
+		clientService:     clientService,
+		conf:              conf,
+	}
+}
+
+func (s *Service) StartDeviceAuthorization(ctx context.Context, req *auth_proto.StartDeviceAuthorizationRequest) (*auth_proto.StartDeviceAuthorizationResponse, error) {
+	if _, err := s.clientService.FindByClientID(req.ClientId); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid client")
+	}
+
+	deviceCode, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return nil, internalError(ctx, err, "Error while generating the device code")
+	}
+
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, internalError(ctx, err, "Error while generating the user code")
+	}
+
+	authorization := &deviceDto.CacheDeviceAuthorization{
+		ClientID:  req.ClientId,
+		UserCode:  userCode,
+		Status:    device.PENDING,
+		Interval:  s.conf.Interval,
+		ExpiresAt: time.Now().Add(time.Duration(s.conf.ExpiresIn) * time.Second).Unix(),
+	}
+
+	err = s.cacheRepository.SaveCache(ctx, deviceCacheKey(deviceCode), authorization, int(s.conf.ExpiresIn))
+	if err != nil {
+		return nil, internalError(ctx, err, "Cannot connect to cache server")
+	}
+
+	err = s.cacheRepository.SaveCache(ctx, userCodeCacheKey(userCode), deviceCacheKey(deviceCode), int(s.conf.ExpiresIn))
+	if err != nil {
+		return nil, internalError(ctx, err, "Cannot connect to cache server")
+	}
+
+	displayCode := userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+
+	return &auth_proto.StartDeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                displayCode,
+		VerificationUri:         s.conf.VerificationUri,
+		VerificationUriComplete: s.conf.VerificationUri + "?user_code=" + url.QueryEscape(displayCode),
+		ExpiresIn:               s.conf.ExpiresIn,
+		Interval:                s.conf.Interval,
+	}, nil
+}
+
+func (s *Service) ApproveDevice(ctx context.Context, req *auth_proto.ApproveDeviceRequest) (*auth_proto.ApproveDeviceResponse, error) {
+	credential, err := utils.Authorize(ctx, s.tokenService.Validate, role.USER, role.ADMIN)
+	if err != nil {
+		return nil, err
+	}
+
+	if credential.ActorId != "" {
+		return nil, status.Error(codes.PermissionDenied, "Cannot approve a device while impersonating")
+	}
+
+	userCode := normalizeUserCode(req.UserCode)
+
+	var deviceKey string
+	err = s.cacheRepository.GetCache(ctx, userCodeCacheKey(userCode), &deviceKey)
+	if err != nil {
+		if err != redis.Nil {
+			return nil, internalError(ctx, err, "Cannot connect to cache server")
+		}
+		return nil, status.Error(codes.NotFound, "Invalid user code")
+	}
+
+	authorization := deviceDto.CacheDeviceAuthorization{}
+	err = s.cacheRepository.GetCache(ctx, deviceKey, &authorization)
+	if err != nil {
+		if err != redis.Nil {
+			return nil, internalError(ctx, err, "Cannot connect to cache server")
+		}
+		return nil, status.Error(codes.NotFound, "Invalid user code")
+	}
+
+	if authorization.Status != device.PENDING {
+		return nil, status.Error(codes.FailedPrecondition, "Device authorization is already handled")
+	}
+
+	authorization.Status = device.APPROVED
+	authorization.UserID = credential.UserId
+	if req.Deny {
+		authorization.Status = device.DENIED
+		authorization.UserID = ""
+	}
+
+	if err := s.saveAuthorization(ctx, deviceKey, &authorization); err != nil {
+		return nil, err
+	}
+
+	if err := s.cacheRepository.RemoveCache(ctx, userCodeCacheKey(userCode)); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).
+			Str("service", "device").
+			Str("module", "approve").
+			Msg("Cannot remove the used user code")
+	}
+
+	zerolog.Ctx(ctx).Info().
+		Str("service", "device").
+		Str("module", "approve").
+		Str("user_id", credential.UserId).
+		Str("client_id", authorization.ClientID).
+		Str("status", string(authorization.Status)).
+		Msg("Device authorization handled")
+
+	return &auth_proto.ApproveDeviceResponse{Success: true}, nil
+}
+
+func (s *Service) PollDeviceToken(ctx context.Context, req *auth_proto.PollDeviceTokenRequest) (*auth_proto.PollDeviceTokenResponse, error) {
+	deviceKey := deviceCacheKey(req.DeviceCode)
+
+	authorization := deviceDto.CacheDeviceAuthorization{}
+	err := s.cacheRepository.GetCache(ctx, deviceKey, &authorization)
+	if err != nil {
+		if err != redis.Nil {
+			return nil, internalError(ctx, err, "Cannot connect to cache server")
+		}
+		return nil, deviceError(codes.NotFound, "expired_token", "Device code is expired")
+	}
+
+	if authorization.ClientID != req.ClientId {
+		return nil, deviceError(codes.PermissionDenied, "invalid_grant", "Device code was issued to another client")
+	}
+
+	// a poll only writes its own state, it can't overwrite a concurrent approval, and an approved
+	// authorization is never written back, a poll racing with its redemption can't revive it
+	if authorization.Status == device.APPROVED {
+		return s.redeem(ctx, deviceKey)
+	}
+
+	pollKey := pollCacheKey(req.DeviceCode)
+
+	poll := deviceDto.CacheDevicePoll{Interval: authorization.Interval}
+	err = s.cacheRepository.GetCache(ctx, pollKey, &poll)
+	if err != nil && err != redis.Nil {
+		return nil, internalError(ctx, err, "Cannot connect to cache server")
+	}
+
+	now := time.Now().Unix()
+	if poll.LastPolledAt != 0 && now-poll.LastPolledAt < int64(poll.Interval) {
+		poll.Interval += slowDownIncrement
+		poll.LastPolledAt = now
+		if err := s.saveUntil(ctx, pollKey, &poll, authorization.ExpiresAt); err != nil {
+			return nil, err
+		}
+		return nil, deviceError(codes.ResourceExhausted, "slow_down", "Polling too frequently")
+	}
+
+	switch authorization.Status {
+	case device.DENIED:
+		if err := s.cacheRepository.RemoveCache(ctx, deviceKey); err != nil {
+			return nil, internalError(ctx, err, "Cannot connect to cache server")
+		}
+
+		return nil, deviceError(codes.PermissionDenied, "access_denied", "Device authorization was denied")
+	default:
+		poll.LastPolledAt = now
+		if err := s.saveUntil(ctx, pollKey, &poll, authorization.ExpiresAt); err != nil {
+			return nil, err
+		}
+
+		return nil, deviceError(codes.FailedPrecondition, "authorization_pending", "Device authorization is pending")
+	}
+}
+
+// redeem takes the approved authorization out of the cache, so concurrent polls can't both get a
+// credential for it. It's put back when the credential can't be issued, for the next poll to retry.
+func (s *Service) redeem(ctx context.Context, deviceKey string) (*auth_proto.PollDeviceTokenResponse, error) {
+	authorization := deviceDto.CacheDeviceAuthorization{}
+	err := s.cacheRepository.TakeCache(ctx, deviceKey, &authorization)
+	if err != nil {
+		if err != redis.Nil {
+			return nil, internalError(ctx, err, "Cannot connect to cache server")
+		}
+		return nil, deviceError(codes.NotFound, "expired_token", "Device code is expired")
+	}
+
+	credential, err := s.credentialService.IssueDeviceCredential(ctx, authorization.UserID, authorization.ClientID)
+	if err != nil {
+		if saveErr := s.saveAuthorization(ctx, deviceKey, &authorization); saveErr != nil {
+			zerolog.Ctx(ctx).Warn().Err(saveErr).
+				Str("service", "device").
+				Str("module", "poll").
+				Msg("Cannot put back the device authorization")
+		}
+		return nil, err
+	}
+
+	return &auth_proto.PollDeviceTokenResponse{Credential: credential}, nil
+}
+
+// saveAuthorization writes the authorization back while keeping the expiry it was created with
+func (s *Service) saveAuthorization(ctx context.Context, deviceKey string, authorization *deviceDto.CacheDeviceAuthorization) error {
+	return s.saveUntil(ctx, deviceKey, authorization, authorization.ExpiresAt)
+}
+
+func (s *Service) saveUntil(ctx context.Context, key string, value interface{}, expiresAt int64) error {
+	ttl := expiresAt - time.Now().Unix()
+	if ttl <= 0 {
+		return deviceError(codes.NotFound, "expired_token", "Device code is expired")
+	}
+
+	err := s.cacheRepository.SaveCache(ctx, key, value, int(ttl))
+	if err != nil {
+		return internalError(ctx, err, "Cannot connect to cache server")
+	}
+
+	return nil
+}
+
+func deviceCacheKey(deviceCode string) string {
+	return "device:" + utils.Hash([]byte(deviceCode))
+}
+
+func pollCacheKey(deviceCode string) string {
+	return "device_poll:" + utils.Hash([]byte(deviceCode))
+}
+
+func userCodeCacheKey(userCode string) string {
+	return "device_user_code:" + userCode
+}
+
+func normalizeUserCode(userCode string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(userCode), "-", ""))
+}
+
+func generateUserCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+
+	var code strings.Builder
+	for i := 0; i < userCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(userCodeAlphabet[n.Int64()])
+	}
+
+	return code.String(), nil
+}
+
+func deviceError(code codes.Code, reason string, msg string) error {
+	st, err := status.New(code, msg).WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: role.ERROR_DOMAIN,
+	})
+	if err != nil {
+		return status.Error(code, msg)
+	}
+
+	return st.Err()
+}
+
+func internalError(ctx context.Context, err error, msg string) error {
+	zerolog.Ctx(ctx).Error().Err(err).
+		Str("service", "device").
+		Msg(msg)
+	return status.Error(codes.Internal, "Internal server error")
+}
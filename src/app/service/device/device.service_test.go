@@ -0,0 +1,335 @@
+package device
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	deviceDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/device"
+	cacheRepo "github.com/bookpanda/mygraderlist-auth/src/app/repository/cache"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/constant/device"
+	mock "github.com/bookpanda/mygraderlist-auth/src/mocks/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/mocks/cache"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/bxcodec/faker/v3"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	testifyMock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type DeviceServiceTest struct {
+	suite.Suite
+	Conf          config.Device
+	Client        *config.Client
+	DeviceCode    string
+	Authorization *deviceDto.CacheDeviceAuthorization
+	Credential    *auth_proto.Credential
+}
+
+func TestDeviceService(t *testing.T) {
+	suite.Run(t, new(DeviceServiceTest))
+}
+
+func (t *DeviceServiceTest) SetupTest() {
+	t.Conf = config.Device{
+		VerificationUri: "https://mygraderlist.bookpanda.dev/device",
+		ExpiresIn:       600,
+		Interval:        5,
+	}
+
+	t.Client = &config.Client{
+		ClientID: "mgl-cli",
+	}
+
+	t.DeviceCode = faker.Password()
+
+	t.Authorization = &deviceDto.CacheDeviceAuthorization{
+		ClientID:  t.Client.ClientID,
+		UserCode:  "BCDFGHJK",
+		Status:    device.PENDING,
+		Interval:  t.Conf.Interval,
+		ExpiresAt: time.Now().Add(time.Duration(t.Conf.ExpiresIn) * time.Second).Unix(),
+	}
+
+	t.Credential = &auth_proto.Credential{
+		AccessToken:  faker.Word(),
+		RefreshToken: faker.Word(),
+		ExpiresIn:    3600,
+	}
+}
+
+func (t *DeviceServiceTest) TestStartDeviceAuthorizationSuccess() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("SaveCache", testifyMock.Anything, testifyMock.Anything, int(t.Conf.ExpiresIn)).Return(nil)
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(t.Client, nil)
+
+	srv := NewService(cacheRepo, &mock.TokenServiceMock{}, &mock.CredentialServiceMock{}, clientService, t.Conf)
+
+	actual, err := srv.StartDeviceAuthorization(context.Background(), &auth_proto.StartDeviceAuthorizationRequest{ClientId: t.Client.ClientID})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Len(t.T(), actual.UserCode, userCodeLength+1)
+	assert.Equal(t.T(), t.Conf.VerificationUri, actual.VerificationUri)
+	assert.True(t.T(), strings.HasPrefix(actual.VerificationUriComplete, t.Conf.VerificationUri+"?user_code="))
+	assert.Equal(t.T(), deviceCacheKey(actual.DeviceCode), cacheRepo.V[userCodeCacheKey(normalizeUserCode(actual.UserCode))])
+	assert.Equal(t.T(), device.PENDING, cacheRepo.V[deviceCacheKey(actual.DeviceCode)].(*deviceDto.CacheDeviceAuthorization).Status)
+}
+
+func (t *DeviceServiceTest) TestStartDeviceAuthorizationInvalidClient() {
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(nil, redis.Nil)
+
+	srv := NewService(&cache.RepositoryMock{}, &mock.TokenServiceMock{}, &mock.CredentialServiceMock{}, clientService, t.Conf)
+
+	actual, err := srv.StartDeviceAuthorization(context.Background(), &auth_proto.StartDeviceAuthorizationRequest{ClientId: t.Client.ClientID})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *DeviceServiceTest) TestApproveDeviceSuccess() {
+	token := faker.Word()
+	userID := faker.UUIDDigit()
+	deviceKey := deviceCacheKey(t.DeviceCode)
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("GetCache", userCodeCacheKey(t.Authorization.UserCode), testifyMock.Anything).Return(&deviceKey, nil)
+	cacheRepo.On("GetCache", deviceKey, testifyMock.Anything).Return(t.Authorization, nil)
+	cacheRepo.On("SaveCache", deviceKey, testifyMock.Anything, testifyMock.Anything).Return(nil)
+	cacheRepo.On("RemoveCache", userCodeCacheKey(t.Authorization.UserCode)).Return(nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{UserId: userID, Role: role.USER}, nil)
+
+	srv := NewService(cacheRepo, tokenService, &mock.CredentialServiceMock{}, &mock.ClientServiceMock{}, t.Conf)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	actual, err := srv.ApproveDevice(ctx, &auth_proto.ApproveDeviceRequest{UserCode: "bcdf-ghjk"})
+
+	saved := cacheRepo.V[deviceKey].(*deviceDto.CacheDeviceAuthorization)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.True(t.T(), actual.Success)
+	assert.Equal(t.T(), device.Status(device.APPROVED), saved.Status)
+	assert.Equal(t.T(), userID, saved.UserID)
+	cacheRepo.AssertCalled(t.T(), "RemoveCache", userCodeCacheKey(t.Authorization.UserCode))
+}
+
+func (t *DeviceServiceTest) TestPollDeviceTokenPending() {
+	deviceKey := deviceCacheKey(t.DeviceCode)
+	pollKey := pollCacheKey(t.DeviceCode)
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("GetCache", deviceKey, testifyMock.Anything).Return(t.Authorization, nil)
+	cacheRepo.On("GetCache", pollKey, testifyMock.Anything).Return(nil, redis.Nil)
+	cacheRepo.On("SaveCache", pollKey, testifyMock.Anything, testifyMock.Anything).Return(nil)
+
+	srv := NewService(cacheRepo, &mock.TokenServiceMock{}, &mock.CredentialServiceMock{}, &mock.ClientServiceMock{}, t.Conf)
+
+	actual, err := srv.PollDeviceToken(context.Background(), &auth_proto.PollDeviceTokenRequest{
+		ClientId:   t.Client.ClientID,
+		DeviceCode: t.DeviceCode,
+	})
+
+	st, _ := status.FromError(err)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.FailedPrecondition, st.Code())
+	assert.Equal(t.T(), "authorization_pending", st.Details()[0].(*errdetails.ErrorInfo).Reason)
+	assert.NotZero(t.T(), cacheRepo.V[pollKey].(*deviceDto.CacheDevicePoll).LastPolledAt)
+	cacheRepo.AssertNotCalled(t.T(), "SaveCache", deviceKey, testifyMock.Anything, testifyMock.Anything)
+}
+
+func (t *DeviceServiceTest) TestPollDeviceTokenSlowDown() {
+	deviceKey := deviceCacheKey(t.DeviceCode)
+	pollKey := pollCacheKey(t.DeviceCode)
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("GetCache", deviceKey, testifyMock.Anything).Return(t.Authorization, nil)
+	cacheRepo.On("GetCache", pollKey, testifyMock.Anything).Return(&deviceDto.CacheDevicePoll{
+		Interval:     t.Conf.Interval,
+		LastPolledAt: time.Now().Unix(),
+	}, nil)
+	cacheRepo.On("SaveCache", pollKey, testifyMock.Anything, testifyMock.Anything).Return(nil)
+
+	srv := NewService(cacheRepo, &mock.TokenServiceMock{}, &mock.CredentialServiceMock{}, &mock.ClientServiceMock{}, t.Conf)
+
+	actual, err := srv.PollDeviceToken(context.Background(), &auth_proto.PollDeviceTokenRequest{
+		ClientId:   t.Client.ClientID,
+		DeviceCode: t.DeviceCode,
+	})
+
+	st, _ := status.FromError(err)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.ResourceExhausted, st.Code())
+	assert.Equal(t.T(), "slow_down", st.Details()[0].(*errdetails.ErrorInfo).Reason)
+	assert.Equal(t.T(), t.Conf.Interval+slowDownIncrement, cacheRepo.V[pollKey].(*deviceDto.CacheDevicePoll).Interval)
+}
+
+func (t *DeviceServiceTest) TestPollDeviceTokenApproved() {
+	deviceKey := deviceCacheKey(t.DeviceCode)
+	t.Authorization.Status = device.APPROVED
+	t.Authorization.UserID = faker.UUIDDigit()
+
+	want := &auth_proto.PollDeviceTokenResponse{Credential: t.Credential}
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("GetCache", deviceKey, testifyMock.Anything).Return(t.Authorization, nil)
+	cacheRepo.On("TakeCache", deviceKey, testifyMock.Anything).Return(t.Authorization, nil)
+
+	credentialService := &mock.CredentialServiceMock{}
+	credentialService.On("IssueDeviceCredential", t.Authorization.UserID, t.Client.ClientID).Return(t.Credential, nil)
+
+	srv := NewService(cacheRepo, &mock.TokenServiceMock{}, credentialService, &mock.ClientServiceMock{}, t.Conf)
+
+	actual, err := srv.PollDeviceToken(context.Background(), &auth_proto.PollDeviceTokenRequest{
+		ClientId:   t.Client.ClientID,
+		DeviceCode: t.DeviceCode,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	cacheRepo.AssertCalled(t.T(), "TakeCache", deviceKey, testifyMock.Anything)
+}
+
+func (t *DeviceServiceTest) TestPollDeviceTokenAlreadyRedeemed() {
+	deviceKey := deviceCacheKey(t.DeviceCode)
+	t.Authorization.Status = device.APPROVED
+	t.Authorization.UserID = faker.UUIDDigit()
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("GetCache", deviceKey, testifyMock.Anything).Return(t.Authorization, nil)
+	cacheRepo.On("TakeCache", deviceKey, testifyMock.Anything).Return(nil, redis.Nil)
+
+	credentialService := &mock.CredentialServiceMock{}
+
+	srv := NewService(cacheRepo, &mock.TokenServiceMock{}, credentialService, &mock.ClientServiceMock{}, t.Conf)
+
+	actual, err := srv.PollDeviceToken(context.Background(), &auth_proto.PollDeviceTokenRequest{
+		ClientId:   t.Client.ClientID,
+		DeviceCode: t.DeviceCode,
+	})
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, status.Code(err))
+	credentialService.AssertNotCalled(t.T(), "IssueDeviceCredential", testifyMock.Anything, testifyMock.Anything)
+}
+
+func (t *DeviceServiceTest) TestPollDeviceTokenIssueFailure() {
+	deviceKey := deviceCacheKey(t.DeviceCode)
+	t.Authorization.Status = device.APPROVED
+	t.Authorization.UserID = faker.UUIDDigit()
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("GetCache", deviceKey, testifyMock.Anything).Return(t.Authorization, nil)
+	cacheRepo.On("TakeCache", deviceKey, testifyMock.Anything).Return(t.Authorization, nil)
+	cacheRepo.On("SaveCache", deviceKey, testifyMock.Anything, testifyMock.Anything).Return(nil)
+
+	credentialService := &mock.CredentialServiceMock{}
+	credentialService.On("IssueDeviceCredential", t.Authorization.UserID, t.Client.ClientID).Return(nil, status.Error(codes.Internal, "Internal server error"))
+
+	srv := NewService(cacheRepo, &mock.TokenServiceMock{}, credentialService, &mock.ClientServiceMock{}, t.Conf)
+
+	actual, err := srv.PollDeviceToken(context.Background(), &auth_proto.PollDeviceTokenRequest{
+		ClientId:   t.Client.ClientID,
+		DeviceCode: t.DeviceCode,
+	})
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Internal, status.Code(err))
+	assert.Equal(t.T(), device.Status(device.APPROVED), cacheRepo.V[deviceKey].(*deviceDto.CacheDeviceAuthorization).Status)
+}
+
+func (t *DeviceServiceTest) TestPollDeviceTokenExpired() {
+	deviceKey := deviceCacheKey(t.DeviceCode)
+
+	cacheRepo := &cache.RepositoryMock{}
+	cacheRepo.On("GetCache", deviceKey, testifyMock.Anything).Return(nil, redis.Nil)
+
+	srv := NewService(cacheRepo, &mock.TokenServiceMock{}, &mock.CredentialServiceMock{}, &mock.ClientServiceMock{}, t.Conf)
+
+	actual, err := srv.PollDeviceToken(context.Background(), &auth_proto.PollDeviceTokenRequest{
+		ClientId:   t.Client.ClientID,
+		DeviceCode: t.DeviceCode,
+	})
+
+	st, _ := status.FromError(err)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, st.Code())
+	assert.Equal(t.T(), "expired_token", st.Details()[0].(*errdetails.ErrorInfo).Reason)
+}
+
+// approvingRepository approves the device while a poll is between reading the authorization and
+// writing its own state back
+type approvingRepository struct {
+	*cacheRepo.MemoryRepository
+	pollKey string
+	approve func()
+}
+
+func (r *approvingRepository) GetCache(ctx context.Context, key string, value interface{}) error {
+	if key == r.pollKey && r.approve != nil {
+		approve := r.approve
+		r.approve = nil
+		approve()
+	}
+
+	return r.MemoryRepository.GetCache(ctx, key, value)
+}
+
+func (t *DeviceServiceTest) TestPollDeviceTokenApprovedWhilePolling() {
+	token := faker.Word()
+	userID := faker.UUIDDigit()
+
+	repo := &approvingRepository{MemoryRepository: cacheRepo.NewMemoryRepository(config.Cache{})}
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(t.Client, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{UserId: userID, Role: role.USER}, nil)
+
+	credentialService := &mock.CredentialServiceMock{}
+	credentialService.On("IssueDeviceCredential", userID, t.Client.ClientID).Return(t.Credential, nil)
+
+	srv := NewService(repo, tokenService, credentialService, clientService, t.Conf)
+
+	started, err := srv.StartDeviceAuthorization(context.Background(), &auth_proto.StartDeviceAuthorizationRequest{ClientId: t.Client.ClientID})
+	assert.Nilf(t.T(), err, "error: %v", err)
+
+	repo.pollKey = pollCacheKey(started.DeviceCode)
+	repo.approve = func() {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+		_, err := srv.ApproveDevice(ctx, &auth_proto.ApproveDeviceRequest{UserCode: started.UserCode})
+		assert.Nilf(t.T(), err, "error: %v", err)
+	}
+
+	req := &auth_proto.PollDeviceTokenRequest{
+		ClientId:   t.Client.ClientID,
+		DeviceCode: started.DeviceCode,
+	}
+
+	_, err = srv.PollDeviceToken(context.Background(), req)
+	assert.Equal(t.T(), codes.FailedPrecondition, status.Code(err))
+
+	actual, err := srv.PollDeviceToken(context.Background(), req)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.PollDeviceTokenResponse{Credential: t.Credential}, actual)
+}
@@ -0,0 +1,206 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// LivenessService is reported as serving for as long as the process runs, while the
+// overall "" service and the registered services follow the readiness
+const LivenessService = "liveness"
+
+const (
+	defaultInterval = 10 * time.Second
+	defaultTimeout  = 2 * time.Second
+)
+
+// Probe checks a single dependency, a nil error means the dependency is healthy
+type Probe func(context.Context) error
+
+// Dependency is probed periodically, the service is not ready while a required dependency is failing
+type Dependency struct {
+	Name     string
+	Probe    Probe
+	Required bool
+}
+
+type Service struct {
+	server       IHealthServer
+	dependencies []Dependency
+	services     []string
+	interval     time.Duration
+	timeout      time.Duration
+
+	mu       sync.RWMutex
+	failures map[string]error
+	checked  bool
+	shutdown bool
+}
+
+type IHealthServer interface {
+	SetServingStatus(string, grpc_health_v1.HealthCheckResponse_ServingStatus)
+	Shutdown()
+}
+
+func NewService(server IHealthServer, dependencies []Dependency, services []string, conf config.Health) *Service {
+	interval := time.Duration(conf.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	timeout := time.Duration(conf.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	s := &Service{
+		server:       server,
+		dependencies: dependencies,
+		services:     services,
+		interval:     interval,
+		timeout:      timeout,
+		failures:     map[string]error{},
+	}
+
+	// not ready until the dependencies have been checked once
+	server.SetServingStatus(LivenessService, grpc_health_v1.HealthCheckResponse_SERVING)
+	s.setServingStatus(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	return s
+}
+
+// Start probes the dependencies right away and then on every interval until the context is done
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.Check(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Check probes every dependency concurrently and updates the serving statuses
+func (s *Service) Check(ctx context.Context) {
+	failures := make([]error, len(s.dependencies))
+
+	var wg sync.WaitGroup
+	for i, dependency := range s.dependencies {
+		wg.Add(1)
+		go func(i int, probe Probe) {
+			defer wg.Done()
+
+			probeCtx, cancel := context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+
+			failures[i] = probe(probeCtx)
+		}(i, dependency.Probe)
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, dependency := range s.dependencies {
+		s.logTransition(dependency, failures[i])
+
+		if failures[i] != nil {
+			s.failures[dependency.Name] = failures[i]
+		} else {
+			delete(s.failures, dependency.Name)
+		}
+	}
+	s.checked = true
+
+	if s.shutdown {
+		return
+	}
+
+	if s.ready() {
+		s.setServingStatus(grpc_health_v1.HealthCheckResponse_SERVING)
+	} else {
+		s.setServingStatus(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+// Status returns the readiness and the state of every dependency
+func (s *Service) Status() (bool, map[string]string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checks := map[string]string{}
+	for _, dependency := range s.dependencies {
+		switch {
+		case !s.checked:
+			checks[dependency.Name] = "unknown"
+		case s.failures[dependency.Name] != nil:
+			checks[dependency.Name] = "failing"
+		default:
+			checks[dependency.Name] = "ok"
+		}
+	}
+
+	return !s.shutdown && s.ready(), checks
+}
+
+// Shutdown reports every service as not serving so the load balancers stop sending new calls
+// while the in-flight ones drain
+func (s *Service) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdown = true
+	s.server.Shutdown()
+}
+
+func (s *Service) ready() bool {
+	if !s.checked {
+		return false
+	}
+
+	for _, dependency := range s.dependencies {
+		if dependency.Required && s.failures[dependency.Name] != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Service) setServingStatus(servingStatus grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	s.server.SetServingStatus("", servingStatus)
+	for _, service := range s.services {
+		s.server.SetServingStatus(service, servingStatus)
+	}
+}
+
+func (s *Service) logTransition(dependency Dependency, err error) {
+	previous := s.failures[dependency.Name]
+
+	switch {
+	case err != nil && previous == nil:
+		log.Error().
+			Err(err).
+			Str("service", "health").
+			Str("dependency", dependency.Name).
+			Bool("required", dependency.Required).
+			Msg("Dependency is failing")
+	case err == nil && previous != nil:
+		log.Info().
+			Str("service", "health").
+			Str("dependency", dependency.Name).
+			Msg("Dependency has recovered")
+	}
+}
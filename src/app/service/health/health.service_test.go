@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const authService = "auth.AuthService"
+
+type HealthServiceTest struct {
+	suite.Suite
+	Server     *health.Server
+	DatabaseUp bool
+	BackendUp  bool
+	Service    *Service
+}
+
+func TestHealthService(t *testing.T) {
+	suite.Run(t, new(HealthServiceTest))
+}
+
+func (t *HealthServiceTest) SetupTest() {
+	t.Server = health.NewServer()
+	t.DatabaseUp = true
+	t.BackendUp = true
+
+	probe := func(up *bool) Probe {
+		return func(context.Context) error {
+			if !*up {
+				return errors.New("connection refused")
+			}
+			return nil
+		}
+	}
+
+	t.Service = NewService(t.Server, []Dependency{
+		{Name: "database", Probe: probe(&t.DatabaseUp), Required: true},
+		{Name: "backend", Probe: probe(&t.BackendUp)},
+	}, []string{authService}, config.Health{})
+}
+
+func (t *HealthServiceTest) servingStatus(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	res, err := t.Server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+	assert.Nil(t.T(), err)
+
+	return res.Status
+}
+
+func (t *HealthServiceTest) TestNotReadyBeforeFirstCheck() {
+	ready, checks := t.Service.Status()
+
+	assert.False(t.T(), ready)
+	assert.Equal(t.T(), map[string]string{"database": "unknown", "backend": "unknown"}, checks)
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_NOT_SERVING, t.servingStatus(""))
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_SERVING, t.servingStatus(LivenessService))
+}
+
+func (t *HealthServiceTest) TestServingWhenDependenciesAreUp() {
+	t.Service.Check(context.Background())
+
+	ready, checks := t.Service.Status()
+
+	assert.True(t.T(), ready)
+	assert.Equal(t.T(), map[string]string{"database": "ok", "backend": "ok"}, checks)
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_SERVING, t.servingStatus(""))
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_SERVING, t.servingStatus(authService))
+}
+
+func (t *HealthServiceTest) TestNotServingWhenRequiredDependencyFails() {
+	t.Service.Check(context.Background())
+	t.DatabaseUp = false
+	t.Service.Check(context.Background())
+
+	ready, checks := t.Service.Status()
+
+	assert.False(t.T(), ready)
+	assert.Equal(t.T(), "failing", checks["database"])
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_NOT_SERVING, t.servingStatus(""))
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_NOT_SERVING, t.servingStatus(authService))
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_SERVING, t.servingStatus(LivenessService))
+
+	t.DatabaseUp = true
+	t.Service.Check(context.Background())
+
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_SERVING, t.servingStatus(authService))
+}
+
+func (t *HealthServiceTest) TestServingWhenOptionalDependencyFails() {
+	t.BackendUp = false
+	t.Service.Check(context.Background())
+
+	ready, checks := t.Service.Status()
+
+	assert.True(t.T(), ready)
+	assert.Equal(t.T(), "failing", checks["backend"])
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_SERVING, t.servingStatus(""))
+}
+
+func (t *HealthServiceTest) TestShutdown() {
+	t.Service.Check(context.Background())
+	t.Service.Shutdown()
+	t.Service.Check(context.Background())
+
+	ready, _ := t.Service.Status()
+
+	assert.False(t.T(), ready)
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_NOT_SERVING, t.servingStatus(""))
+	assert.Equal(t.T(), grpc_health_v1.HealthCheckResponse_NOT_SERVING, t.servingStatus(authService))
+}
@@ -1,15 +1,22 @@
 package jwt
 
 import (
+	"strings"
 	"time"
 
 	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
 	model "github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
 	"github.com/bookpanda/mygraderlist-auth/src/config"
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
 	_jwt "github.com/golang-jwt/jwt/v4"
 	"github.com/pkg/errors"
 )
 
+const (
+	defaultImpersonationExpiresIn = 900
+	defaultServiceExpiresIn       = 300
+)
+
 type IStrategy interface {
 	AuthDecode(*_jwt.Token) (interface{}, error)
 }
@@ -20,6 +27,14 @@ type Service struct {
 }
 
 func NewJwtService(conf config.Jwt, strategy IStrategy) *Service {
+	if conf.ImpersonationExpiresIn == 0 {
+		conf.ImpersonationExpiresIn = defaultImpersonationExpiresIn
+	}
+
+	if conf.ServiceExpiresIn == 0 {
+		conf.ServiceExpiresIn = defaultServiceExpiresIn
+	}
+
 	return &Service{
 		conf:     conf,
 		strategy: strategy,
@@ -34,7 +49,60 @@ func (s *Service) SignAuth(in *model.Auth) (string, error) {
 			IssuedAt:  _jwt.NewNumericDate(time.Now()),
 		},
 		UserId: in.UserID,
+		Role:   role.Role(in.Role),
+	}
+
+	return s.sign(payloads)
+}
+
+// SignDevice signs an access token of the device session, which is tracked apart from the one of the account
+func (s *Service) SignDevice(in *model.Auth, sessionID string) (string, error) {
+	payloads := &dto.TokenPayloadAuth{
+		RegisteredClaims: _jwt.RegisteredClaims{
+			Issuer:    s.conf.Issuer,
+			ExpiresAt: _jwt.NewNumericDate(time.Now().Add(time.Second * time.Duration(s.conf.ExpiresIn))),
+			IssuedAt:  _jwt.NewNumericDate(time.Now()),
+		},
+		UserId: in.UserID,
+		Role:   role.Role(in.Role),
+		Sid:    sessionID,
+	}
+
+	return s.sign(payloads)
+}
+
+func (s *Service) SignImpersonation(in *model.Auth, actorID string) (string, error) {
+	payloads := &dto.TokenPayloadAuth{
+		RegisteredClaims: _jwt.RegisteredClaims{
+			Issuer:    s.conf.Issuer,
+			ExpiresAt: _jwt.NewNumericDate(time.Now().Add(time.Second * time.Duration(s.conf.ImpersonationExpiresIn))),
+			IssuedAt:  _jwt.NewNumericDate(time.Now()),
+		},
+		UserId: in.UserID,
+		Role:   role.Role(in.Role),
+		Act:    &dto.ActorClaims{Sub: actorID},
 	}
+
+	return s.sign(payloads)
+}
+
+func (s *Service) SignService(clientID string, audience []string, scopes []string) (string, error) {
+	payloads := &dto.TokenPayloadService{
+		RegisteredClaims: _jwt.RegisteredClaims{
+			Issuer:    s.conf.Issuer,
+			Subject:   clientID,
+			Audience:  audience,
+			ExpiresAt: _jwt.NewNumericDate(time.Now().Add(time.Second * time.Duration(s.conf.ServiceExpiresIn))),
+			IssuedAt:  _jwt.NewNumericDate(time.Now()),
+		},
+		ClientId: clientID,
+		Scope:    strings.Join(scopes, " "),
+	}
+
+	return s.sign(payloads)
+}
+
+func (s *Service) sign(payloads _jwt.Claims) (string, error) {
 	token := _jwt.NewWithClaims(_jwt.SigningMethodHS256, payloads)
 
 	tokenStr, err := token.SignedString([]byte(s.conf.Secret))
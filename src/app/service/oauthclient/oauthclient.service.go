@@ -0,0 +1,67 @@
+package oauthclient
+
+import (
+	"crypto/subtle"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidClient = errors.New("Invalid client credentials")
+	ErrNotFound      = errors.New("Not found client")
+)
+
+// Service is the registry of clients allowed to obtain tokens from the auth service
+type Service struct {
+	clients map[string]config.Client
+}
+
+func NewService(clients []config.Client) *Service {
+	registry := make(map[string]config.Client, len(clients))
+	for _, c := range clients {
+		registry[c.ClientID] = c
+	}
+
+	return &Service{clients: registry}
+}
+
+func (s *Service) FindByClientID(clientID string) (*config.Client, error) {
+	client, ok := s.clients[clientID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &client, nil
+}
+
+func (s *Service) Authenticate(clientID string, clientSecret string) (*config.Client, error) {
+	client, ok := s.clients[clientID]
+	if !ok || client.ClientSecret == "" {
+		return nil, ErrInvalidClient
+	}
+
+	if subtle.ConstantTimeCompare([]byte(client.ClientSecret), []byte(clientSecret)) != 1 {
+		return nil, ErrInvalidClient
+	}
+
+	return &client, nil
+}
+
+// Identify accepts the client id alone from the public clients, which have no secret to keep, and
+// authenticates the confidential ones (RFC 7009 section 2.1)
+func (s *Service) Identify(clientID string, clientSecret string) (*config.Client, error) {
+	client, ok := s.clients[clientID]
+	if !ok {
+		return nil, ErrInvalidClient
+	}
+
+	if client.ClientSecret == "" {
+		if clientSecret != "" {
+			return nil, ErrInvalidClient
+		}
+		return &client, nil
+	}
+
+	return s.Authenticate(clientID, clientSecret)
+}
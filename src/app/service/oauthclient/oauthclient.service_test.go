@@ -0,0 +1,95 @@
+package oauthclient
+
+import (
+	"testing"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type OauthClientServiceTest struct {
+	suite.Suite
+	Client config.Client
+}
+
+func TestOauthClientService(t *testing.T) {
+	suite.Run(t, new(OauthClientServiceTest))
+}
+
+func (t *OauthClientServiceTest) SetupTest() {
+	t.Client = config.Client{
+		ClientID:     faker.Word(),
+		ClientSecret: faker.Password(),
+		Name:         faker.Name(),
+		Audiences:    []string{faker.Word()},
+		Scopes:       []string{faker.Word()},
+	}
+}
+
+func (t *OauthClientServiceTest) TestAuthenticateSuccess() {
+	srv := NewService([]config.Client{t.Client})
+
+	actual, err := srv.Authenticate(t.Client.ClientID, t.Client.ClientSecret)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), &t.Client, actual)
+}
+
+func (t *OauthClientServiceTest) TestAuthenticateInvalidSecret() {
+	srv := NewService([]config.Client{t.Client})
+
+	actual, err := srv.Authenticate(t.Client.ClientID, faker.Password())
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidClient, err)
+}
+
+func (t *OauthClientServiceTest) TestAuthenticateUnknownClient() {
+	srv := NewService([]config.Client{t.Client})
+
+	actual, err := srv.Authenticate(faker.Word(), t.Client.ClientSecret)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidClient, err)
+}
+
+func (t *OauthClientServiceTest) TestAuthenticatePublicClient() {
+	t.Client.ClientSecret = ""
+	srv := NewService([]config.Client{t.Client})
+
+	actual, err := srv.Authenticate(t.Client.ClientID, "")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidClient, err)
+}
+
+func (t *OauthClientServiceTest) TestIdentifyPublicClient() {
+	t.Client.ClientSecret = ""
+	srv := NewService([]config.Client{t.Client})
+
+	actual, err := srv.Identify(t.Client.ClientID, "")
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), &t.Client, actual)
+}
+
+func (t *OauthClientServiceTest) TestIdentifyPublicClientWithSecret() {
+	t.Client.ClientSecret = ""
+	srv := NewService([]config.Client{t.Client})
+
+	actual, err := srv.Identify(t.Client.ClientID, faker.Password())
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidClient, err)
+}
+
+func (t *OauthClientServiceTest) TestIdentifyConfidentialClientWithoutSecret() {
+	srv := NewService([]config.Client{t.Client})
+
+	actual, err := srv.Identify(t.Client.ClientID, "")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidClient, err)
+}
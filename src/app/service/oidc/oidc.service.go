@@ -0,0 +1,587 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	oidcDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oidc"
+	"github.com/bookpanda/mygraderlist-auth/src/app/metrics"
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
+	consentModel "github.com/bookpanda/mygraderlist-auth/src/app/model/consent"
+	ts "github.com/bookpanda/mygraderlist-auth/src/app/service/token"
+	"github.com/bookpanda/mygraderlist-auth/src/app/utils"
+	"github.com/bookpanda/mygraderlist-auth/src/client"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/go-redis/redis/v8"
+	_jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+
+	// RFC 7636 section 4.1
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+)
+
+var supportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+// Error is an OAuth 2.0 error response. When RedirectUri is set the error is reported back to the client
+// through the redirect, otherwise it is shown to the user agent directly (RFC 6749 section 4.1.2.1)
+type Error struct {
+	Code        string
+	Description string
+	RedirectUri string
+	State       string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+type Service struct {
+	cacheRepository   ICacheRepository
+	consentRepository IConsentRepository
+	tokenService      ITokenService
+	clientService     IClientService
+	accountService    IAccountService
+	googleOauthClient IGoogleOauthClient
+	oauthConfig       *oauth2.Config
+	key               *rsa.PrivateKey
+	keyID             string
+	conf              config.Oidc
+}
+
+type ICacheRepository interface {
+	SaveCache(context.Context, string, interface{}, int) error
+	GetCache(context.Context, string, interface{}) error
+	TakeCache(context.Context, string, interface{}) error
+	RemoveCache(context.Context, string) error
+}
+
+type IConsentRepository interface {
+	FindByUserIDAndClientID(context.Context, string, string, *consentModel.Consent) error
+	Create(context.Context, *consentModel.Consent) error
+	Update(context.Context, string, *consentModel.Consent) error
+}
+
+type ITokenService interface {
+	CreateOidcAccessToken(context.Context, *oidcDto.CacheAccessToken) (string, error)
+	ValidateOidcAccessToken(context.Context, string) (*oidcDto.CacheAccessToken, error)
+}
+
+type IClientService interface {
+	FindByClientID(string) (*config.Client, error)
+	Authenticate(string, string) (*config.Client, error)
+}
+
+type IAccountService interface {
+	FindOrCreateGoogleUser(context.Context, *client.GoogleUserEmailResponse) (*model.Auth, error)
+	FindActiveAccount(context.Context, string) (*model.Auth, error)
+}
+
+type IGoogleOauthClient interface {
+	GetUserEmail(context.Context, string) (*client.GoogleUserEmailResponse, error)
+}
+
+func NewService(
+	cacheRepository ICacheRepository,
+	consentRepository IConsentRepository,
+	tokenService ITokenService,
+	clientService IClientService,
+	accountService IAccountService,
+	googleOauthClient IGoogleOauthClient,
+	oauthConfig *oauth2.Config,
+	key *rsa.PrivateKey,
+	conf config.Oidc,
+) *Service {
+	return &Service{
+		cacheRepository:   cacheRepository,
+		consentRepository: consentRepository,
+		tokenService:      tokenService,
+		clientService:     clientService,
+		accountService:    accountService,
+		googleOauthClient: googleOauthClient,
+		oauthConfig:       oauthConfig,
+		key:               key,
+		keyID:             utils.KeyThumbprint(&key.PublicKey),
+		conf:              conf,
+	}
+}
+
+func (s *Service) Discovery() *oidcDto.DiscoveryDocument {
+	return &oidcDto.DiscoveryDocument{
+		Issuer:                            s.conf.Issuer,
+		AuthorizationEndpoint:             s.conf.Issuer + "/oidc/authorize",
+		TokenEndpoint:                     s.conf.Issuer + "/oidc/token",
+		UserInfoEndpoint:                  s.conf.Issuer + "/oidc/userinfo",
+		JwksUri:                           s.conf.Issuer + "/.well-known/jwks.json",
+		RevocationEndpoint:                s.conf.Issuer + "/oauth/revoke",
+		IntrospectionEndpoint:             s.conf.Issuer + "/oauth/introspect",
+		ScopesSupported:                   supportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IdTokenSigningAlgValuesSupported:  []string{_jwt.SigningMethodRS256.Alg()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "email", "name"},
+	}
+}
+
+func (s *Service) Jwks() *oidcDto.JwkSet {
+	n, e := utils.EncodeRsaPublicKey(&s.key.PublicKey)
+
+	return &oidcDto.JwkSet{
+		Keys: []oidcDto.Jwk{{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: _jwt.SigningMethodRS256.Alg(),
+			Kid: s.keyID,
+			N:   n,
+			E:   e,
+		}},
+	}
+}
+
+// Authorize validates the authorization request and stores it while the user signs in with google.
+// It returns the id of the stored request, which is also the state sent upstream, and the google login url
+func (s *Service) Authorize(ctx context.Context, req *oidcDto.AuthorizeRequest) (string, string, error) {
+	c, err := s.clientService.FindByClientID(req.ClientID)
+	if err != nil {
+		return "", "", &Error{Code: "invalid_request", Description: "Unknown client"}
+	}
+
+	if req.RedirectUri == "" || !slices.Contains(c.RedirectUris, req.RedirectUri) {
+		return "", "", &Error{Code: "invalid_request", Description: "Redirect uri is not registered for the client"}
+	}
+
+	redirectError := func(code string, description string) error {
+		return &Error{Code: code, Description: description, RedirectUri: req.RedirectUri, State: req.State}
+	}
+
+	if req.ResponseType != "code" {
+		return "", "", redirectError("unsupported_response_type", "Only the code response type is supported")
+	}
+
+	var scopes []string
+	for _, scope := range strings.Fields(req.Scope) {
+		if !slices.Contains(supportedScopes, scope) {
+			return "", "", redirectError("invalid_scope", "Unsupported scope "+scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if !slices.Contains(scopes, ScopeOpenID) {
+		return "", "", redirectError("invalid_scope", "The openid scope is required")
+	}
+
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return "", "", redirectError("invalid_request", "PKCE with the S256 method is required")
+	}
+
+	requestID, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", "", internalError(ctx, err, "Error while generating the request id")
+	}
+
+	authorization := &oidcDto.CacheAuthorizationRequest{
+		ClientID:      c.ClientID,
+		RedirectUri:   req.RedirectUri,
+		Scopes:        scopes,
+		State:         req.State,
+		Nonce:         req.Nonce,
+		Prompt:        req.Prompt,
+		CodeChallenge: req.CodeChallenge,
+	}
+
+	err = s.cacheRepository.SaveCache(ctx, requestCacheKey(requestID), authorization, int(s.conf.RequestExpiresIn))
+	if err != nil {
+		return "", "", internalError(ctx, err, "Cannot connect to cache server")
+	}
+
+	return requestID, s.oauthConfig.AuthCodeURL(requestID), nil
+}
+
+// Callback completes the upstream google login. When the user has already consented to the requested
+// scopes it returns the url redirecting back to the client, otherwise the consent to ask for
+func (s *Service) Callback(ctx context.Context, requestID string, code string) (*oidcDto.ConsentPrompt, string, error) {
+	authorization, err := s.findRequest(ctx, requestID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	redirectError := func(code string, description string) error {
+		return &Error{Code: code, Description: description, RedirectUri: authorization.RedirectUri, State: authorization.State}
+	}
+
+	if code == "" {
+		return nil, "", redirectError("access_denied", "Google login was cancelled")
+	}
+
+	profile, err := s.googleOauthClient.GetUserEmail(ctx, code)
+	if err != nil {
+		metrics.Logins.WithLabelValues("oidc", "invalid").Inc()
+		return nil, "", redirectError("access_denied", "Google login failed")
+	}
+
+	auth, err := s.accountService.FindOrCreateGoogleUser(ctx, profile)
+	metrics.Logins.WithLabelValues("oidc", metrics.Outcome(err)).Inc()
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).
+			Str("service", "oidc").
+			Str("module", "callback").
+			Msg("Cannot sign in the user")
+		return nil, "", redirectError("access_denied", "Cannot sign in the user")
+	}
+
+	authorization.UserID = auth.UserID
+	authorization.Email = profile.Email
+	authorization.Name = strings.TrimSpace(profile.Firstname + " " + profile.Lastname)
+	authorization.AuthTime = time.Now().Unix()
+
+	if authorization.Prompt != "consent" {
+		consent := consentModel.Consent{}
+		err := s.consentRepository.FindByUserIDAndClientID(ctx, auth.UserID, authorization.ClientID, &consent)
+		if err == nil && containsAll(strings.Fields(consent.Scopes), authorization.Scopes) {
+			if err := s.cacheRepository.RemoveCache(ctx, requestCacheKey(requestID)); err != nil {
+				return nil, "", internalError(ctx, err, "Cannot connect to cache server")
+			}
+
+			redirect, err := s.issueCode(ctx, authorization)
+			return nil, redirect, err
+		}
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return nil, "", internalError(ctx, err, "Cannot find the consent")
+		}
+	}
+
+	err = s.cacheRepository.SaveCache(ctx, requestCacheKey(requestID), authorization, int(s.conf.RequestExpiresIn))
+	if err != nil {
+		return nil, "", internalError(ctx, err, "Cannot connect to cache server")
+	}
+
+	c, err := s.clientService.FindByClientID(authorization.ClientID)
+	if err != nil {
+		return nil, "", redirectError("unauthorized_client", "Client is no longer registered")
+	}
+
+	name := c.Name
+	if name == "" {
+		name = c.ClientID
+	}
+
+	return &oidcDto.ConsentPrompt{
+		RequestID:  requestID,
+		ClientName: name,
+		Scopes:     authorization.Scopes,
+	}, "", nil
+}
+
+// Consent records the user's decision on a pending request and returns the url redirecting back to the client
+func (s *Service) Consent(ctx context.Context, requestID string, approved bool) (string, error) {
+	authorization, err := s.findRequest(ctx, requestID)
+	if err != nil {
+		return "", err
+	}
+
+	if authorization.UserID == "" {
+		return "", &Error{Code: "invalid_request", Description: "User is not signed in"}
+	}
+
+	if err := s.cacheRepository.RemoveCache(ctx, requestCacheKey(requestID)); err != nil {
+		return "", internalError(ctx, err, "Cannot connect to cache server")
+	}
+
+	if !approved {
+		return "", &Error{Code: "access_denied", Description: "User denied the request", RedirectUri: authorization.RedirectUri, State: authorization.State}
+	}
+
+	if err := s.saveConsent(ctx, authorization.UserID, authorization.ClientID, authorization.Scopes); err != nil {
+		return "", err
+	}
+
+	return s.issueCode(ctx, authorization)
+}
+
+// Token exchanges an authorization code for an access token and an id token
+func (s *Service) Token(ctx context.Context, req *oidcDto.TokenRequest) (*oidcDto.TokenResponse, error) {
+	c, err := s.clientService.FindByClientID(req.ClientID)
+	if err != nil {
+		return nil, &Error{Code: "invalid_client", Description: "Unknown client"}
+	}
+
+	// confidential clients must authenticate, public clients rely on PKCE alone
+	if c.ClientSecret != "" {
+		if _, err := s.clientService.Authenticate(req.ClientID, req.ClientSecret); err != nil {
+			return nil, &Error{Code: "invalid_client", Description: err.Error()}
+		}
+	}
+
+	if req.GrantType != "authorization_code" {
+		return nil, &Error{Code: "unsupported_grant_type", Description: "Only the authorization_code grant is supported"}
+	}
+
+	if req.Code == "" {
+		return nil, &Error{Code: "invalid_request", Description: "No code is provided"}
+	}
+
+	// codes are single use, even when the exchange below fails, and taking the code at once makes
+	// sure only one of the concurrent requests gets it
+	grant := oidcDto.CacheAuthorizationCode{}
+	err = s.cacheRepository.TakeCache(ctx, codeCacheKey(req.Code), &grant)
+	if err != nil {
+		if err != redis.Nil {
+			return nil, internalError(ctx, err, "Cannot connect to cache server")
+		}
+		return nil, &Error{Code: "invalid_grant", Description: "Code is invalid or expired"}
+	}
+
+	if grant.ClientID != c.ClientID || grant.RedirectUri != req.RedirectUri {
+		return nil, &Error{Code: "invalid_grant", Description: "Code was issued to another client or redirect uri"}
+	}
+
+	if !verifyCodeChallenge(req.CodeVerifier, grant.CodeChallenge) {
+		return nil, &Error{Code: "invalid_grant", Description: "Invalid code verifier"}
+	}
+
+	accessToken, err := s.tokenService.CreateOidcAccessToken(ctx, &oidcDto.CacheAccessToken{
+		ClientID: grant.ClientID,
+		Scopes:   grant.Scopes,
+		UserID:   grant.UserID,
+		Email:    grant.Email,
+		Name:     grant.Name,
+	})
+	if err != nil {
+		return nil, internalError(ctx, err, "Error while creating the access token")
+	}
+
+	idToken, err := s.signIdToken(&grant)
+	if err != nil {
+		return nil, internalError(ctx, err, "Error while signing the id token")
+	}
+
+	zerolog.Ctx(ctx).Info().
+		Str("service", "oidc").
+		Str("module", "token").
+		Str("user_id", grant.UserID).
+		Str("client_id", grant.ClientID).
+		Msg("Id token issued")
+
+	return &oidcDto.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   s.conf.TokenExpiresIn,
+		IdToken:     idToken,
+		Scope:       strings.Join(grant.Scopes, " "),
+	}, nil
+}
+
+func (s *Service) UserInfo(ctx context.Context, accessToken string) (*oidcDto.UserInfoResponse, error) {
+	token, err := s.tokenService.ValidateOidcAccessToken(ctx, accessToken)
+	if err != nil {
+		if !errors.Is(err, ts.ErrInvalidAccessToken) {
+			return nil, internalError(ctx, err, "Cannot validate the access token")
+		}
+		return nil, &Error{Code: "invalid_token", Description: err.Error()}
+	}
+
+	if _, err := s.accountService.FindActiveAccount(ctx, token.UserID); err != nil {
+		switch status.Code(err) {
+		case codes.NotFound, codes.PermissionDenied:
+			return nil, &Error{Code: "invalid_token", Description: status.Convert(err).Message()}
+		}
+		return nil, internalError(ctx, err, "Cannot find the account")
+	}
+
+	res := &oidcDto.UserInfoResponse{Sub: token.UserID}
+	if slices.Contains(token.Scopes, ScopeEmail) {
+		res.Email = token.Email
+	}
+	if slices.Contains(token.Scopes, ScopeProfile) {
+		res.Name = token.Name
+	}
+
+	return res, nil
+}
+
+func (s *Service) findRequest(ctx context.Context, requestID string) (*oidcDto.CacheAuthorizationRequest, error) {
+	authorization := oidcDto.CacheAuthorizationRequest{}
+
+	err := s.cacheRepository.GetCache(ctx, requestCacheKey(requestID), &authorization)
+	if err != nil {
+		if err != redis.Nil {
+			return nil, internalError(ctx, err, "Cannot connect to cache server")
+		}
+		return nil, &Error{Code: "invalid_request", Description: "Authorization request is invalid or expired"}
+	}
+
+	return &authorization, nil
+}
+
+func (s *Service) issueCode(ctx context.Context, authorization *oidcDto.CacheAuthorizationRequest) (string, error) {
+	code, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", internalError(ctx, err, "Error while generating the code")
+	}
+
+	err = s.cacheRepository.SaveCache(ctx, codeCacheKey(code), &oidcDto.CacheAuthorizationCode{
+		ClientID:      authorization.ClientID,
+		RedirectUri:   authorization.RedirectUri,
+		Scopes:        authorization.Scopes,
+		Nonce:         authorization.Nonce,
+		CodeChallenge: authorization.CodeChallenge,
+		UserID:        authorization.UserID,
+		Email:         authorization.Email,
+		Name:          authorization.Name,
+		AuthTime:      authorization.AuthTime,
+	}, int(s.conf.CodeExpiresIn))
+	if err != nil {
+		return "", internalError(ctx, err, "Cannot connect to cache server")
+	}
+
+	params := url.Values{"code": {code}}
+	if authorization.State != "" {
+		params.Set("state", authorization.State)
+	}
+
+	return AppendQuery(authorization.RedirectUri, params), nil
+}
+
+// saveConsent adds the scopes to what the user has already allowed the client to access
+func (s *Service) saveConsent(ctx context.Context, userID string, clientID string, scopes []string) error {
+	consent := consentModel.Consent{}
+
+	err := s.consentRepository.FindByUserIDAndClientID(ctx, userID, clientID, &consent)
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return internalError(ctx, err, "Cannot find the consent")
+		}
+
+		err = s.consentRepository.Create(ctx, &consentModel.Consent{
+			UserID:   userID,
+			ClientID: clientID,
+			Scopes:   strings.Join(scopes, " "),
+		})
+		if err != nil {
+			return internalError(ctx, err, "Cannot save the consent")
+		}
+
+		return nil
+	}
+
+	granted := strings.Fields(consent.Scopes)
+	if containsAll(granted, scopes) {
+		return nil
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	consent.Scopes = strings.Join(granted, " ")
+
+	if err := s.consentRepository.Update(ctx, consent.ID.String(), &consent); err != nil {
+		return internalError(ctx, err, "Cannot save the consent")
+	}
+
+	return nil
+}
+
+func (s *Service) signIdToken(grant *oidcDto.CacheAuthorizationCode) (string, error) {
+	now := time.Now()
+
+	payloads := &oidcDto.TokenPayloadId{
+		RegisteredClaims: _jwt.RegisteredClaims{
+			Issuer:    s.conf.Issuer,
+			Subject:   grant.UserID,
+			Audience:  _jwt.ClaimStrings{grant.ClientID},
+			ExpiresAt: _jwt.NewNumericDate(now.Add(time.Second * time.Duration(s.conf.TokenExpiresIn))),
+			IssuedAt:  _jwt.NewNumericDate(now),
+		},
+		AuthTime: grant.AuthTime,
+		Nonce:    grant.Nonce,
+	}
+	if slices.Contains(grant.Scopes, ScopeEmail) {
+		payloads.Email = grant.Email
+	}
+	if slices.Contains(grant.Scopes, ScopeProfile) {
+		payloads.Name = grant.Name
+	}
+
+	token := _jwt.NewWithClaims(_jwt.SigningMethodRS256, payloads)
+	token.Header["kid"] = s.keyID
+
+	return token.SignedString(s.key)
+}
+
+// AppendQuery adds the parameters to the url while keeping the query it already has
+func AppendQuery(rawUrl string, params url.Values) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		for _, v := range values {
+			query.Add(key, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+func verifyCodeChallenge(verifier string, challenge string) bool {
+	if len(verifier) < minCodeVerifierLength || len(verifier) > maxCodeVerifierLength {
+		return false
+	}
+
+	h := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(h[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func containsAll(granted []string, requested []string) bool {
+	for _, scope := range requested {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func requestCacheKey(requestID string) string {
+	return "oidc_request:" + utils.Hash([]byte(requestID))
+}
+
+func codeCacheKey(code string) string {
+	return "oidc_code:" + utils.Hash([]byte(code))
+}
+
+func internalError(ctx context.Context, err error, msg string) error {
+	zerolog.Ctx(ctx).Error().Err(err).
+		Str("service", "oidc").
+		Msg(msg)
+	return &Error{Code: "server_error", Description: "Internal server error"}
+}
@@ -0,0 +1,480 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	oidcDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oidc"
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
+	consentModel "github.com/bookpanda/mygraderlist-auth/src/app/model/consent"
+	ts "github.com/bookpanda/mygraderlist-auth/src/app/service/token"
+	"github.com/bookpanda/mygraderlist-auth/src/app/utils"
+	"github.com/bookpanda/mygraderlist-auth/src/client"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	mock "github.com/bookpanda/mygraderlist-auth/src/mocks/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/mocks/cache"
+	"github.com/bookpanda/mygraderlist-auth/src/mocks/oidc"
+	"github.com/bxcodec/faker/v3"
+	"github.com/go-redis/redis/v8"
+	_jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	testifyMock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type OidcServiceTest struct {
+	suite.Suite
+	Key          *rsa.PrivateKey
+	Conf         config.Oidc
+	OauthConfig  *oauth2.Config
+	Client       *config.Client
+	RequestID    string
+	CodeVerifier string
+	Request      *oidcDto.CacheAuthorizationRequest
+	Profile      *client.GoogleUserEmailResponse
+	Auth         *model.Auth
+	TokenService *mock.TokenServiceMock
+}
+
+func TestOidcService(t *testing.T) {
+	suite.Run(t, new(OidcServiceTest))
+}
+
+func (t *OidcServiceTest) SetupSuite() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.T().Fatal(err)
+	}
+
+	t.Key = key
+}
+
+func (t *OidcServiceTest) SetupTest() {
+	t.Conf = config.Oidc{
+		Issuer:            "https://auth.mygraderlist.bookpanda.dev",
+		GoogleRedirectUri: "https://auth.mygraderlist.bookpanda.dev/oidc/callback",
+		RequestExpiresIn:  600,
+		CodeExpiresIn:     60,
+		TokenExpiresIn:    3600,
+	}
+
+	t.OauthConfig = &oauth2.Config{
+		ClientID:    faker.Word(),
+		RedirectURL: t.Conf.GoogleRedirectUri,
+		Endpoint:    google.Endpoint,
+	}
+
+	t.Client = &config.Client{
+		ClientID:     "mgl-forum",
+		Name:         "MyGraderList Forum",
+		RedirectUris: []string{"https://forum.mygraderlist.bookpanda.dev/auth/callback"},
+	}
+
+	t.RequestID = faker.Password()
+	t.CodeVerifier, _ = utils.GenerateRandomString(32)
+
+	challenge := sha256.Sum256([]byte(t.CodeVerifier))
+
+	t.Request = &oidcDto.CacheAuthorizationRequest{
+		ClientID:      t.Client.ClientID,
+		RedirectUri:   t.Client.RedirectUris[0],
+		Scopes:        []string{ScopeOpenID, ScopeEmail},
+		State:         faker.Word(),
+		Nonce:         faker.Word(),
+		CodeChallenge: base64.RawURLEncoding.EncodeToString(challenge[:]),
+	}
+
+	t.Profile = &client.GoogleUserEmailResponse{
+		Email:     faker.Email(),
+		Firstname: faker.FirstName(),
+		Lastname:  faker.LastName(),
+	}
+
+	t.Auth = &model.Auth{
+		UserID: faker.UUIDDigit(),
+		Role:   "user",
+	}
+
+	t.TokenService = &mock.TokenServiceMock{}
+}
+
+func (t *OidcServiceTest) newService(cacheRepo *cache.RepositoryMock, consentRepo *oidc.ConsentRepositoryMock, clientService *mock.ClientServiceMock, accountService *oidc.AccountServiceMock, googleClient *oidc.GoogleOauthClientMock) *Service {
+	return NewService(cacheRepo, consentRepo, t.TokenService, clientService, accountService, googleClient, t.OauthConfig, t.Key, t.Conf)
+}
+
+func (t *OidcServiceTest) TestAuthorizeSuccess() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("SaveCache", testifyMock.Anything, testifyMock.Anything, int(t.Conf.RequestExpiresIn)).Return(nil)
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(t.Client, nil)
+
+	srv := t.newService(cacheRepo, &oidc.ConsentRepositoryMock{}, clientService, &oidc.AccountServiceMock{}, &oidc.GoogleOauthClientMock{})
+
+	requestID, loginUrl, err := srv.Authorize(context.Background(), &oidcDto.AuthorizeRequest{
+		ClientID:            t.Client.ClientID,
+		RedirectUri:         t.Request.RedirectUri,
+		ResponseType:        "code",
+		Scope:               "openid email openid",
+		State:               t.Request.State,
+		Nonce:               t.Request.Nonce,
+		CodeChallenge:       t.Request.CodeChallenge,
+		CodeChallengeMethod: "S256",
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.NotEmpty(t.T(), requestID)
+
+	u, _ := url.Parse(loginUrl)
+	assert.Equal(t.T(), requestID, u.Query().Get("state"))
+	assert.Equal(t.T(), t.Conf.GoogleRedirectUri, u.Query().Get("redirect_uri"))
+	assert.Equal(t.T(), t.Request, cacheRepo.V[requestCacheKey(requestID)])
+}
+
+func (t *OidcServiceTest) TestAuthorizeUnregisteredRedirectUri() {
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(t.Client, nil)
+
+	srv := t.newService(&cache.RepositoryMock{}, &oidc.ConsentRepositoryMock{}, clientService, &oidc.AccountServiceMock{}, &oidc.GoogleOauthClientMock{})
+
+	_, _, err := srv.Authorize(context.Background(), &oidcDto.AuthorizeRequest{
+		ClientID:     t.Client.ClientID,
+		RedirectUri:  "https://evil.example.com/callback",
+		ResponseType: "code",
+		Scope:        "openid",
+	})
+
+	var oidcErr *Error
+	assert.True(t.T(), errors.As(err, &oidcErr))
+	assert.Equal(t.T(), "invalid_request", oidcErr.Code)
+	assert.Empty(t.T(), oidcErr.RedirectUri)
+}
+
+func (t *OidcServiceTest) TestAuthorizeRequiresPkce() {
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(t.Client, nil)
+
+	srv := t.newService(&cache.RepositoryMock{}, &oidc.ConsentRepositoryMock{}, clientService, &oidc.AccountServiceMock{}, &oidc.GoogleOauthClientMock{})
+
+	_, _, err := srv.Authorize(context.Background(), &oidcDto.AuthorizeRequest{
+		ClientID:            t.Client.ClientID,
+		RedirectUri:         t.Request.RedirectUri,
+		ResponseType:        "code",
+		Scope:               "openid",
+		State:               t.Request.State,
+		CodeChallenge:       t.CodeVerifier,
+		CodeChallengeMethod: "plain",
+	})
+
+	var oidcErr *Error
+	assert.True(t.T(), errors.As(err, &oidcErr))
+	assert.Equal(t.T(), "invalid_request", oidcErr.Code)
+	assert.Equal(t.T(), t.Request.RedirectUri, oidcErr.RedirectUri)
+	assert.Equal(t.T(), t.Request.State, oidcErr.State)
+}
+
+func (t *OidcServiceTest) TestCallbackWithExistingConsent() {
+	code := faker.Word()
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("GetCache", requestCacheKey(t.RequestID), &oidcDto.CacheAuthorizationRequest{}).Return(t.Request, nil)
+	cacheRepo.On("RemoveCache", requestCacheKey(t.RequestID)).Return(nil)
+	cacheRepo.On("SaveCache", testifyMock.Anything, testifyMock.Anything, int(t.Conf.CodeExpiresIn)).Return(nil)
+
+	consentRepo := &oidc.ConsentRepositoryMock{}
+	consentRepo.On("FindByUserIDAndClientID", t.Auth.UserID, t.Client.ClientID, &consentModel.Consent{}).Return(&consentModel.Consent{
+		UserID:   t.Auth.UserID,
+		ClientID: t.Client.ClientID,
+		Scopes:   "openid profile email",
+	}, nil)
+
+	accountService := &oidc.AccountServiceMock{}
+	accountService.On("FindOrCreateGoogleUser", t.Profile).Return(t.Auth, nil)
+
+	googleClient := &oidc.GoogleOauthClientMock{}
+	googleClient.On("GetUserEmail", code).Return(t.Profile, nil)
+
+	srv := t.newService(cacheRepo, consentRepo, &mock.ClientServiceMock{}, accountService, googleClient)
+
+	prompt, redirect, err := srv.Callback(context.Background(), t.RequestID, code)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Nil(t.T(), prompt)
+
+	u, _ := url.Parse(redirect)
+	assert.Equal(t.T(), "forum.mygraderlist.bookpanda.dev", u.Host)
+	assert.Equal(t.T(), t.Request.State, u.Query().Get("state"))
+	assert.NotEmpty(t.T(), u.Query().Get("code"))
+
+	grant := cacheRepo.V[codeCacheKey(u.Query().Get("code"))].(*oidcDto.CacheAuthorizationCode)
+	assert.Equal(t.T(), t.Auth.UserID, grant.UserID)
+	assert.Equal(t.T(), t.Profile.Email, grant.Email)
+}
+
+func (t *OidcServiceTest) TestCallbackAsksForConsent() {
+	code := faker.Word()
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("GetCache", requestCacheKey(t.RequestID), &oidcDto.CacheAuthorizationRequest{}).Return(t.Request, nil)
+	cacheRepo.On("SaveCache", requestCacheKey(t.RequestID), testifyMock.Anything, int(t.Conf.RequestExpiresIn)).Return(nil)
+
+	consentRepo := &oidc.ConsentRepositoryMock{}
+	consentRepo.On("FindByUserIDAndClientID", t.Auth.UserID, t.Client.ClientID, &consentModel.Consent{}).Return(nil, gorm.ErrRecordNotFound)
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(t.Client, nil)
+
+	accountService := &oidc.AccountServiceMock{}
+	accountService.On("FindOrCreateGoogleUser", t.Profile).Return(t.Auth, nil)
+
+	googleClient := &oidc.GoogleOauthClientMock{}
+	googleClient.On("GetUserEmail", code).Return(t.Profile, nil)
+
+	srv := t.newService(cacheRepo, consentRepo, clientService, accountService, googleClient)
+
+	prompt, redirect, err := srv.Callback(context.Background(), t.RequestID, code)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Empty(t.T(), redirect)
+	assert.Equal(t.T(), &oidcDto.ConsentPrompt{
+		RequestID:  t.RequestID,
+		ClientName: t.Client.Name,
+		Scopes:     t.Request.Scopes,
+	}, prompt)
+	assert.Equal(t.T(), t.Auth.UserID, cacheRepo.V[requestCacheKey(t.RequestID)].(*oidcDto.CacheAuthorizationRequest).UserID)
+}
+
+func (t *OidcServiceTest) TestConsentDenied() {
+	t.Request.UserID = t.Auth.UserID
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("GetCache", requestCacheKey(t.RequestID), &oidcDto.CacheAuthorizationRequest{}).Return(t.Request, nil)
+	cacheRepo.On("RemoveCache", requestCacheKey(t.RequestID)).Return(nil)
+
+	consentRepo := &oidc.ConsentRepositoryMock{}
+
+	srv := t.newService(cacheRepo, consentRepo, &mock.ClientServiceMock{}, &oidc.AccountServiceMock{}, &oidc.GoogleOauthClientMock{})
+
+	_, err := srv.Consent(context.Background(), t.RequestID, false)
+
+	var oidcErr *Error
+	assert.True(t.T(), errors.As(err, &oidcErr))
+	assert.Equal(t.T(), "access_denied", oidcErr.Code)
+	assert.Equal(t.T(), t.Request.RedirectUri, oidcErr.RedirectUri)
+	consentRepo.AssertNotCalled(t.T(), "Create", testifyMock.Anything)
+}
+
+func (t *OidcServiceTest) TestTokenSuccess() {
+	code := faker.Word()
+	grant := &oidcDto.CacheAuthorizationCode{
+		ClientID:      t.Client.ClientID,
+		RedirectUri:   t.Request.RedirectUri,
+		Scopes:        t.Request.Scopes,
+		Nonce:         t.Request.Nonce,
+		CodeChallenge: t.Request.CodeChallenge,
+		UserID:        t.Auth.UserID,
+		Email:         t.Profile.Email,
+		Name:          t.Profile.Firstname,
+		AuthTime:      time.Now().Unix(),
+	}
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("TakeCache", codeCacheKey(code), &oidcDto.CacheAuthorizationCode{}).Return(grant, nil)
+	t.TokenService.On("CreateOidcAccessToken", testifyMock.AnythingOfType("*oidc.CacheAccessToken")).Return(faker.Password(), nil)
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(t.Client, nil)
+
+	srv := t.newService(cacheRepo, &oidc.ConsentRepositoryMock{}, clientService, &oidc.AccountServiceMock{}, &oidc.GoogleOauthClientMock{})
+
+	actual, err := srv.Token(context.Background(), &oidcDto.TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectUri:  t.Request.RedirectUri,
+		CodeVerifier: t.CodeVerifier,
+		ClientID:     t.Client.ClientID,
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), "Bearer", actual.TokenType)
+	assert.Equal(t.T(), "openid email", actual.Scope)
+
+	claims := &oidcDto.TokenPayloadId{}
+	token, err := _jwt.ParseWithClaims(actual.IdToken, claims, func(token *_jwt.Token) (interface{}, error) {
+		return &t.Key.PublicKey, nil
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.True(t.T(), token.Valid)
+	assert.Equal(t.T(), srv.Jwks().Keys[0].Kid, token.Header["kid"])
+	assert.Equal(t.T(), t.Conf.Issuer, claims.Issuer)
+	assert.Equal(t.T(), t.Auth.UserID, claims.Subject)
+	assert.True(t.T(), claims.VerifyAudience(t.Client.ClientID, true))
+	assert.Equal(t.T(), t.Request.Nonce, claims.Nonce)
+	assert.Equal(t.T(), t.Profile.Email, claims.Email)
+	assert.Empty(t.T(), claims.Name)
+}
+
+func (t *OidcServiceTest) TestTokenCodeRedeemedOnce() {
+	code := faker.Word()
+	grant := &oidcDto.CacheAuthorizationCode{
+		ClientID:      t.Client.ClientID,
+		RedirectUri:   t.Request.RedirectUri,
+		Scopes:        t.Request.Scopes,
+		CodeChallenge: t.Request.CodeChallenge,
+		UserID:        t.Auth.UserID,
+	}
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("TakeCache", codeCacheKey(code), &oidcDto.CacheAuthorizationCode{}).Return(grant, nil).Once()
+	cacheRepo.On("TakeCache", codeCacheKey(code), &oidcDto.CacheAuthorizationCode{}).Return(nil, redis.Nil)
+	t.TokenService.On("CreateOidcAccessToken", testifyMock.AnythingOfType("*oidc.CacheAccessToken")).Return(faker.Password(), nil)
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(t.Client, nil)
+
+	srv := t.newService(cacheRepo, &oidc.ConsentRepositoryMock{}, clientService, &oidc.AccountServiceMock{}, &oidc.GoogleOauthClientMock{})
+
+	req := &oidcDto.TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectUri:  t.Request.RedirectUri,
+		CodeVerifier: t.CodeVerifier,
+		ClientID:     t.Client.ClientID,
+	}
+
+	_, err := srv.Token(context.Background(), req)
+	assert.Nilf(t.T(), err, "error: %v", err)
+
+	actual, err := srv.Token(context.Background(), req)
+
+	var oidcErr *Error
+	assert.Nil(t.T(), actual)
+	assert.True(t.T(), errors.As(err, &oidcErr))
+	assert.Equal(t.T(), "invalid_grant", oidcErr.Code)
+}
+
+func (t *OidcServiceTest) TestTokenInvalidCodeVerifier() {
+	code := faker.Word()
+	grant := &oidcDto.CacheAuthorizationCode{
+		ClientID:      t.Client.ClientID,
+		RedirectUri:   t.Request.RedirectUri,
+		Scopes:        t.Request.Scopes,
+		CodeChallenge: t.Request.CodeChallenge,
+		UserID:        t.Auth.UserID,
+	}
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("TakeCache", codeCacheKey(code), &oidcDto.CacheAuthorizationCode{}).Return(grant, nil)
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(t.Client, nil)
+
+	srv := t.newService(cacheRepo, &oidc.ConsentRepositoryMock{}, clientService, &oidc.AccountServiceMock{}, &oidc.GoogleOauthClientMock{})
+
+	actual, err := srv.Token(context.Background(), &oidcDto.TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectUri:  t.Request.RedirectUri,
+		CodeVerifier: strings.Repeat("a", 43),
+		ClientID:     t.Client.ClientID,
+	})
+
+	var oidcErr *Error
+	assert.Nil(t.T(), actual)
+	assert.True(t.T(), errors.As(err, &oidcErr))
+	assert.Equal(t.T(), "invalid_grant", oidcErr.Code)
+	cacheRepo.AssertNumberOfCalls(t.T(), "TakeCache", 1)
+}
+
+func (t *OidcServiceTest) TestTokenConfidentialClientRequiresSecret() {
+	t.Client.ClientSecret = faker.Password()
+
+	clientService := &mock.ClientServiceMock{}
+	clientService.On("FindByClientID", t.Client.ClientID).Return(t.Client, nil)
+	clientService.On("Authenticate", t.Client.ClientID, "").Return(nil, errors.New("Invalid client credentials"))
+
+	srv := t.newService(&cache.RepositoryMock{}, &oidc.ConsentRepositoryMock{}, clientService, &oidc.AccountServiceMock{}, &oidc.GoogleOauthClientMock{})
+
+	_, err := srv.Token(context.Background(), &oidcDto.TokenRequest{
+		GrantType: "authorization_code",
+		Code:      faker.Word(),
+		ClientID:  t.Client.ClientID,
+	})
+
+	var oidcErr *Error
+	assert.True(t.T(), errors.As(err, &oidcErr))
+	assert.Equal(t.T(), "invalid_client", oidcErr.Code)
+}
+
+func (t *OidcServiceTest) TestUserInfoFiltersClaimsByScope() {
+	accessToken := faker.Word()
+
+	t.TokenService.On("ValidateOidcAccessToken", accessToken).Return(&oidcDto.CacheAccessToken{
+		ClientID: t.Client.ClientID,
+		Scopes:   []string{ScopeOpenID, ScopeProfile},
+		UserID:   t.Auth.UserID,
+		Email:    t.Profile.Email,
+		Name:     t.Profile.Firstname,
+	}, nil)
+
+	accountService := &oidc.AccountServiceMock{}
+	accountService.On("FindActiveAccount", t.Auth.UserID).Return(t.Auth, nil)
+
+	srv := t.newService(&cache.RepositoryMock{}, &oidc.ConsentRepositoryMock{}, &mock.ClientServiceMock{}, accountService, &oidc.GoogleOauthClientMock{})
+
+	actual, err := srv.UserInfo(context.Background(), accessToken)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &oidcDto.UserInfoResponse{Sub: t.Auth.UserID, Name: t.Profile.Firstname}, actual)
+}
+
+func (t *OidcServiceTest) TestUserInfoInvalidToken() {
+	accessToken := faker.Word()
+
+	t.TokenService.On("ValidateOidcAccessToken", accessToken).Return(nil, ts.ErrInvalidAccessToken)
+
+	srv := t.newService(&cache.RepositoryMock{}, &oidc.ConsentRepositoryMock{}, &mock.ClientServiceMock{}, &oidc.AccountServiceMock{}, &oidc.GoogleOauthClientMock{})
+
+	actual, err := srv.UserInfo(context.Background(), accessToken)
+
+	var oidcErr *Error
+	assert.Nil(t.T(), actual)
+	assert.True(t.T(), errors.As(err, &oidcErr))
+	assert.Equal(t.T(), "invalid_token", oidcErr.Code)
+}
+
+func (t *OidcServiceTest) TestUserInfoSuspendedAccount() {
+	accessToken := faker.Word()
+
+	t.TokenService.On("ValidateOidcAccessToken", accessToken).Return(&oidcDto.CacheAccessToken{
+		ClientID: t.Client.ClientID,
+		Scopes:   []string{ScopeOpenID},
+		UserID:   t.Auth.UserID,
+	}, nil)
+
+	accountService := &oidc.AccountServiceMock{}
+	accountService.On("FindActiveAccount", t.Auth.UserID).Return(nil, status.Error(codes.PermissionDenied, ts.ErrAccountSuspended.Error()))
+
+	srv := t.newService(&cache.RepositoryMock{}, &oidc.ConsentRepositoryMock{}, &mock.ClientServiceMock{}, accountService, &oidc.GoogleOauthClientMock{})
+
+	actual, err := srv.UserInfo(context.Background(), accessToken)
+
+	var oidcErr *Error
+	assert.Nil(t.T(), actual)
+	assert.True(t.T(), errors.As(err, &oidcErr))
+	assert.Equal(t.T(), "invalid_token", oidcErr.Code)
+}
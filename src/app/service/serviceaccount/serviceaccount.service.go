@@ -0,0 +1,408 @@
+package serviceaccount
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/serviceaccount"
+	"github.com/bookpanda/mygraderlist-auth/src/app/utils"
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const (
+	ApiKeyPrefix = "mgl_"
+
+	// lastUsedInterval limits how often the last used time of a key is written back to the database
+	lastUsedInterval = time.Minute
+)
+
+var (
+	ErrInvalidApiKey = errors.New("Invalid api key")
+	ErrApiKeyExpired = errors.New("Api key is expired")
+)
+
+type Service struct {
+	repo           IRepository
+	revocationRepo IRevocationRepository
+	tokenService   ITokenService
+}
+
+type IRepository interface {
+	FindAll(context.Context, *[]*model.ServiceAccount) error
+	FindOne(context.Context, string, *model.ServiceAccount) error
+	Create(context.Context, *model.ServiceAccount) error
+	Update(context.Context, string, *model.ServiceAccount) error
+	Delete(context.Context, string) error
+	FindAllApiKey(context.Context, string, *[]*model.ApiKey) error
+	FindApiKeyByPrefix(context.Context, string, *model.ApiKey) error
+	CreateApiKey(context.Context, *model.ApiKey) error
+	DeleteApiKey(context.Context, string, *model.ApiKey) error
+	UpdateApiKeyLastUsed(context.Context, string, time.Time) error
+}
+
+type IRevocationRepository interface {
+	Append(context.Context, *dto.RevocationEvent) (string, error)
+}
+
+type ITokenService interface {
+	Validate(context.Context, string) (*dto.UserCredential, error)
+}
+
+func NewService(repo IRepository, revocationRepo IRevocationRepository, tokenService ITokenService) *Service {
+	return &Service{
+		repo:           repo,
+		revocationRepo: revocationRepo,
+		tokenService:   tokenService,
+	}
+}
+
+func (s *Service) FindAll(ctx context.Context, _ *auth_proto.FindAllServiceAccountRequest) (*auth_proto.FindAllServiceAccountResponse, error) {
+	if _, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN); err != nil {
+		return nil, err
+	}
+
+	var accounts []*model.ServiceAccount
+
+	err := s.repo.FindAll(ctx, &accounts)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "service account").
+			Str("module", "find all").
+			Msg("Error while querying service accounts")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	return &auth_proto.FindAllServiceAccountResponse{ServiceAccounts: rawToServiceAccountDtoList(accounts)}, nil
+}
+
+func (s *Service) Create(ctx context.Context, req *auth_proto.CreateServiceAccountRequest) (*auth_proto.CreateServiceAccountResponse, error) {
+	if _, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN); err != nil {
+		return nil, err
+	}
+
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "No name is provided")
+	}
+
+	account := &model.ServiceAccount{
+		Name:        req.Name,
+		Description: req.Description,
+		Role:        role.SERVICE,
+	}
+
+	err := s.repo.Create(ctx, account)
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, status.Error(codes.AlreadyExists, "Duplicated service account name")
+		}
+
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "service account").
+			Str("module", "create").
+			Msg("Error while creating the service account")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	return &auth_proto.CreateServiceAccountResponse{ServiceAccount: rawToServiceAccountDto(account)}, nil
+}
+
+func (s *Service) Update(ctx context.Context, req *auth_proto.UpdateServiceAccountRequest) (*auth_proto.UpdateServiceAccountResponse, error) {
+	if _, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN); err != nil {
+		return nil, err
+	}
+
+	account := &model.ServiceAccount{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	err := s.repo.Update(ctx, req.Id, account)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, status.Error(codes.NotFound, "Not found service account")
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, status.Error(codes.AlreadyExists, "Duplicated service account name")
+		}
+
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "service account").
+			Str("module", "update").
+			Msg("Error while updating the service account")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	return &auth_proto.UpdateServiceAccountResponse{ServiceAccount: rawToServiceAccountDto(account)}, nil
+}
+
+func (s *Service) Delete(ctx context.Context, req *auth_proto.DeleteServiceAccountRequest) (*auth_proto.DeleteServiceAccountResponse, error) {
+	if _, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN); err != nil {
+		return nil, err
+	}
+
+	err := s.repo.Delete(ctx, req.Id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "Not found service account")
+		}
+
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "service account").
+			Str("module", "delete").
+			Msg("Error while deleting the service account")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	s.publishRevocation(ctx, &dto.RevocationEvent{
+		Type:   role.SESSION_REVOKED,
+		UserID: req.Id,
+		Role:   role.SERVICE,
+	})
+
+	return &auth_proto.DeleteServiceAccountResponse{Success: true}, nil
+}
+
+func (s *Service) CreateApiKey(ctx context.Context, req *auth_proto.CreateApiKeyRequest) (*auth_proto.CreateApiKeyResponse, error) {
+	if _, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN); err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != 0 {
+		t := time.Unix(req.ExpiresAt, 0)
+		if !t.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "Expiry must be in the future")
+		}
+		expiresAt = &t
+	}
+
+	account := model.ServiceAccount{}
+
+	err := s.repo.FindOne(ctx, req.ServiceAccountId, &account)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "Not found service account")
+	}
+
+	prefix, secret, err := generateApiKey()
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "service account").
+			Str("module", "create api key").
+			Msg("Error while generating the api key")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	key := &model.ApiKey{
+		ServiceAccountID: account.ID.String(),
+		Prefix:           prefix,
+		Secret:           utils.Hash([]byte(secret)),
+		Scopes:           strings.Join(req.Scopes, " "),
+		ExpiresAt:        expiresAt,
+	}
+
+	err = s.repo.CreateApiKey(ctx, key)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "service account").
+			Str("module", "create api key").
+			Msg("Error while creating the api key")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	return &auth_proto.CreateApiKeyResponse{
+		ApiKey: rawToApiKeyDto(key),
+		Key:    ApiKeyPrefix + prefix + "_" + secret,
+	}, nil
+}
+
+func (s *Service) FindAllApiKey(ctx context.Context, req *auth_proto.FindAllApiKeyRequest) (*auth_proto.FindAllApiKeyResponse, error) {
+	if _, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN); err != nil {
+		return nil, err
+	}
+
+	var keys []*model.ApiKey
+
+	err := s.repo.FindAllApiKey(ctx, req.ServiceAccountId, &keys)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "service account").
+			Str("module", "find all api key").
+			Msg("Error while querying api keys")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	return &auth_proto.FindAllApiKeyResponse{ApiKeys: rawToApiKeyDtoList(keys)}, nil
+}
+
+func (s *Service) RevokeApiKey(ctx context.Context, req *auth_proto.RevokeApiKeyRequest) (*auth_proto.RevokeApiKeyResponse, error) {
+	if _, err := utils.Authorize(ctx, s.tokenService.Validate, role.ADMIN); err != nil {
+		return nil, err
+	}
+
+	key := model.ApiKey{}
+
+	err := s.repo.DeleteApiKey(ctx, req.Id, &key)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "Not found api key")
+		}
+
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "service account").
+			Str("module", "revoke api key").
+			Msg("Error while deleting the api key")
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+
+	// the services drop what they cached for the account, the keys left are validated again
+	s.publishRevocation(ctx, &dto.RevocationEvent{
+		Type:   role.SESSION_REVOKED,
+		UserID: key.ServiceAccountID,
+		Role:   role.SERVICE,
+	})
+
+	return &auth_proto.RevokeApiKeyResponse{Success: true}, nil
+}
+
+// ValidateApiKey checks a plain api key against its stored hash and returns the credential of its service account
+func (s *Service) ValidateApiKey(ctx context.Context, apiKey string) (*dto.UserCredential, error) {
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(apiKey, ApiKeyPrefix), "_")
+	if !ok || prefix == "" || secret == "" {
+		return nil, ErrInvalidApiKey
+	}
+
+	key := model.ApiKey{}
+
+	err := s.repo.FindApiKeyByPrefix(ctx, prefix, &key)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			zerolog.Ctx(ctx).Error().Err(err).
+				Str("service", "service account").
+				Str("module", "validate").
+				Msg("Error while querying the api key")
+		}
+		return nil, ErrInvalidApiKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Secret), []byte(utils.Hash([]byte(secret)))) != 1 {
+		return nil, ErrInvalidApiKey
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return nil, ErrApiKeyExpired
+	}
+
+	account := model.ServiceAccount{}
+
+	err = s.repo.FindOne(ctx, key.ServiceAccountID, &account)
+	if err != nil {
+		return nil, ErrInvalidApiKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedInterval {
+		if err := s.repo.UpdateApiKeyLastUsed(ctx, key.ID.String(), now); err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).
+				Str("service", "service account").
+				Str("module", "validate").
+				Msg("Cannot update api key last used time")
+		}
+	}
+
+	credential := &dto.UserCredential{
+		UserId:   account.ID.String(),
+		Role:     role.Role(account.Role),
+		Scopes:   strings.Fields(key.Scopes),
+		IssuedAt: key.CreatedAt.Unix(),
+	}
+	if key.ExpiresAt != nil {
+		credential.ExpiresAt = key.ExpiresAt.Unix()
+	}
+
+	return credential, nil
+}
+
+// publishRevocation announces a removed account or key to the services caching validations, a failure
+// is only logged since they keep the validations for a short time anyway
+func (s *Service) publishRevocation(ctx context.Context, event *dto.RevocationEvent) {
+	event.OccurredAt = time.Now().Unix()
+
+	if _, err := s.revocationRepo.Append(context.WithoutCancel(ctx), event); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).
+			Str("service", "service account").
+			Str("module", "revocation").
+			Str("user_id", event.UserID).
+			Msg("Error while publishing the revocation event")
+	}
+}
+
+func IsApiKey(token string) bool {
+	return strings.HasPrefix(token, ApiKeyPrefix)
+}
+
+func generateApiKey() (prefix string, secret string, err error) {
+	b := make([]byte, 4)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+	prefix = hex.EncodeToString(b)
+
+	secret, err = utils.GenerateRandomString(32)
+	return
+}
+
+func rawToServiceAccountDto(in *model.ServiceAccount) *auth_proto.ServiceAccount {
+	return &auth_proto.ServiceAccount{
+		Id:          in.ID.String(),
+		Name:        in.Name,
+		Description: in.Description,
+		Role:        in.Role,
+	}
+}
+
+func rawToServiceAccountDtoList(in []*model.ServiceAccount) []*auth_proto.ServiceAccount {
+	var result []*auth_proto.ServiceAccount
+	for _, account := range in {
+		result = append(result, rawToServiceAccountDto(account))
+	}
+
+	return result
+}
+
+func rawToApiKeyDto(in *model.ApiKey) *auth_proto.ApiKey {
+	key := &auth_proto.ApiKey{
+		Id:               in.ID.String(),
+		ServiceAccountId: in.ServiceAccountID,
+		Prefix:           in.Prefix,
+		Scopes:           strings.Fields(in.Scopes),
+		CreatedAt:        in.CreatedAt.Unix(),
+	}
+	if in.ExpiresAt != nil {
+		key.ExpiresAt = in.ExpiresAt.Unix()
+	}
+	if in.LastUsedAt != nil {
+		key.LastUsedAt = in.LastUsedAt.Unix()
+	}
+
+	return key
+}
+
+func rawToApiKeyDtoList(in []*model.ApiKey) []*auth_proto.ApiKey {
+	var result []*auth_proto.ApiKey
+	for _, key := range in {
+		result = append(result, rawToApiKeyDto(key))
+	}
+
+	return result
+}
@@ -0,0 +1,301 @@
+package serviceaccount
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/app/model"
+	sa "github.com/bookpanda/mygraderlist-auth/src/app/model/serviceaccount"
+	"github.com/bookpanda/mygraderlist-auth/src/app/utils"
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	mock "github.com/bookpanda/mygraderlist-auth/src/mocks/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/mocks/cache"
+	"github.com/bookpanda/mygraderlist-auth/src/mocks/serviceaccount"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/bxcodec/faker/v3"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	testifyMock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type ServiceAccountServiceTest struct {
+	suite.Suite
+	ServiceAccount *sa.ServiceAccount
+	ApiKey         *sa.ApiKey
+	Secret         string
+	AdminToken     string
+	AdminCtx       context.Context
+}
+
+func TestServiceAccountService(t *testing.T) {
+	suite.Run(t, new(ServiceAccountServiceTest))
+}
+
+func (t *ServiceAccountServiceTest) SetupTest() {
+	t.ServiceAccount = &sa.ServiceAccount{
+		Base: model.Base{
+			ID: uuid.New(),
+		},
+		Name:        faker.Word(),
+		Description: faker.Sentence(),
+		Role:        role.SERVICE,
+	}
+
+	t.Secret = faker.Password()
+
+	t.ApiKey = &sa.ApiKey{
+		Base: model.Base{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+		},
+		ServiceAccountID: t.ServiceAccount.ID.String(),
+		Prefix:           "0a1b2c3d",
+		Secret:           utils.Hash([]byte(t.Secret)),
+		Scopes:           "rating:read rating:write",
+	}
+
+	t.AdminToken = faker.Word()
+	t.AdminCtx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+t.AdminToken))
+}
+
+func (t *ServiceAccountServiceTest) newTokenService() *mock.TokenServiceMock {
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", t.AdminToken).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.ADMIN}, nil)
+
+	return tokenService
+}
+
+func (t *ServiceAccountServiceTest) TestCreateForbidden() {
+	token := faker.Word()
+
+	repo := &serviceaccount.RepositoryMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{UserId: faker.UUIDDigit(), Role: role.USER}, nil)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, tokenService)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	actual, err := srv.Create(ctx, &auth_proto.CreateServiceAccountRequest{Name: t.ServiceAccount.Name})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+}
+
+func (t *ServiceAccountServiceTest) TestCreateDuplicatedName() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("Create", testifyMock.AnythingOfType("*serviceaccount.ServiceAccount")).Return(nil, gorm.ErrDuplicatedKey)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, t.newTokenService())
+
+	actual, err := srv.Create(t.AdminCtx, &auth_proto.CreateServiceAccountRequest{Name: t.ServiceAccount.Name})
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.AlreadyExists, status.Code(err))
+}
+
+func (t *ServiceAccountServiceTest) TestCreateInternalError() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("Create", testifyMock.AnythingOfType("*serviceaccount.ServiceAccount")).Return(nil, errors.New("Connection refused"))
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, t.newTokenService())
+
+	actual, err := srv.Create(t.AdminCtx, &auth_proto.CreateServiceAccountRequest{Name: t.ServiceAccount.Name})
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Internal, status.Code(err))
+}
+
+func (t *ServiceAccountServiceTest) TestUpdateNotFound() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("Update", t.ServiceAccount.ID.String(), testifyMock.AnythingOfType("*serviceaccount.ServiceAccount")).Return(nil, gorm.ErrRecordNotFound)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, t.newTokenService())
+
+	actual, err := srv.Update(t.AdminCtx, &auth_proto.UpdateServiceAccountRequest{Id: t.ServiceAccount.ID.String(), Name: t.ServiceAccount.Name})
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, status.Code(err))
+}
+
+func (t *ServiceAccountServiceTest) TestUpdateDuplicatedName() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("Update", t.ServiceAccount.ID.String(), testifyMock.AnythingOfType("*serviceaccount.ServiceAccount")).Return(nil, gorm.ErrDuplicatedKey)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, t.newTokenService())
+
+	actual, err := srv.Update(t.AdminCtx, &auth_proto.UpdateServiceAccountRequest{Id: t.ServiceAccount.ID.String(), Name: t.ServiceAccount.Name})
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.AlreadyExists, status.Code(err))
+}
+
+func (t *ServiceAccountServiceTest) TestCreateApiKeySuccess() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("FindOne", t.ServiceAccount.ID.String(), &sa.ServiceAccount{}).Return(t.ServiceAccount, nil)
+	repo.On("CreateApiKey", testifyMock.AnythingOfType("*serviceaccount.ApiKey")).Return(nil)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, t.newTokenService())
+
+	actual, err := srv.CreateApiKey(t.AdminCtx, &auth_proto.CreateApiKeyRequest{
+		ServiceAccountId: t.ServiceAccount.ID.String(),
+		Scopes:           []string{"rating:read"},
+	})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.True(t.T(), strings.HasPrefix(actual.Key, ApiKeyPrefix+actual.ApiKey.Prefix+"_"))
+	assert.Equal(t.T(), []string{"rating:read"}, actual.ApiKey.Scopes)
+
+	stored := repo.Calls[1].Arguments.Get(0).(*sa.ApiKey)
+	secret := strings.TrimPrefix(actual.Key, ApiKeyPrefix+actual.ApiKey.Prefix+"_")
+	assert.Equal(t.T(), utils.Hash([]byte(secret)), stored.Secret)
+}
+
+func (t *ServiceAccountServiceTest) TestValidateApiKeySuccess() {
+	want := &dto.UserCredential{
+		UserId:   t.ServiceAccount.ID.String(),
+		Role:     role.SERVICE,
+		Scopes:   []string{"rating:read", "rating:write"},
+		IssuedAt: t.ApiKey.CreatedAt.Unix(),
+	}
+
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("FindApiKeyByPrefix", t.ApiKey.Prefix, &sa.ApiKey{}).Return(t.ApiKey, nil)
+	repo.On("FindOne", t.ServiceAccount.ID.String(), &sa.ServiceAccount{}).Return(t.ServiceAccount, nil)
+	repo.On("UpdateApiKeyLastUsed", t.ApiKey.ID.String(), testifyMock.AnythingOfType("time.Time")).Return(nil)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, &mock.TokenServiceMock{})
+
+	actual, err := srv.ValidateApiKey(context.Background(), ApiKeyPrefix+t.ApiKey.Prefix+"_"+t.Secret)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	repo.AssertCalled(t.T(), "UpdateApiKeyLastUsed", t.ApiKey.ID.String(), testifyMock.AnythingOfType("time.Time"))
+}
+
+func (t *ServiceAccountServiceTest) TestValidateApiKeyRecentlyUsed() {
+	lastUsedAt := time.Now()
+	t.ApiKey.LastUsedAt = &lastUsedAt
+
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("FindApiKeyByPrefix", t.ApiKey.Prefix, &sa.ApiKey{}).Return(t.ApiKey, nil)
+	repo.On("FindOne", t.ServiceAccount.ID.String(), &sa.ServiceAccount{}).Return(t.ServiceAccount, nil)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, &mock.TokenServiceMock{})
+
+	_, err := srv.ValidateApiKey(context.Background(), ApiKeyPrefix+t.ApiKey.Prefix+"_"+t.Secret)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	repo.AssertNotCalled(t.T(), "UpdateApiKeyLastUsed", t.ApiKey.ID.String(), testifyMock.Anything)
+}
+
+func (t *ServiceAccountServiceTest) TestValidateApiKeyInvalidSecret() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("FindApiKeyByPrefix", t.ApiKey.Prefix, &sa.ApiKey{}).Return(t.ApiKey, nil)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, &mock.TokenServiceMock{})
+
+	actual, err := srv.ValidateApiKey(context.Background(), ApiKeyPrefix+t.ApiKey.Prefix+"_"+faker.Password())
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidApiKey, err)
+}
+
+func (t *ServiceAccountServiceTest) TestValidateApiKeyNotFound() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("FindApiKeyByPrefix", t.ApiKey.Prefix, &sa.ApiKey{}).Return(nil, gorm.ErrRecordNotFound)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, &mock.TokenServiceMock{})
+
+	actual, err := srv.ValidateApiKey(context.Background(), ApiKeyPrefix+t.ApiKey.Prefix+"_"+t.Secret)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidApiKey, err)
+}
+
+func (t *ServiceAccountServiceTest) TestValidateApiKeyExpired() {
+	expiresAt := time.Now().Add(-time.Minute)
+	t.ApiKey.ExpiresAt = &expiresAt
+
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("FindApiKeyByPrefix", t.ApiKey.Prefix, &sa.ApiKey{}).Return(t.ApiKey, nil)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, &mock.TokenServiceMock{})
+
+	actual, err := srv.ValidateApiKey(context.Background(), ApiKeyPrefix+t.ApiKey.Prefix+"_"+t.Secret)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrApiKeyExpired, err)
+}
+
+func (t *ServiceAccountServiceTest) TestDeleteSuccess() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("Delete", t.ServiceAccount.ID.String()).Return(nil)
+
+	revocationRepo := &cache.RevocationRepositoryMock{}
+	revocationRepo.On("Append", testifyMock.MatchedBy(func(event *dto.RevocationEvent) bool {
+		return event.Type == role.SESSION_REVOKED && event.UserID == t.ServiceAccount.ID.String()
+	})).Return("1-0", nil)
+
+	srv := NewService(repo, revocationRepo, t.newTokenService())
+
+	actual, err := srv.Delete(t.AdminCtx, &auth_proto.DeleteServiceAccountRequest{Id: t.ServiceAccount.ID.String()})
+
+	assert.Nil(t.T(), err)
+	assert.True(t.T(), actual.Success)
+	revocationRepo.AssertExpectations(t.T())
+}
+
+func (t *ServiceAccountServiceTest) TestDeleteNotFound() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("Delete", t.ServiceAccount.ID.String()).Return(gorm.ErrRecordNotFound)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, t.newTokenService())
+
+	actual, err := srv.Delete(t.AdminCtx, &auth_proto.DeleteServiceAccountRequest{Id: t.ServiceAccount.ID.String()})
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, status.Code(err))
+}
+
+func (t *ServiceAccountServiceTest) TestRevokeApiKeySuccess() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("DeleteApiKey", t.ApiKey.ID.String(), &sa.ApiKey{}).Return(t.ApiKey, nil)
+
+	revocationRepo := &cache.RevocationRepositoryMock{}
+	revocationRepo.On("Append", testifyMock.MatchedBy(func(event *dto.RevocationEvent) bool {
+		return event.Type == role.SESSION_REVOKED && event.UserID == t.ServiceAccount.ID.String()
+	})).Return("1-0", nil)
+
+	srv := NewService(repo, revocationRepo, t.newTokenService())
+
+	actual, err := srv.RevokeApiKey(t.AdminCtx, &auth_proto.RevokeApiKeyRequest{Id: t.ApiKey.ID.String()})
+
+	assert.Nil(t.T(), err)
+	assert.True(t.T(), actual.Success)
+	revocationRepo.AssertExpectations(t.T())
+}
+
+func (t *ServiceAccountServiceTest) TestRevokeApiKeyNotFound() {
+	repo := &serviceaccount.RepositoryMock{}
+	repo.On("DeleteApiKey", t.ApiKey.ID.String(), &sa.ApiKey{}).Return(nil, gorm.ErrRecordNotFound)
+
+	srv := NewService(repo, &cache.RevocationRepositoryMock{}, t.newTokenService())
+
+	actual, err := srv.RevokeApiKey(t.AdminCtx, &auth_proto.RevokeApiKeyRequest{Id: t.ApiKey.ID.String()})
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, status.Code(err))
+}
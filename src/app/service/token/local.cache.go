@@ -0,0 +1,147 @@
+package token
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+)
+
+// localCache keeps the recently validated tokens in process by fingerprint, so a hot token skips
+// both the jwt parsing and the cache server. The entries are indexed by their session key to be
+// dropped when the session changes on any replica.
+type localCache struct {
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	sessions map[string]map[string]struct{}
+	lru      *list.List
+	size     int
+	ttl      time.Duration
+	now      func() time.Time
+
+	// generation is bumped by every invalidation, a result read before it is not stored afterwards
+	generation atomic.Uint64
+	// enabled is off while the invalidations may be missed
+	enabled atomic.Bool
+}
+
+type localEntry struct {
+	fingerprint string
+	session     string
+	credential  *dto.UserCredential
+	expiresAt   time.Time
+}
+
+func newLocalCache(size int, ttl time.Duration) *localCache {
+	return &localCache{
+		entries:  map[string]*list.Element{},
+		sessions: map[string]map[string]struct{}{},
+		lru:      list.New(),
+		size:     size,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+}
+
+func (c *localCache) get(fingerprint string) (*dto.UserCredential, bool) {
+	if !c.enabled.Load() {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[fingerprint]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*localEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+	credential := *entry.credential
+
+	return &credential, true
+}
+
+// set stores the credential unless an invalidation happened since the generation was read
+func (c *localCache) set(fingerprint string, session string, credential *dto.UserCredential, generation uint64) {
+	if !c.enabled.Load() {
+		return
+	}
+
+	expiresAt := c.now().Add(c.ttl)
+	if credential.ExpiresAt > 0 && time.Unix(credential.ExpiresAt, 0).Before(expiresAt) {
+		expiresAt = time.Unix(credential.ExpiresAt, 0)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation.Load() != generation {
+		return
+	}
+
+	if element, ok := c.entries[fingerprint]; ok {
+		c.remove(element)
+	}
+
+	c.entries[fingerprint] = c.lru.PushFront(&localEntry{
+		fingerprint: fingerprint,
+		session:     session,
+		credential:  credential,
+		expiresAt:   expiresAt,
+	})
+	if session != "" {
+		if c.sessions[session] == nil {
+			c.sessions[session] = map[string]struct{}{}
+		}
+		c.sessions[session][fingerprint] = struct{}{}
+	}
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *localCache) invalidate(session string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation.Add(1)
+
+	for fingerprint := range c.sessions[session] {
+		c.remove(c.entries[fingerprint])
+	}
+}
+
+func (c *localCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation.Add(1)
+
+	c.entries = map[string]*list.Element{}
+	c.sessions = map[string]map[string]struct{}{}
+	c.lru.Init()
+}
+
+func (c *localCache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*localEntry)
+	delete(c.entries, entry.fingerprint)
+
+	if entry.session == "" {
+		return
+	}
+
+	delete(c.sessions[entry.session], entry.fingerprint)
+	if len(c.sessions[entry.session]) == 0 {
+		delete(c.sessions, entry.session)
+	}
+}
@@ -1,44 +1,133 @@
 package token
 
 import (
+	"context"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	oidcDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oidc"
+	"github.com/bookpanda/mygraderlist-auth/src/app/metrics"
 	model "github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/app/utils"
 	"github.com/bookpanda/mygraderlist-auth/src/config"
 	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
-	auth_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
 	"github.com/go-redis/redis/v8"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
+const (
+	// ValidationStrict rejects every user token while the cache is unreachable
+	ValidationStrict = "strict"
+	// ValidationDegraded accepts the user tokens from their signature and claims alone for a grace
+	// period of cache outage, a session revoked in the meantime stays valid until then
+	ValidationDegraded = "degraded"
+
+	defaultGracePeriod   = 5 * time.Minute
+	defaultLocalCacheTTL = 5 * time.Second
+)
+
+var (
+	ErrAccountSuspended   = errors.New("Account is suspended")
+	ErrAccountBanned      = errors.New("Account is banned")
+	ErrInvalidAccessToken = errors.New("Access token is invalid or expired")
+)
+
+// AccountStatusError is the error of validating the token of a suspended or banned account, it
+// matches ErrAccountSuspended or ErrAccountBanned and tells until when and why
+type AccountStatusError struct {
+	Status role.Status
+	Until  *time.Time
+	Reason string
+}
+
+func (e *AccountStatusError) Error() string {
+	return e.cause().Error()
+}
+
+func (e *AccountStatusError) Is(target error) bool {
+	return target == e.cause()
+}
+
+func (e *AccountStatusError) cause() error {
+	if e.Status == role.BANNED {
+		return ErrAccountBanned
+	}
+	return ErrAccountSuspended
+}
+
 type Service struct {
 	jwtService      IJwtService
 	cacheRepository ICacheRepository
+	degraded        bool
+	gracePeriod     time.Duration
+	// outageSince is the unix nano time of the first cache error of the current outage, 0 when the cache is up
+	outageSince atomic.Int64
+	invalidator IInvalidator
+	// localCache is nil when it's turned off
+	localCache *localCache
+	// oidcExpiresIn is the lifetime of the access tokens of the OIDC provider, in seconds
+	oidcExpiresIn int32
 }
 
 type IJwtService interface {
 	SignAuth(*model.Auth) (string, error)
+	SignDevice(*model.Auth, string) (string, error)
+	SignImpersonation(*model.Auth, string) (string, error)
+	SignService(string, []string, []string) (string, error)
 	VerifyAuth(string) (*jwt.Token, error)
 	GetConfig() *config.Jwt
 }
 
 type ICacheRepository interface {
-	SaveCache(string, interface{}, int) error
-	GetCache(string, interface{}) error
+	SaveCache(context.Context, string, interface{}, int) error
+	GetCache(context.Context, string, interface{}) error
+	GetCaches(context.Context, []string, []interface{}) ([]error, error)
+	RemoveCache(context.Context, string) error
+}
+
+type IInvalidator interface {
+	Publish(context.Context, string) error
 }
 
-func NewTokenService(jwtService IJwtService, cacheRepository ICacheRepository) *Service {
-	return &Service{
+// NewTokenService creates the token service, the invalidator is nil when there is a single replica.
+// With an invalidator the local cache stays off until Subscribed is called, since the revocations
+// made by the other replicas would be missed.
+func NewTokenService(jwtService IJwtService, cacheRepository ICacheRepository, invalidator IInvalidator, conf config.Validation, oidcConf config.Oidc) *Service {
+	gracePeriod := time.Duration(conf.GracePeriod) * time.Second
+	if gracePeriod <= 0 {
+		gracePeriod = defaultGracePeriod
+	}
+
+	s := &Service{
 		jwtService:      jwtService,
 		cacheRepository: cacheRepository,
+		degraded:        conf.Mode == ValidationDegraded,
+		gracePeriod:     gracePeriod,
+		invalidator:     invalidator,
+		oidcExpiresIn:   oidcConf.TokenExpiresIn,
+	}
+
+	if conf.LocalCacheSize > 0 {
+		ttl := time.Duration(conf.LocalCacheTTL) * time.Second
+		if ttl <= 0 {
+			ttl = defaultLocalCacheTTL
+		}
+
+		s.localCache = newLocalCache(conf.LocalCacheSize, ttl)
+		s.localCache.enabled.Store(invalidator == nil)
 	}
+
+	return s
 }
 
-func (s *Service) CreateCredentials(auth *model.Auth, secret string) (*auth_proto.Credential, error) {
+func (s *Service) CreateCredentials(ctx context.Context, auth *model.Auth, secret string) (*auth_proto.Credential, error) {
 	token, err := s.jwtService.SignAuth(auth)
 	if err != nil {
 		return nil, err
@@ -49,15 +138,16 @@ func (s *Service) CreateCredentials(auth *model.Auth, secret string) (*auth_prot
 		Role:  role.Role(auth.Role),
 	}
 
-	err = s.cacheRepository.SaveCache(auth.UserID, &cache, int(s.jwtService.GetConfig().ExpiresIn))
+	err = s.cacheRepository.SaveCache(ctx, sessionCacheKey(auth.UserID), &cache, int(s.jwtService.GetConfig().ExpiresIn))
 	if err != nil {
-		log.Error().
+		zerolog.Ctx(ctx).Error().
 			Err(err).
 			Str("service", "auth").
 			Str("module", "validate").
 			Msg("Cannot connect to cache server")
 		return nil, errors.New("Internal service error")
 	}
+	s.invalidate(ctx, sessionCacheKey(auth.UserID))
 
 	credential := &auth_proto.Credential{
 		AccessToken:  token,
@@ -68,47 +158,723 @@ func (s *Service) CreateCredentials(auth *model.Auth, secret string) (*auth_prot
 	return credential, nil
 }
 
-func (s *Service) Validate(token string) (*dto.UserCredential, error) {
-	t, err := s.jwtService.VerifyAuth(token)
+// CreateDeviceCredentials issues the credential of a device session, cached under its own key so
+// the session of the account and the other devices are left untouched
+func (s *Service) CreateDeviceCredentials(ctx context.Context, auth *model.Auth, sessionID string) (*auth_proto.Credential, error) {
+	token, err := s.jwtService.SignDevice(auth, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	payload := t.Claims.(jwt.MapClaims)
+	cache := dto.CacheAuth{
+		Token: token,
+		Role:  role.Role(auth.Role),
+	}
 
-	if payload["iss"] != s.jwtService.GetConfig().Issuer {
-		return nil, errors.New("Invalid token")
+	err = s.cacheRepository.SaveCache(ctx, deviceSessionCacheKey(sessionID), &cache, int(s.jwtService.GetConfig().ExpiresIn))
+	if err != nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "device credentials").
+			Msg("Cannot connect to cache server")
+		return nil, errors.New("Internal service error")
 	}
+	s.invalidate(ctx, deviceSessionCacheKey(sessionID))
+
+	return &auth_proto.Credential{
+		AccessToken:  token,
+		RefreshToken: s.CreateRefreshToken(),
+		ExpiresIn:    s.jwtService.GetConfig().ExpiresIn,
+	}, nil
+}
+
+func (s *Service) Validate(ctx context.Context, token string) (*dto.UserCredential, error) {
+	if s.localCache == nil {
+		credential, reason, err := s.validate(ctx, token)
+		metrics.Validations.WithLabelValues(reason).Inc()
 
-	if time.Unix(int64(payload["exp"].(float64)), 0).Before(time.Now()) {
-		return nil, errors.New("Token is expired")
+		return credential, err
+	}
+
+	fingerprint := utils.Hash([]byte(token))
+	if credential, ok := s.localCache.get(fingerprint); ok {
+		metrics.LocalValidations.WithLabelValues("hit").Inc()
+		metrics.Validations.WithLabelValues("ok").Inc()
+
+		return credential, nil
+	}
+	metrics.LocalValidations.WithLabelValues("miss").Inc()
+
+	generation := s.localCache.generation.Load()
+	credential, reason, err := s.validate(ctx, token)
+	metrics.Validations.WithLabelValues(reason).Inc()
+
+	if reason == "ok" {
+		s.localCache.set(fingerprint, credentialCacheKey(credential), credential, generation)
+	}
+
+	return credential, err
+}
+
+// Invalidate drops the locally cached validations of the cache key, when it's changed on another replica
+func (s *Service) Invalidate(key string) {
+	if s.localCache != nil {
+		s.localCache.invalidate(key)
+	}
+}
+
+// Subscribed turns the local cache off while the invalidations of the other replicas may be missed
+func (s *Service) Subscribed(ok bool) {
+	if s.localCache != nil {
+		s.localCache.clear()
+		s.localCache.enabled.Store(ok)
+	}
+}
+
+// invalidate drops the locally cached validations of the key on every replica
+func (s *Service) invalidate(ctx context.Context, key string) {
+	s.Invalidate(key)
+
+	if s.invalidator == nil {
+		return
+	}
+
+	if err := s.invalidator.Publish(context.WithoutCancel(ctx), key); err != nil {
+		zerolog.Ctx(ctx).Warn().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "invalidate").
+			Msg("Cannot publish the invalidation, the other replicas keep their copy until it expires")
+	}
+}
+
+// ValidateBatch validates the tokens with a single cache read, the results are in the order of the tokens
+func (s *Service) ValidateBatch(ctx context.Context, tokens []string) ([]*dto.UserCredential, []error) {
+	credentials := make([]*dto.UserCredential, len(tokens))
+	errs := make([]error, len(tokens))
+	reasons := make([]string, len(tokens))
+
+	var generation uint64
+	if s.localCache != nil {
+		generation = s.localCache.generation.Load()
+	}
+
+	var pending []int
+	var sessions []*session
+	for i, token := range tokens {
+		if s.localCache != nil {
+			if credential, ok := s.localCache.get(utils.Hash([]byte(token))); ok {
+				metrics.LocalValidations.WithLabelValues("hit").Inc()
+				metrics.Validations.WithLabelValues("ok").Inc()
+
+				credentials[i] = credential
+				continue
+			}
+			metrics.LocalValidations.WithLabelValues("miss").Inc()
+		}
+
+		credential, claimed, reason, err := s.parse(token)
+		if claimed != nil {
+			pending = append(pending, i)
+			sessions = append(sessions, claimed)
+			continue
+		}
+
+		credentials[i], reasons[i], errs[i] = credential, reason, err
+	}
+
+	if len(pending) > 0 {
+		keys := make([]string, len(sessions))
+		caches := make([]dto.CacheAuth, len(sessions))
+		values := make([]interface{}, len(sessions))
+		for j, claimed := range sessions {
+			keys[j] = claimed.cacheKey
+			values[j] = &caches[j]
+		}
+
+		cacheErrs, err := s.cacheRepository.GetCaches(ctx, keys, values)
+		s.trackOutage(ctx, err)
+
+		for j, i := range pending {
+			cacheErr := err
+			if err == nil {
+				cacheErr = cacheErrs[j]
+			}
+
+			credentials[i], reasons[i], errs[i] = s.check(ctx, tokens[i], sessions[j], &caches[j], cacheErr)
+		}
+	}
+
+	for i, reason := range reasons {
+		// the local cache hits were already counted
+		if reason == "" {
+			continue
+		}
+		metrics.Validations.WithLabelValues(reason).Inc()
+
+		if s.localCache != nil && reason == "ok" {
+			s.localCache.set(utils.Hash([]byte(tokens[i])), credentialCacheKey(credentials[i]), credentials[i], generation)
+		}
+	}
+
+	return credentials, errs
+}
+
+// session is what a user token claims, it's accepted once it matches the session in the cache
+type session struct {
+	payload   jwt.MapClaims
+	userID    string
+	actorID   string
+	sessionID string
+	cacheKey  string
+}
+
+// validate checks the token and returns the reason it was accepted or rejected for the metrics
+func (s *Service) validate(ctx context.Context, token string) (*dto.UserCredential, string, error) {
+	credential, claimed, reason, err := s.parse(token)
+	if claimed == nil {
+		return credential, reason, err
 	}
 
 	cache := dto.CacheAuth{}
-	err = s.cacheRepository.GetCache(payload["user_id"].(string), &cache)
+	err = s.cacheRepository.GetCache(ctx, claimed.cacheKey, &cache)
+	s.trackOutage(ctx, err)
+
+	return s.check(ctx, token, claimed, &cache, err)
+}
+
+// parse checks the signature and the claims of the token. A service token is done with then, a user
+// token returns the session it has to match in the cache.
+func (s *Service) parse(token string) (*dto.UserCredential, *session, string, error) {
+	t, err := s.jwtService.VerifyAuth(token)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, nil, "expired", err
+		}
+		return nil, nil, "invalid", err
+	}
+
+	payload, ok := t.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, nil, "invalid", errors.New("Invalid token")
+	}
+
+	if payload["iss"] != s.jwtService.GetConfig().Issuer {
+		return nil, nil, "invalid", errors.New("Invalid token")
+	}
+
+	exp, ok := payload["exp"].(float64)
+	if !ok {
+		return nil, nil, "invalid", errors.New("Invalid token")
+	}
+
+	if time.Unix(int64(exp), 0).Before(time.Now()) {
+		return nil, nil, "expired", errors.New("Token is expired")
+	}
+
+	if clientID, ok := payload["client_id"].(string); ok {
+		credential, err := validateServiceClaims(clientID, payload)
+		if err != nil {
+			return nil, nil, "invalid", err
+		}
+		setTimeClaims(credential, payload)
+		return credential, nil, "ok", nil
+	}
+
+	userID, ok := payload["user_id"].(string)
+	if !ok || userID == "" {
+		return nil, nil, "invalid", errors.New("Invalid token")
+	}
+
+	sessionID, _ := payload["sid"].(string)
+
+	claimed := &session{
+		payload:   payload,
+		userID:    userID,
+		actorID:   actorFromClaims(payload),
+		sessionID: sessionID,
+		cacheKey:  sessionCacheKey(userID),
+	}
+	switch {
+	case claimed.actorID != "":
+		claimed.cacheKey = impersonationCacheKey(claimed.actorID)
+	case claimed.sessionID != "":
+		claimed.cacheKey = deviceSessionCacheKey(claimed.sessionID)
+	}
+
+	return nil, claimed, "", nil
+}
+
+// check matches the token with the cached session, err is the error of reading it
+func (s *Service) check(ctx context.Context, token string, claimed *session, cache *dto.CacheAuth, err error) (*dto.UserCredential, string, error) {
 	if err != nil {
 		if err != redis.Nil {
-			log.Error().
+			if credential, ok := s.validateDegraded(ctx, claimed); ok {
+				return credential, "degraded", nil
+			}
+
+			zerolog.Ctx(ctx).Error().
 				Err(err).
 				Str("service", "auth").
 				Str("module", "validate").
 				Msg("Cannot connect to cache server")
-			return nil, errors.New("Internal service error")
+			return nil, "cache_error", errors.New("Internal service error")
 		}
 
-		return nil, errors.New("Invalid token")
+		return nil, "cache_miss", errors.New("Invalid token")
+	}
+
+	switch cache.Status {
+	case role.SUSPENDED:
+		return nil, "suspended", &AccountStatusError{Status: cache.Status, Until: cache.SuspendedUntil, Reason: cache.StatusReason}
+	case role.BANNED:
+		return nil, "banned", &AccountStatusError{Status: cache.Status, Reason: cache.StatusReason}
 	}
 
 	if cache.Token != token {
-		return nil, errors.New("Invalid token")
+		return nil, "mismatch", errors.New("Invalid token")
 	}
 
-	return &dto.UserCredential{
-		UserId: payload["user_id"].(string),
-		Role:   cache.Role,
+	credential := &dto.UserCredential{
+		UserId:    claimed.userID,
+		Role:      cache.Role,
+		ActorId:   claimed.actorID,
+		SessionId: claimed.sessionID,
+	}
+	setTimeClaims(credential, claimed.payload)
+
+	return credential, "ok", nil
+}
+
+// CreateImpersonationCredentials issues a short-lived access token for the target user on
+// behalf of the actor. The session is kept under the actor's key, so the target's own session
+// is left untouched and an actor can only hold a single impersonation at a time.
+func (s *Service) CreateImpersonationCredentials(ctx context.Context, target *model.Auth, actorID string) (*auth_proto.Credential, error) {
+	token, err := s.jwtService.SignImpersonation(target, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := dto.CacheAuth{
+		Token: token,
+		Role:  role.Role(target.Role),
+	}
+
+	expiresIn := s.jwtService.GetConfig().ImpersonationExpiresIn
+
+	err = s.cacheRepository.SaveCache(ctx, impersonationCacheKey(actorID), &cache, int(expiresIn))
+	if err != nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "impersonation").
+			Msg("Cannot connect to cache server")
+		return nil, errors.New("Internal service error")
+	}
+	s.invalidate(ctx, impersonationCacheKey(actorID))
+
+	if err := s.indexImpersonation(ctx, target.UserID, actorID, int(expiresIn)); err != nil {
+		return nil, err
+	}
+
+	return &auth_proto.Credential{
+		AccessToken: token,
+		ExpiresIn:   expiresIn,
 	}, nil
 }
 
+// indexImpersonation adds the actor to the impersonators of the target, kept for as long as the
+// latest impersonation of the target
+func (s *Service) indexImpersonation(ctx context.Context, targetID string, actorID string, ttl int) error {
+	impersonators := dto.CacheImpersonators{}
+
+	err := s.cacheRepository.GetCache(ctx, impersonationTargetCacheKey(targetID), &impersonators)
+	if err != nil && err != redis.Nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "impersonation").
+			Msg("Cannot connect to cache server")
+		return errors.New("Internal service error")
+	}
+
+	if !slices.Contains(impersonators.ActorIDs, actorID) {
+		impersonators.ActorIDs = append(impersonators.ActorIDs, actorID)
+	}
+
+	err = s.cacheRepository.SaveCache(ctx, impersonationTargetCacheKey(targetID), &impersonators, ttl)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "impersonation").
+			Msg("Cannot connect to cache server")
+		return errors.New("Internal service error")
+	}
+
+	return nil
+}
+
+// RevokeImpersonations ends the impersonations of the target and returns their actors. The actors
+// that have moved on to another user since are left alone.
+func (s *Service) RevokeImpersonations(ctx context.Context, targetID string) ([]string, error) {
+	impersonators := dto.CacheImpersonators{}
+
+	err := s.cacheRepository.GetCache(ctx, impersonationTargetCacheKey(targetID), &impersonators)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "impersonation").
+			Msg("Cannot connect to cache server")
+		return nil, errors.New("Internal service error")
+	}
+
+	var revoked []string
+	for _, actorID := range impersonators.ActorIDs {
+		userID, err := s.ImpersonatedUserID(ctx, actorID)
+		if err != nil {
+			zerolog.Ctx(ctx).Error().
+				Err(err).
+				Str("service", "auth").
+				Str("module", "impersonation").
+				Msg("Cannot connect to cache server")
+			return nil, errors.New("Internal service error")
+		}
+		if userID != targetID {
+			continue
+		}
+
+		if err := s.RemoveImpersonationCredentials(ctx, actorID); err != nil {
+			return nil, err
+		}
+		revoked = append(revoked, actorID)
+	}
+
+	if err := s.cacheRepository.RemoveCache(ctx, impersonationTargetCacheKey(targetID)); err != nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "impersonation").
+			Msg("Cannot connect to cache server")
+		return nil, errors.New("Internal service error")
+	}
+
+	return revoked, nil
+}
+
+// ImpersonatedUserID returns the user the actor is impersonating, empty when there is no impersonation
+func (s *Service) ImpersonatedUserID(ctx context.Context, actorID string) (string, error) {
+	cache := dto.CacheAuth{}
+
+	err := s.cacheRepository.GetCache(ctx, impersonationCacheKey(actorID), &cache)
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// the token was signed by this service, it's only read for the target
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(cache.Token, claims); err != nil {
+		return "", err
+	}
+
+	userID, _ := claims["user_id"].(string)
+	return userID, nil
+}
+
+func (s *Service) RemoveImpersonationCredentials(ctx context.Context, actorID string) error {
+	return s.removeCache(ctx, impersonationCacheKey(actorID))
+}
+
+// CreateServiceCredentials issues a short-lived access token for a registered client. Service tokens
+// are not tracked in the cache since they can't be refreshed and expire quickly.
+func (s *Service) CreateServiceCredentials(clientID string, audience []string, scopes []string) (*auth_proto.Credential, error) {
+	token, err := s.jwtService.SignService(clientID, audience, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth_proto.Credential{
+		AccessToken: token,
+		ExpiresIn:   s.jwtService.GetConfig().ServiceExpiresIn,
+	}, nil
+}
+
+// RevokeCredentials replaces the cached session of a non-active account with a
+// status marker, so outstanding access tokens are rejected with the account status
+// instead of a generic invalid token error.
+func (s *Service) RevokeCredentials(ctx context.Context, auth *model.Auth) error {
+	cache := dto.CacheAuth{
+		Role:           role.Role(auth.Role),
+		Status:         role.Status(auth.Status),
+		SuspendedUntil: auth.SuspendedUntil,
+		StatusReason:   auth.StatusReason,
+	}
+
+	err := s.cacheRepository.SaveCache(ctx, sessionCacheKey(auth.UserID), &cache, int(s.jwtService.GetConfig().ExpiresIn))
+	if err != nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "revoke credentials").
+			Msg("Cannot connect to cache server")
+		return errors.New("Internal service error")
+	}
+	s.invalidate(ctx, sessionCacheKey(auth.UserID))
+
+	return nil
+}
+
+func (s *Service) RemoveCredentials(ctx context.Context, userID string) error {
+	return s.removeCache(ctx, sessionCacheKey(userID))
+}
+
+func (s *Service) RemoveDeviceCredentials(ctx context.Context, sessionID string) error {
+	return s.removeCache(ctx, deviceSessionCacheKey(sessionID))
+}
+
+// CreateOidcAccessToken issues the opaque access token of the OIDC provider for the grant, the token
+// is only known to the cache
+func (s *Service) CreateOidcAccessToken(ctx context.Context, grant *oidcDto.CacheAccessToken) (string, error) {
+	accessToken, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	grant.IssuedAt = now.Unix()
+	grant.ExpiresAt = now.Add(time.Duration(s.oidcExpiresIn) * time.Second).Unix()
+
+	err = s.cacheRepository.SaveCache(ctx, oidcAccessTokenCacheKey(accessToken), grant, int(s.oidcExpiresIn))
+	if err != nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "oidc access token").
+			Msg("Cannot connect to cache server")
+		return "", errors.New("Internal service error")
+	}
+
+	return accessToken, nil
+}
+
+// ValidateOidcAccessToken returns the grant behind the access token of the OIDC provider, or
+// ErrInvalidAccessToken when it's unknown, expired or revoked along with the account
+func (s *Service) ValidateOidcAccessToken(ctx context.Context, accessToken string) (*oidcDto.CacheAccessToken, error) {
+	grant := oidcDto.CacheAccessToken{}
+
+	err := s.cacheRepository.GetCache(ctx, oidcAccessTokenCacheKey(accessToken), &grant)
+	if err == redis.Nil {
+		return nil, ErrInvalidAccessToken
+	}
+	if err != nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "oidc access token").
+			Msg("Cannot connect to cache server")
+		return nil, errors.New("Internal service error")
+	}
+
+	revoked := oidcDto.CacheRevokedAccessTokens{}
+
+	err = s.cacheRepository.GetCache(ctx, oidcRevokedCacheKey(grant.UserID), &revoked)
+	if err != nil && err != redis.Nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "oidc access token").
+			Msg("Cannot connect to cache server")
+		return nil, errors.New("Internal service error")
+	}
+	if err == nil && grant.IssuedAt <= revoked.RevokedAt {
+		return nil, ErrInvalidAccessToken
+	}
+
+	return &grant, nil
+}
+
+// RevokeOidcAccessTokens revokes every access token of the OIDC provider issued to the user so far,
+// the mark outlives the tokens it covers
+func (s *Service) RevokeOidcAccessTokens(ctx context.Context, userID string) error {
+	revoked := oidcDto.CacheRevokedAccessTokens{RevokedAt: time.Now().Unix()}
+
+	err := s.cacheRepository.SaveCache(ctx, oidcRevokedCacheKey(userID), &revoked, int(s.oidcExpiresIn))
+	if err != nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "oidc access token").
+			Msg("Cannot connect to cache server")
+		return errors.New("Internal service error")
+	}
+
+	return nil
+}
+
+func (s *Service) RemoveOidcAccessToken(ctx context.Context, accessToken string) error {
+	return s.removeCache(ctx, oidcAccessTokenCacheKey(accessToken))
+}
+
+func (s *Service) removeCache(ctx context.Context, key string) error {
+	err := s.cacheRepository.RemoveCache(ctx, key)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "remove credentials").
+			Msg("Cannot connect to cache server")
+		return errors.New("Internal service error")
+	}
+	s.invalidate(ctx, key)
+
+	return nil
+}
+
 func (s *Service) CreateRefreshToken() string {
 	return uuid.New().String()
 }
+
+func validateServiceClaims(clientID string, payload jwt.MapClaims) (*dto.UserCredential, error) {
+	if clientID == "" || payload["sub"] != clientID {
+		return nil, errors.New("Invalid token")
+	}
+
+	var audience []string
+	switch aud := payload["aud"].(type) {
+	case string:
+		audience = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if v, ok := a.(string); ok {
+				audience = append(audience, v)
+			}
+		}
+	}
+
+	scope, _ := payload["scope"].(string)
+
+	return &dto.UserCredential{
+		UserId:   clientID,
+		Role:     role.SERVICE,
+		Scopes:   strings.Fields(scope),
+		Audience: audience,
+	}, nil
+}
+
+// trackOutage records when the cache became unreachable, a missing key means it answered. A call
+// the caller has given up on says nothing about the cache.
+func (s *Service) trackOutage(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err == nil || err == redis.Nil {
+		if s.outageSince.Load() != 0 {
+			s.outageSince.Store(0)
+		}
+		return
+	}
+
+	s.outageSince.CompareAndSwap(0, time.Now().UnixNano())
+}
+
+// validateDegraded accepts a user token whose signature and claims have already been checked, when
+// the degraded mode is on and the outage is still within the grace period
+func (s *Service) validateDegraded(ctx context.Context, claimed *session) (*dto.UserCredential, bool) {
+	if !s.degraded {
+		return nil, false
+	}
+
+	outage := time.Since(time.Unix(0, s.outageSince.Load()))
+	if outage > s.gracePeriod {
+		return nil, false
+	}
+
+	// tokens signed before the role claim was added are only trusted as a user
+	userRole, _ := claimed.payload["role"].(string)
+	if userRole == "" {
+		userRole = role.USER
+	}
+
+	zerolog.Ctx(ctx).Warn().
+		Str("service", "auth").
+		Str("module", "validate").
+		Str("user_id", claimed.userID).
+		Dur("outage", outage).
+		Msg("Accepted token without the cache server")
+
+	credential := &dto.UserCredential{
+		UserId:    claimed.userID,
+		Role:      role.Role(userRole),
+		ActorId:   claimed.actorID,
+		SessionId: claimed.sessionID,
+	}
+	setTimeClaims(credential, claimed.payload)
+
+	return credential, true
+}
+
+func setTimeClaims(credential *dto.UserCredential, payload jwt.MapClaims) {
+	if exp, ok := payload["exp"].(float64); ok {
+		credential.ExpiresAt = int64(exp)
+	}
+	if iat, ok := payload["iat"].(float64); ok {
+		credential.IssuedAt = int64(iat)
+	}
+}
+
+// credentialCacheKey is the cache key of the session behind the credential, service tokens have none
+func credentialCacheKey(credential *dto.UserCredential) string {
+	switch {
+	case credential.Role == role.SERVICE:
+		return ""
+	case credential.ActorId != "":
+		return impersonationCacheKey(credential.ActorId)
+	case credential.SessionId != "":
+		return deviceSessionCacheKey(credential.SessionId)
+	default:
+		return sessionCacheKey(credential.UserId)
+	}
+}
+
+func sessionCacheKey(userID string) string {
+	return "session:" + userID
+}
+
+func deviceSessionCacheKey(sessionID string) string {
+	return "device_session:" + sessionID
+}
+
+func impersonationCacheKey(actorID string) string {
+	return "impersonation:" + actorID
+}
+
+func impersonationTargetCacheKey(targetID string) string {
+	return "impersonation_target:" + targetID
+}
+
+func oidcAccessTokenCacheKey(accessToken string) string {
+	return "oidc_access:" + utils.Hash([]byte(accessToken))
+}
+
+func oidcRevokedCacheKey(userID string) string {
+	return "oidc_revoked:" + userID
+}
+
+func actorFromClaims(payload jwt.MapClaims) string {
+	act, ok := payload["act"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	sub, _ := act["sub"].(string)
+	return sub
+}
@@ -1,23 +1,28 @@
 package token
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	oidcDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oidc"
+	"github.com/bookpanda/mygraderlist-auth/src/app/metrics"
 	base "github.com/bookpanda/mygraderlist-auth/src/app/model"
 	model "github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
 	"github.com/bookpanda/mygraderlist-auth/src/config"
 	"github.com/bookpanda/mygraderlist-auth/src/constant/auth"
 	mock "github.com/bookpanda/mygraderlist-auth/src/mocks/auth"
 	"github.com/bookpanda/mygraderlist-auth/src/mocks/cache"
-	auth_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
 	"github.com/bxcodec/faker/v3"
 	"github.com/go-redis/redis/v8"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	testifyMock "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
 )
@@ -105,11 +110,11 @@ func (t *TokenServiceTest) TestCreateCredentialsSuccess() {
 	cacheRepo := cache.RepositoryMock{
 		V: map[string]interface{}{},
 	}
-	cacheRepo.On("SaveCache", t.TokenDecoded["user_id"], cacheData, 3600).Return(nil)
+	cacheRepo.On("SaveCache", "session:"+t.TokenDecoded["user_id"].(string), cacheData, 3600).Return(nil)
 
-	srv := NewTokenService(&jwtSrv, &cacheRepo)
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
 
-	actual, err := srv.CreateCredentials(t.Auth, "asuperstrong32bitpasswordgohere!")
+	actual, err := srv.CreateCredentials(context.Background(), t.Auth, "asuperstrong32bitpasswordgohere!")
 
 	assert.Nilf(t.T(), err, "error: %v", err)
 	assert.Equal(t.T(), want.AccessToken, actual.AccessToken)
@@ -124,9 +129,9 @@ func (t *TokenServiceTest) TestCreateCredentialsInternalErr() {
 
 	cacheRepo := cache.RepositoryMock{}
 
-	srv := NewTokenService(&jwtSrv, &cacheRepo)
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
 
-	actual, err := srv.CreateCredentials(t.Auth, "asuperstrong32bitpasswordgohere!")
+	actual, err := srv.CreateCredentials(context.Background(), t.Auth, "asuperstrong32bitpasswordgohere!")
 
 	var credential *auth_proto.Credential
 
@@ -136,8 +141,9 @@ func (t *TokenServiceTest) TestCreateCredentialsInternalErr() {
 
 func (t *TokenServiceTest) TestValidateAccessTokenSuccess() {
 	want := &dto.UserCredential{
-		UserId: t.Token.Claims.(dto.TokenPayloadAuth).UserId,
-		Role:   auth.Role(t.Auth.Role),
+		UserId:    t.Token.Claims.(dto.TokenPayloadAuth).UserId,
+		Role:      auth.Role(t.Auth.Role),
+		ExpiresAt: int64(t.TokenDecoded["exp"].(float64)),
 	}
 	token := faker.Word()
 
@@ -153,16 +159,126 @@ func (t *TokenServiceTest) TestValidateAccessTokenSuccess() {
 		Role:  auth.USER,
 	}
 	cacheRepo := cache.RepositoryMock{}
-	cacheRepo.On("GetCache", t.TokenDecoded["user_id"], &dto.CacheAuth{}).Return(&cacheAuth, nil)
+	cacheRepo.On("GetCache", "session:"+t.TokenDecoded["user_id"].(string), &dto.CacheAuth{}).Return(&cacheAuth, nil)
 
-	srv := NewTokenService(&jwtSrv, &cacheRepo)
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
 
-	actual, err := srv.Validate(token)
+	actual, err := srv.Validate(context.Background(), token)
 
 	assert.Nilf(t.T(), err, "error: %v", err)
 	assert.Equal(t.T(), want, actual)
 }
 
+func (t *TokenServiceTest) TestValidateFromLocalCache() {
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", "session:"+t.TokenDecoded["user_id"].(string), &dto.CacheAuth{}).Return(&dto.CacheAuth{
+		Token: token,
+		Role:  auth.USER,
+	}, nil)
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{LocalCacheSize: 10}, config.Oidc{})
+
+	want, err := srv.Validate(context.Background(), token)
+	assert.Nil(t.T(), err)
+
+	actual, err := srv.Validate(context.Background(), token)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+	jwtSrv.AssertNumberOfCalls(t.T(), "VerifyAuth", 1)
+	cacheRepo.AssertNumberOfCalls(t.T(), "GetCache", 1)
+}
+
+func (t *TokenServiceTest) TestValidateAfterRemoteRevocation() {
+	token := faker.Word()
+	sessionKey := "session:" + t.TokenDecoded["user_id"].(string)
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", sessionKey, &dto.CacheAuth{}).Return(&dto.CacheAuth{
+		Token: token,
+		Role:  auth.USER,
+	}, nil).Once()
+	cacheRepo.On("GetCache", sessionKey, &dto.CacheAuth{}).Return(nil, redis.Nil)
+
+	invalidator := cache.InvalidatorMock{}
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, &invalidator, config.Validation{LocalCacheSize: 10}, config.Oidc{})
+	srv.Subscribed(true)
+
+	_, err := srv.Validate(context.Background(), token)
+	assert.Nil(t.T(), err)
+
+	srv.Invalidate(sessionKey)
+
+	actual, err := srv.Validate(context.Background(), token)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), "Invalid token", err.Error())
+}
+
+func (t *TokenServiceTest) TestValidateSkipsLocalCacheWhenUnsubscribed() {
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", "session:"+t.TokenDecoded["user_id"].(string), &dto.CacheAuth{}).Return(&dto.CacheAuth{
+		Token: token,
+		Role:  auth.USER,
+	}, nil)
+
+	invalidator := cache.InvalidatorMock{}
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, &invalidator, config.Validation{LocalCacheSize: 10}, config.Oidc{})
+
+	_, _ = srv.Validate(context.Background(), token)
+	_, _ = srv.Validate(context.Background(), token)
+
+	cacheRepo.AssertNumberOfCalls(t.T(), "GetCache", 2)
+}
+
+func (t *TokenServiceTest) TestRemoveCredentialsPublishesInvalidation() {
+	sessionKey := "session:" + t.Auth.UserID
+
+	jwtSrv := mock.JwtServiceMock{}
+
+	cacheRepo := cache.RepositoryMock{
+		V: map[string]interface{}{},
+	}
+	cacheRepo.On("RemoveCache", sessionKey).Return(nil)
+
+	invalidator := cache.InvalidatorMock{}
+	invalidator.On("Publish", sessionKey).Return(nil)
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, &invalidator, config.Validation{LocalCacheSize: 10}, config.Oidc{})
+
+	err := srv.RemoveCredentials(context.Background(), t.Auth.UserID)
+
+	assert.Nil(t.T(), err)
+	invalidator.AssertCalled(t.T(), "Publish", sessionKey)
+}
+
 func (t *TokenServiceTest) TestValidateAccessTokenInvalidToken() {
 	testValidateAccessTokenInvalidTokenMalformedToken(t.T(), faker.Word())
 
@@ -180,6 +296,21 @@ func (t *TokenServiceTest) TestValidateAccessTokenInvalidToken() {
 		Claims: t.TokenDecoded,
 		Valid:  true,
 	}, "Token is expired")
+
+	t.TokenDecoded["exp"] = float64(time.Now().Add(time.Hour).Unix())
+	delete(t.TokenDecoded, "user_id")
+
+	testValidateAccessTokenInvalidTokenInvalidCase(t.T(), t.Conf, &jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, "Invalid token")
+
+	delete(t.TokenDecoded, "exp")
+
+	testValidateAccessTokenInvalidTokenInvalidCase(t.T(), t.Conf, &jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, "Invalid token")
 }
 
 func testValidateAccessTokenInvalidTokenMalformedToken(t *testing.T, refreshToken string) {
@@ -190,9 +321,9 @@ func testValidateAccessTokenInvalidTokenMalformedToken(t *testing.T, refreshToke
 
 	cacheRepo := cache.RepositoryMock{}
 
-	srv := NewTokenService(&jwtSrv, &cacheRepo)
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
 
-	actual, err := srv.Validate(refreshToken)
+	actual, err := srv.Validate(context.Background(), refreshToken)
 
 	var payload *dto.UserCredential
 
@@ -211,9 +342,9 @@ func testValidateAccessTokenInvalidTokenInvalidCase(t *testing.T, conf *config.J
 
 	cacheRepo := cache.RepositoryMock{}
 
-	srv := NewTokenService(&jwtSrv, &cacheRepo)
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
 
-	actual, err := srv.Validate(in)
+	actual, err := srv.Validate(context.Background(), in)
 
 	var payload *dto.UserCredential
 
@@ -238,14 +369,17 @@ func (t *TokenServiceTest) TestValidateAccessTokenNotMatchWithCache() {
 	jwtSrv.On("GetConfig").Return(t.Conf, nil)
 
 	cacheRepo := cache.RepositoryMock{}
-	cacheRepo.On("GetCache", t.TokenDecoded["user_id"], &dto.CacheAuth{}).Return(&cacheAuth, nil)
+	cacheRepo.On("GetCache", "session:"+t.TokenDecoded["user_id"].(string), &dto.CacheAuth{}).Return(&cacheAuth, nil)
+
+	before := testutil.ToFloat64(metrics.Validations.WithLabelValues("mismatch"))
 
-	srv := NewTokenService(&jwtSrv, &cacheRepo)
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
 
-	actual, err := srv.Validate(token)
+	actual, err := srv.Validate(context.Background(), token)
 
 	assert.Nil(t.T(), actual)
 	assert.Equal(t.T(), want.Error(), err.Error())
+	assert.Equal(t.T(), before+1, testutil.ToFloat64(metrics.Validations.WithLabelValues("mismatch")))
 }
 
 func (t *TokenServiceTest) TestValidateCacheNotFoundUser() {
@@ -260,12 +394,514 @@ func (t *TokenServiceTest) TestValidateCacheNotFoundUser() {
 	jwtSrv.On("GetConfig").Return(t.Conf, nil)
 
 	cacheRepo := cache.RepositoryMock{}
-	cacheRepo.On("GetCache", t.TokenDecoded["user_id"], &dto.CacheAuth{}).Return(nil, redis.Nil)
+	cacheRepo.On("GetCache", "session:"+t.TokenDecoded["user_id"].(string), &dto.CacheAuth{}).Return(nil, redis.Nil)
+
+	before := testutil.ToFloat64(metrics.Validations.WithLabelValues("cache_miss"))
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.Validate(context.Background(), token)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), want.Error(), err.Error())
+	assert.Equal(t.T(), before+1, testutil.ToFloat64(metrics.Validations.WithLabelValues("cache_miss")))
+}
+
+func (t *TokenServiceTest) TestValidateCacheDownStrict() {
+	want := errors.New("Internal service error")
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", "session:"+t.TokenDecoded["user_id"].(string), &dto.CacheAuth{}).Return(nil, errors.New("Connection refused"))
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{Mode: ValidationStrict}, config.Oidc{})
+
+	actual, err := srv.Validate(context.Background(), token)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), want.Error(), err.Error())
+}
+
+func (t *TokenServiceTest) TestValidateCacheDownDegraded() {
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", "session:"+t.TokenDecoded["user_id"].(string), &dto.CacheAuth{}).Return(nil, errors.New("Connection refused"))
 
-	srv := NewTokenService(&jwtSrv, &cacheRepo)
+	before := testutil.ToFloat64(metrics.Validations.WithLabelValues("degraded"))
 
-	actual, err := srv.Validate(token)
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{Mode: ValidationDegraded, GracePeriod: 60}, config.Oidc{})
+
+	actual, err := srv.Validate(context.Background(), token)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Auth.UserID, actual.UserId)
+	assert.Equal(t.T(), auth.Role(auth.USER), actual.Role)
+	assert.Equal(t.T(), before+1, testutil.ToFloat64(metrics.Validations.WithLabelValues("degraded")))
+}
+
+func (t *TokenServiceTest) TestValidateCacheDownAfterGracePeriod() {
+	want := errors.New("Internal service error")
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", "session:"+t.TokenDecoded["user_id"].(string), &dto.CacheAuth{}).Return(nil, errors.New("Connection refused"))
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{Mode: ValidationDegraded, GracePeriod: 60}, config.Oidc{})
+	srv.outageSince.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+
+	actual, err := srv.Validate(context.Background(), token)
 
 	assert.Nil(t.T(), actual)
 	assert.Equal(t.T(), want.Error(), err.Error())
 }
+
+func (t *TokenServiceTest) TestValidateSuspendedAccount() {
+	token := faker.Word()
+
+	until := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	cacheAuth := dto.CacheAuth{
+		Role:           auth.Role(t.Auth.Role),
+		Status:         auth.SUSPENDED,
+		SuspendedUntil: &until,
+		StatusReason:   faker.Sentence(),
+	}
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", "session:"+t.TokenDecoded["user_id"].(string), &dto.CacheAuth{}).Return(&cacheAuth, nil)
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.Validate(context.Background(), token)
+
+	assert.Nil(t.T(), actual)
+	assert.True(t.T(), errors.Is(err, ErrAccountSuspended))
+	assert.Equal(t.T(), &AccountStatusError{Status: auth.SUSPENDED, Until: &until, Reason: cacheAuth.StatusReason}, err)
+}
+
+func (t *TokenServiceTest) TestRevokeCredentialsSuccess() {
+	t.Auth.Status = string(auth.BANNED)
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheData := &dto.CacheAuth{
+		Role:   auth.USER,
+		Status: auth.BANNED,
+	}
+
+	cacheRepo := cache.RepositoryMock{
+		V: map[string]interface{}{},
+	}
+	cacheRepo.On("SaveCache", "session:"+t.Auth.UserID, cacheData, 3600).Return(nil)
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	err := srv.RevokeCredentials(context.Background(), t.Auth)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), cacheData, cacheRepo.V["session:"+t.Auth.UserID])
+}
+
+func (t *TokenServiceTest) TestValidateImpersonationToken() {
+	token := faker.Word()
+	actorID := faker.UUIDDigit()
+	t.TokenDecoded["act"] = map[string]interface{}{"sub": actorID}
+
+	want := &dto.UserCredential{
+		UserId:    t.Auth.UserID,
+		Role:      auth.USER,
+		ActorId:   actorID,
+		ExpiresAt: int64(t.TokenDecoded["exp"].(float64)),
+	}
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheAuth := dto.CacheAuth{
+		Token: token,
+		Role:  auth.USER,
+	}
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", "impersonation:"+actorID, &dto.CacheAuth{}).Return(&cacheAuth, nil)
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.Validate(context.Background(), token)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *TokenServiceTest) TestCreateDeviceCredentials() {
+	sessionID := faker.UUIDHyphenated()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("SignDevice", t.Auth, sessionID).Return(t.Credential.AccessToken, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{
+		V: map[string]interface{}{},
+	}
+	cacheRepo.On("SaveCache", "device_session:"+sessionID, &dto.CacheAuth{Token: t.Credential.AccessToken, Role: auth.USER}, 3600).Return(nil)
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.CreateDeviceCredentials(context.Background(), t.Auth, sessionID)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), t.Credential.AccessToken, actual.AccessToken)
+	assert.NotEmpty(t.T(), actual.RefreshToken)
+}
+
+func (t *TokenServiceTest) TestValidateDeviceToken() {
+	token := faker.Word()
+	sessionID := faker.UUIDHyphenated()
+	t.TokenDecoded["sid"] = sessionID
+
+	want := &dto.UserCredential{
+		UserId:    t.Auth.UserID,
+		Role:      auth.USER,
+		SessionId: sessionID,
+		ExpiresAt: int64(t.TokenDecoded["exp"].(float64)),
+	}
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheAuth := dto.CacheAuth{
+		Token: token,
+		Role:  auth.USER,
+	}
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", "device_session:"+sessionID, &dto.CacheAuth{}).Return(&cacheAuth, nil)
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.Validate(context.Background(), token)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *TokenServiceTest) TestValidateServiceToken() {
+	token := faker.Word()
+	clientID := faker.Word()
+
+	exp := time.Now().Add(time.Minute).Unix()
+	iat := time.Now().Unix()
+
+	claims := jwt.MapClaims{
+		"iss":       t.Conf.Issuer,
+		"sub":       clientID,
+		"aud":       []interface{}{"mgl-auth"},
+		"exp":       float64(exp),
+		"iat":       float64(iat),
+		"client_id": clientID,
+		"scope":     "auth:validate user:read",
+	}
+
+	want := &dto.UserCredential{
+		UserId:    clientID,
+		Role:      auth.SERVICE,
+		Scopes:    []string{"auth:validate", "user:read"},
+		Audience:  []string{"mgl-auth"},
+		ExpiresAt: exp,
+		IssuedAt:  iat,
+	}
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: claims,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.Validate(context.Background(), token)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	cacheRepo.AssertNotCalled(t.T(), "GetCache")
+}
+
+func (t *TokenServiceTest) TestValidateBatch() {
+	token := faker.Word()
+	impersonationToken := faker.Word()
+	invalidToken := faker.Word()
+	actorID := faker.UUIDDigit()
+
+	impersonationClaims := jwt.MapClaims{}
+	for k, v := range t.TokenDecoded {
+		impersonationClaims[k] = v
+	}
+	impersonationClaims["act"] = map[string]interface{}{"sub": actorID}
+
+	want := &dto.UserCredential{
+		UserId:    t.Auth.UserID,
+		Role:      auth.USER,
+		ExpiresAt: int64(t.TokenDecoded["exp"].(float64)),
+	}
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{Claims: t.TokenDecoded, Valid: true}, nil)
+	jwtSrv.On("VerifyAuth", impersonationToken).Return(&jwt.Token{Claims: impersonationClaims, Valid: true}, nil)
+	jwtSrv.On("VerifyAuth", invalidToken).Return(nil, errors.New("Invalid token"))
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCaches", []string{"session:" + t.Auth.UserID, "impersonation:" + actorID}).Return([]interface{}{
+		&dto.CacheAuth{Token: token, Role: auth.USER},
+		&dto.CacheAuth{Token: faker.Word(), Role: auth.USER},
+	}, []error{nil, nil}, nil)
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, errs := srv.ValidateBatch(context.Background(), []string{token, invalidToken, impersonationToken})
+
+	assert.Equal(t.T(), []*dto.UserCredential{want, nil, nil}, actual)
+	assert.Nil(t.T(), errs[0])
+	assert.Equal(t.T(), "Invalid token", errs[1].Error())
+	assert.Equal(t.T(), "Invalid token", errs[2].Error())
+	cacheRepo.AssertNumberOfCalls(t.T(), "GetCaches", 1)
+	cacheRepo.AssertNotCalled(t.T(), "GetCache")
+}
+
+func (t *TokenServiceTest) TestValidateBatchCacheDown() {
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{Claims: t.TokenDecoded, Valid: true}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCaches", []string{"session:" + t.Auth.UserID, "session:" + t.Auth.UserID}).Return(nil, nil, errors.New("Connection refused"))
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{Mode: ValidationStrict}, config.Oidc{})
+
+	actual, errs := srv.ValidateBatch(context.Background(), []string{token, token})
+
+	assert.Equal(t.T(), []*dto.UserCredential{nil, nil}, actual)
+	assert.Equal(t.T(), "Internal service error", errs[0].Error())
+	assert.Equal(t.T(), "Internal service error", errs[1].Error())
+}
+
+func (t *TokenServiceTest) TestValidateBatchFromLocalCache() {
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{Claims: t.TokenDecoded, Valid: true}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCaches", []string{"session:" + t.Auth.UserID}).Return([]interface{}{
+		&dto.CacheAuth{Token: token, Role: auth.USER},
+	}, []error{nil}, nil)
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{LocalCacheSize: 10}, config.Oidc{})
+
+	want, errs := srv.ValidateBatch(context.Background(), []string{token})
+	assert.Nil(t.T(), errs[0])
+
+	actual, errs := srv.ValidateBatch(context.Background(), []string{token})
+
+	assert.Nil(t.T(), errs[0])
+	assert.Equal(t.T(), want, actual)
+	jwtSrv.AssertNumberOfCalls(t.T(), "VerifyAuth", 1)
+	cacheRepo.AssertNumberOfCalls(t.T(), "GetCaches", 1)
+}
+
+func (t *TokenServiceTest) TestImpersonatedUserID() {
+	actorID := faker.UUIDDigit()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": t.Auth.UserID}).SignedString([]byte(t.Conf.Secret))
+	assert.Nil(t.T(), err)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", "impersonation:"+actorID, &dto.CacheAuth{}).Return(&dto.CacheAuth{Token: token, Role: auth.USER}, nil)
+
+	srv := NewTokenService(&mock.JwtServiceMock{}, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.ImpersonatedUserID(context.Background(), actorID)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Auth.UserID, actual)
+}
+
+func (t *TokenServiceTest) TestCreateImpersonationCredentialsIndexesTarget() {
+	actorID := faker.UUIDDigit()
+	previousID := faker.UUIDDigit()
+	t.Conf.ImpersonationExpiresIn = 900
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("SignImpersonation", t.Auth, actorID).Return(t.Credential.AccessToken, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("SaveCache", "impersonation:"+actorID, &dto.CacheAuth{Token: t.Credential.AccessToken, Role: auth.USER}, 900).Return(nil)
+	cacheRepo.On("GetCache", "impersonation_target:"+t.Auth.UserID, &dto.CacheImpersonators{}).Return(&dto.CacheImpersonators{ActorIDs: []string{previousID}}, nil)
+	cacheRepo.On("SaveCache", "impersonation_target:"+t.Auth.UserID, &dto.CacheImpersonators{ActorIDs: []string{previousID, actorID}}, 900).Return(nil)
+
+	srv := NewTokenService(&jwtSrv, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.CreateImpersonationCredentials(context.Background(), t.Auth, actorID)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), t.Credential.AccessToken, actual.AccessToken)
+	cacheRepo.AssertCalled(t.T(), "SaveCache", "impersonation_target:"+t.Auth.UserID, &dto.CacheImpersonators{ActorIDs: []string{previousID, actorID}}, 900)
+}
+
+func (t *TokenServiceTest) TestRevokeImpersonations() {
+	actorID := faker.UUIDDigit()
+	movedOnID := faker.UUIDDigit()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": t.Auth.UserID}).SignedString([]byte(t.Conf.Secret))
+	assert.Nil(t.T(), err)
+	otherToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": faker.UUIDDigit()}).SignedString([]byte(t.Conf.Secret))
+	assert.Nil(t.T(), err)
+
+	cacheRepo := cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("GetCache", "impersonation_target:"+t.Auth.UserID, &dto.CacheImpersonators{}).Return(&dto.CacheImpersonators{ActorIDs: []string{actorID, movedOnID}}, nil)
+	cacheRepo.On("GetCache", "impersonation:"+actorID, &dto.CacheAuth{}).Return(&dto.CacheAuth{Token: token, Role: auth.USER}, nil)
+	cacheRepo.On("GetCache", "impersonation:"+movedOnID, &dto.CacheAuth{}).Return(&dto.CacheAuth{Token: otherToken, Role: auth.USER}, nil)
+	cacheRepo.On("RemoveCache", "impersonation:"+actorID).Return(nil)
+	cacheRepo.On("RemoveCache", "impersonation_target:"+t.Auth.UserID).Return(nil)
+
+	srv := NewTokenService(&mock.JwtServiceMock{}, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.RevokeImpersonations(context.Background(), t.Auth.UserID)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), []string{actorID}, actual)
+	cacheRepo.AssertNotCalled(t.T(), "RemoveCache", "impersonation:"+movedOnID)
+}
+
+func (t *TokenServiceTest) TestImpersonatedUserIDNone() {
+	actorID := faker.UUIDDigit()
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", "impersonation:"+actorID, &dto.CacheAuth{}).Return(nil, redis.Nil)
+
+	srv := NewTokenService(&mock.JwtServiceMock{}, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.ImpersonatedUserID(context.Background(), actorID)
+
+	assert.Nil(t.T(), err)
+	assert.Empty(t.T(), actual)
+}
+
+func (t *TokenServiceTest) TestCreateOidcAccessToken() {
+	grant := &oidcDto.CacheAccessToken{
+		ClientID: faker.Word(),
+		Scopes:   []string{"openid"},
+		UserID:   t.Auth.UserID,
+	}
+
+	cacheRepo := cache.RepositoryMock{
+		V: map[string]interface{}{},
+	}
+	cacheRepo.On("SaveCache", testifyMock.AnythingOfType("string"), grant, 3600).Return(nil)
+
+	srv := NewTokenService(&mock.JwtServiceMock{}, &cacheRepo, nil, config.Validation{}, config.Oidc{TokenExpiresIn: 3600})
+
+	actual, err := srv.CreateOidcAccessToken(context.Background(), grant)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.NotEmpty(t.T(), actual)
+	assert.Equal(t.T(), grant, cacheRepo.V[oidcAccessTokenCacheKey(actual)])
+	assert.Equal(t.T(), grant.IssuedAt+3600, grant.ExpiresAt)
+}
+
+func (t *TokenServiceTest) TestValidateOidcAccessToken() {
+	token := faker.Word()
+	grant := &oidcDto.CacheAccessToken{
+		ClientID: faker.Word(),
+		Scopes:   []string{"openid"},
+		UserID:   t.Auth.UserID,
+	}
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", oidcAccessTokenCacheKey(token), &oidcDto.CacheAccessToken{}).Return(grant, nil)
+	cacheRepo.On("GetCache", "oidc_revoked:"+t.Auth.UserID, &oidcDto.CacheRevokedAccessTokens{}).Return(nil, redis.Nil)
+
+	srv := NewTokenService(&mock.JwtServiceMock{}, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.ValidateOidcAccessToken(context.Background(), token)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), grant, actual)
+}
+
+func (t *TokenServiceTest) TestValidateOidcAccessTokenRevoked() {
+	token := faker.Word()
+	grant := &oidcDto.CacheAccessToken{
+		ClientID: faker.Word(),
+		Scopes:   []string{"openid"},
+		UserID:   t.Auth.UserID,
+		IssuedAt: time.Now().Add(-time.Minute).Unix(),
+	}
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", oidcAccessTokenCacheKey(token), &oidcDto.CacheAccessToken{}).Return(grant, nil)
+	cacheRepo.On("GetCache", "oidc_revoked:"+t.Auth.UserID, &oidcDto.CacheRevokedAccessTokens{}).Return(&oidcDto.CacheRevokedAccessTokens{RevokedAt: time.Now().Unix()}, nil)
+
+	srv := NewTokenService(&mock.JwtServiceMock{}, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.ValidateOidcAccessToken(context.Background(), token)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidAccessToken, err)
+}
+
+func (t *TokenServiceTest) TestValidateOidcAccessTokenUnknown() {
+	token := faker.Word()
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", oidcAccessTokenCacheKey(token), &oidcDto.CacheAccessToken{}).Return(nil, redis.Nil)
+
+	srv := NewTokenService(&mock.JwtServiceMock{}, &cacheRepo, nil, config.Validation{}, config.Oidc{})
+
+	actual, err := srv.ValidateOidcAccessToken(context.Background(), token)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidAccessToken, err)
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"time"
 
+	"github.com/bookpanda/mygraderlist-auth/src/app/metrics"
 	user_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/backend/user"
+	"google.golang.org/grpc/status"
 )
 
 type Service struct {
@@ -15,11 +17,13 @@ func NewUserService(client user_proto.UserServiceClient) *Service {
 	return &Service{client: client}
 }
 
-func (s *Service) FindByEmail(email string) (*user_proto.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5000)
+func (s *Service) FindByEmail(ctx context.Context, email string) (*user_proto.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5000)
 	defer cancel()
 
+	start := time.Now()
 	res, err := s.client.FindByEmail(ctx, &user_proto.FindByEmailUserRequest{Email: email})
+	observe("FindByEmail", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -27,14 +31,20 @@ func (s *Service) FindByEmail(email string) (*user_proto.User, error) {
 	return res.User, nil
 }
 
-func (s *Service) Create(user *user_proto.User) (*user_proto.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5000)
+func (s *Service) Create(ctx context.Context, user *user_proto.User) (*user_proto.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5000)
 	defer cancel()
 
+	start := time.Now()
 	res, err := s.client.Create(ctx, &user_proto.CreateUserRequest{User: user})
+	observe("Create", start, err)
 	if err != nil {
 		return nil, err
 	}
 
 	return res.User, nil
 }
+
+func observe(method string, start time.Time, err error) {
+	metrics.BackendDuration.WithLabelValues(method, status.Code(err).String()).Observe(time.Since(start).Seconds())
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"testing"
 
 	mock "github.com/bookpanda/mygraderlist-auth/src/mocks/user"
@@ -46,7 +47,7 @@ func (t *UserServiceTest) TestFindByEmailSuccess() {
 
 	srv := NewUserService(c)
 
-	actual, err := srv.FindByEmail(t.UserDto.Email)
+	actual, err := srv.FindByEmail(context.Background(), t.UserDto.Email)
 
 	assert.Nil(t.T(), err)
 	assert.Equal(t.T(), want, actual)
@@ -59,7 +60,7 @@ func (t *UserServiceTest) TestFindByEmailUnauthorized() {
 
 	srv := NewUserService(c)
 
-	actual, err := srv.FindByEmail(t.UserDto.Email)
+	actual, err := srv.FindByEmail(context.Background(), t.UserDto.Email)
 
 	st, ok := status.FromError(err)
 	assert.True(t.T(), ok)
@@ -74,7 +75,7 @@ func (t *UserServiceTest) TestFindByEmailNotFound() {
 
 	srv := NewUserService(c)
 
-	actual, err := srv.FindByEmail(t.UserDto.Email)
+	actual, err := srv.FindByEmail(context.Background(), t.UserDto.Email)
 
 	st, ok := status.FromError(err)
 	assert.True(t.T(), ok)
@@ -89,7 +90,7 @@ func (t *UserServiceTest) TestFindByEmailGrpcError() {
 
 	srv := NewUserService(c)
 
-	actual, err := srv.FindByEmail(t.UserDto.Email)
+	actual, err := srv.FindByEmail(context.Background(), t.UserDto.Email)
 
 	st, ok := status.FromError(err)
 	assert.True(t.T(), ok)
@@ -106,7 +107,7 @@ func (t *UserServiceTest) TestCreateSuccess() {
 
 	srv := NewUserService(c)
 
-	actual, err := srv.Create(&user_proto.User{})
+	actual, err := srv.Create(context.Background(), &user_proto.User{})
 
 	assert.Nil(t.T(), err)
 	assert.Equal(t.T(), want, actual)
@@ -119,7 +120,7 @@ func (t *UserServiceTest) TestCreateGrpcErr() {
 
 	srv := NewUserService(c)
 
-	actual, err := srv.Create(&user_proto.User{})
+	actual, err := srv.Create(context.Background(), &user_proto.User{})
 
 	st, ok := status.FromError(err)
 	assert.True(t.T(), ok)
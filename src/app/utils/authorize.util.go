@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+
+	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Authorize validates the bearer token of the incoming call and checks that the caller has one of the given roles
+func Authorize(ctx context.Context, validate func(context.Context, string) (*dto.UserCredential, error), roles ...role.Role) (*dto.UserCredential, error) {
+	token, err := GetBearerToken(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	credential, err := validate(ctx, token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	for _, r := range roles {
+		if credential.Role == r {
+			return credential, nil
+		}
+	}
+
+	return nil, status.Error(codes.PermissionDenied, "Insufficient permission")
+}
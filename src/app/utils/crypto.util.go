@@ -63,3 +63,12 @@ func Hash(bv []byte) string {
 	h.Write(bv)
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
+
+func GenerateRandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"os"
+
+	_jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+)
+
+// LoadRsaPrivateKey reads a PEM encoded RSA private key, generating a throwaway key when no path is configured
+func LoadRsaPrivateKey(path string) (*rsa.PrivateKey, error) {
+	if path == "" {
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error occurs while reading the key file")
+	}
+
+	key, err := _jwt.ParseRSAPrivateKeyFromPEM(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "error occurs while parsing the key file")
+	}
+
+	return key, nil
+}
+
+// KeyThumbprint computes the RFC 7638 thumbprint of the public key, used as its key id
+func KeyThumbprint(key *rsa.PublicKey) string {
+	n, e := EncodeRsaPublicKey(key)
+
+	h := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+// EncodeRsaPublicKey returns the modulus and exponent of the key in the base64url form used by JWK
+func EncodeRsaPublicKey(key *rsa.PublicKey) (string, string) {
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+
+	return n, e
+}
+
+// DecodeRsaPublicKey reads the modulus and exponent of a JWK back into a public key
+func DecodeRsaPublicKey(n string, e string) (*rsa.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid modulus")
+	}
+
+	exponent, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bookpanda/mygraderlist-auth/src/app/repository/cache"
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/bookpanda/mygraderlist-auth/src/database"
+	"github.com/rs/zerolog/log"
+)
+
+var cacheUsage = "usage: server cache list [type] | server cache purge <type>\ntypes: " + strings.Join(cache.KeyTypes, ", ")
+
+// cacheKeys runs the `cache` command against the configured redis and returns the exit code
+func cacheKeys(args []string) int {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, cacheUsage)
+		return 2
+	}
+
+	var keyType string
+	if len(args) == 2 {
+		keyType = args[1]
+	}
+
+	// purging every type at once would sign out everyone, so it has to be asked for type by type
+	if keyType != "" && !cache.IsKeyType(keyType) || args[0] == "purge" && keyType == "" {
+		fmt.Fprintln(os.Stderr, cacheUsage)
+		return 2
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		log.Error().Err(err).Str("service", "cache").Msg("Failed to load config")
+		return 1
+	}
+
+	client, err := database.InitRedisConnect(&conf.Redis)
+	if err != nil {
+		log.Error().Err(err).Str("service", "cache").Msg("Failed to connect to redis")
+		return 1
+	}
+	defer client.Close()
+
+	repo := cache.NewRepository(client, conf.Cache)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "list":
+		keys, err := repo.Keys(ctx, keyType)
+		if err != nil {
+			log.Error().Err(err).Str("service", "cache").Msg("Failed to list the keys")
+			return 1
+		}
+
+		for _, key := range keys {
+			fmt.Println(key)
+		}
+	case "purge":
+		purged, err := repo.Purge(ctx, keyType)
+		if err != nil {
+			log.Error().Err(err).Str("service", "cache").Msg("Failed to purge the keys")
+			return 1
+		}
+		fmt.Printf("purged %v %v key(s)\n", purged, keyType)
+	default:
+		fmt.Fprintln(os.Stderr, cacheUsage)
+		return 2
+	}
+
+	return 0
+}
@@ -9,16 +9,19 @@ import (
 	"net/url"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/oauth2"
 )
 
 type GoogleOauthClient struct {
 	oauthConfig *oauth2.Config
+	httpClient  *http.Client
 }
 
 func NewGoogleOauthClient(oauthConfig *oauth2.Config) *GoogleOauthClient {
 	return &GoogleOauthClient{
-		oauthConfig,
+		oauthConfig: oauthConfig,
+		httpClient:  &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
 	}
 }
 
@@ -35,14 +38,23 @@ var (
 	InvalidFormat = errors.New("Google sent unexpected format")
 )
 
-func (c *GoogleOauthClient) GetUserEmail(code string) (*GoogleUserEmailResponse, error) {
-	token, err := c.oauthConfig.Exchange(context.TODO(), code)
+func (c *GoogleOauthClient) GetUserEmail(ctx context.Context, code string) (*GoogleUserEmailResponse, error) {
+	// the oauth2 package takes its http client from the context
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, c.httpClient)
+
+	token, err := c.oauthConfig.Exchange(ctx, code)
 	if err != nil {
 		log.Error().Err(err).Msg("Unable to exchange oauth token")
 		return nil, InvalidCode
 	}
 
-	resp, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + url.QueryEscape(token.AccessToken))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.googleapis.com/oauth2/v2/userinfo?access_token="+url.QueryEscape(token.AccessToken), nil)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to get user info")
+		return nil, HttpError
+	}
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		log.Error().Err(err).Msg("Unable to get user info")
 		return nil, HttpError
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+)
+
+// refreshMargin is how long before expiry a cached service token is replaced
+const refreshMargin = 30 * time.Second
+
+type ServiceTokenIssuer interface {
+	CreateServiceCredentials(string, []string, []string) (*auth_proto.Credential, error)
+}
+
+// ServiceTokenCredentials attaches a service token of the auth service to every outgoing call
+type ServiceTokenCredentials struct {
+	issuer    ServiceTokenIssuer
+	clientID  string
+	audience  string
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewServiceTokenCredentials(issuer ServiceTokenIssuer, clientID string, audience string) *ServiceTokenCredentials {
+	return &ServiceTokenCredentials{
+		issuer:   issuer,
+		clientID: clientID,
+		audience: audience,
+	}
+}
+
+func (c *ServiceTokenCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == "" || time.Now().Add(refreshMargin).After(c.expiresAt) {
+		credential, err := c.issuer.CreateServiceCredentials(c.clientID, []string{c.audience}, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		c.token = credential.AccessToken
+		c.expiresAt = time.Now().Add(time.Duration(credential.ExpiresIn) * time.Second)
+	}
+
+	return map[string]string{"authorization": "Bearer " + c.token}, nil
+}
+
+// RequireTransportSecurity is false since the services talk to each other over the internal network
+func (c *ServiceTokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
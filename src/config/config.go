@@ -8,34 +8,87 @@ import (
 )
 
 type Redis struct {
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	Password string `mapstructure:"password"`
+	Mode             string   `mapstructure:"mode"`
+	Host             string   `mapstructure:"host"`
+	Port             int      `mapstructure:"port"`
+	Addrs            []string `mapstructure:"addrs"`
+	MasterName       string   `mapstructure:"master_name"`
+	SentinelUsername string   `mapstructure:"sentinel_username"`
+	SentinelPassword string   `mapstructure:"sentinel_password"`
+	DB               int      `mapstructure:"db"`
+	Username         string   `mapstructure:"username"`
+	Password         string   `mapstructure:"password"`
+	TLS              RedisTLS `mapstructure:"tls"`
+	PoolSize         int      `mapstructure:"pool_size"`
+	MinIdleConns     int      `mapstructure:"min_idle_conns"`
+	DialTimeout      int32    `mapstructure:"dial_timeout"`
+	ReadTimeout      int32    `mapstructure:"read_timeout"`
+	WriteTimeout     int32    `mapstructure:"write_timeout"`
+	PoolTimeout      int32    `mapstructure:"pool_timeout"`
+	IdleTimeout      int32    `mapstructure:"idle_timeout"`
+	ConnectRetries   int      `mapstructure:"connect_retries"`
+	ConnectBackoff   int32    `mapstructure:"connect_backoff"`
+}
+
+type RedisTLS struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	CaFile             string `mapstructure:"ca_file"`
+	CertFile           string `mapstructure:"cert_file"`
+	KeyFile            string `mapstructure:"key_file"`
+	ServerName         string `mapstructure:"server_name"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+}
+
+type Cache struct {
+	Driver                 string `mapstructure:"driver"`
+	MaxEntries             int    `mapstructure:"max_entries"`
+	MaxBytes               int    `mapstructure:"max_bytes"`
+	KeyPrefix              string `mapstructure:"key_prefix"`
+	KeyVersion             int    `mapstructure:"key_version"`
+	SkipLegacyKeys         bool   `mapstructure:"skip_legacy_keys"`
+	BreakerThreshold       int    `mapstructure:"breaker_threshold"`
+	BreakerCooldown        int32  `mapstructure:"breaker_cooldown"`
+	RevocationStreamLength int64  `mapstructure:"revocation_stream_length"`
+}
+
+type Validation struct {
+	Mode           string `mapstructure:"mode"`
+	GracePeriod    int32  `mapstructure:"grace_period"`
+	LocalCacheSize int    `mapstructure:"local_cache_size"`
+	LocalCacheTTL  int32  `mapstructure:"local_cache_ttl"`
 }
 
 type Database struct {
+	Driver   string `mapstructure:"driver"`
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
 	User     string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	Name     string `mapstructure:"name"`
 	SSL      string `mapstructure:"ssl"`
+	Migrate  string `mapstructure:"migrate"`
 }
 
 type Service struct {
-	Backend string `mapstructure:"backend"`
+	Backend         string `mapstructure:"backend"`
+	BackendAudience string `mapstructure:"backend_audience"`
+	ClientID        string `mapstructure:"client_id"`
 }
 
 type App struct {
-	Port   int    `mapstructure:"port"`
-	Debug  bool   `mapstructure:"debug"`
-	Secret string `mapstructure:"secret"`
+	Port      int    `mapstructure:"port"`
+	HttpPort  int    `mapstructure:"http_port"`
+	AdminPort int    `mapstructure:"admin_port"`
+	Debug     bool   `mapstructure:"debug"`
+	Secret    string `mapstructure:"secret"`
 }
 
 type Jwt struct {
-	Secret    string `mapstructure:"secret"`
-	ExpiresIn int32  `mapstructure:"expires_in"`
-	Issuer    string `mapstructure:"issuer"`
+	Secret                 string `mapstructure:"secret"`
+	ExpiresIn              int32  `mapstructure:"expires_in"`
+	ImpersonationExpiresIn int32  `mapstructure:"impersonation_expires_in"`
+	ServiceExpiresIn       int32  `mapstructure:"service_expires_in"`
+	Issuer                 string `mapstructure:"issuer"`
 }
 
 type Oauth struct {
@@ -44,13 +97,70 @@ type Oauth struct {
 	RedirectUri  string `mapstructure:"redirect_uri"`
 }
 
+type Device struct {
+	VerificationUri string `mapstructure:"verification_uri"`
+	ExpiresIn       int32  `mapstructure:"expires_in"`
+	Interval        int32  `mapstructure:"interval"`
+}
+
+type Client struct {
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	Name         string   `mapstructure:"name"`
+	Audiences    []string `mapstructure:"audiences"`
+	Scopes       []string `mapstructure:"scopes"`
+	RedirectUris []string `mapstructure:"redirect_uris"`
+}
+
+type Oidc struct {
+	Issuer            string `mapstructure:"issuer"`
+	GoogleRedirectUri string `mapstructure:"google_redirect_uri"`
+	KeyFile           string `mapstructure:"key_file"`
+	RequestExpiresIn  int32  `mapstructure:"request_expires_in"`
+	CodeExpiresIn     int32  `mapstructure:"code_expires_in"`
+	TokenExpiresIn    int32  `mapstructure:"token_expires_in"`
+}
+
+type Session struct {
+	Domain           string `mapstructure:"domain"`
+	SameSite         string `mapstructure:"same_site"`
+	Insecure         bool   `mapstructure:"insecure"`
+	RefreshExpiresIn int32  `mapstructure:"refresh_expires_in"`
+}
+
+type GrpcWeb struct {
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+}
+
+type Tracing struct {
+	Exporter    string  `mapstructure:"exporter"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+type Health struct {
+	Interval int32 `mapstructure:"interval"`
+	Timeout  int32 `mapstructure:"timeout"`
+}
+
 type Config struct {
-	Redis    Redis    `mapstructure:"redis"`
-	Database Database `mapstructure:"database"`
-	App      App      `mapstructure:"app"`
-	Jwt      Jwt      `mapstructure:"jwt"`
-	Oauth    Oauth    `mapstructure:"google-oauth"`
-	Service  Service  `mapstructure:"service"`
+	Redis      Redis      `mapstructure:"redis"`
+	Cache      Cache      `mapstructure:"cache"`
+	Database   Database   `mapstructure:"database"`
+	App        App        `mapstructure:"app"`
+	Jwt        Jwt        `mapstructure:"jwt"`
+	Validation Validation `mapstructure:"validation"`
+	Oauth      Oauth      `mapstructure:"google-oauth"`
+	Service    Service    `mapstructure:"service"`
+	Clients    []Client   `mapstructure:"clients"`
+	Device     Device     `mapstructure:"device"`
+	Oidc       Oidc       `mapstructure:"oidc"`
+	Session    Session    `mapstructure:"session"`
+	GrpcWeb    GrpcWeb    `mapstructure:"grpc_web"`
+	Tracing    Tracing    `mapstructure:"tracing"`
+	Health     Health     `mapstructure:"health"`
 }
 
 func LoadConfig() (config *Config, err error) {
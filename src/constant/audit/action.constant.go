@@ -0,0 +1,8 @@
+package audit
+
+type Action string
+
+const (
+	IMPERSONATION_START Action = "impersonation_start"
+	IMPERSONATION_END          = "impersonation_end"
+)
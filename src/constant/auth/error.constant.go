@@ -0,0 +1,4 @@
+package auth
+
+// ERROR_DOMAIN is the domain of the error details attached to the returned status
+const ERROR_DOMAIN = "auth.mygraderlist"
@@ -0,0 +1,12 @@
+package auth
+
+// Revocation is the kind of change announced to the services that cache validations
+type Revocation string
+
+const (
+	SESSION_REVOKED    Revocation = "session_revoked"
+	ROLE_CHANGED       Revocation = "role_changed"
+	ACCOUNT_SUSPENDED  Revocation = "account_suspended"
+	ACCOUNT_BANNED     Revocation = "account_banned"
+	ACCOUNT_REINSTATED Revocation = "account_reinstated"
+)
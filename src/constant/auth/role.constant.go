@@ -3,6 +3,7 @@ package auth
 type Role string
 
 const (
-	ADMIN Role = "admin"
-	USER       = "user"
+	ADMIN   Role = "admin"
+	USER         = "user"
+	SERVICE      = "service"
 )
@@ -0,0 +1,9 @@
+package auth
+
+type Status string
+
+const (
+	ACTIVE    Status = "active"
+	SUSPENDED        = "suspended"
+	BANNED           = "banned"
+)
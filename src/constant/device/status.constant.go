@@ -0,0 +1,9 @@
+package device
+
+type Status string
+
+const (
+	PENDING  Status = "pending"
+	APPROVED        = "approved"
+	DENIED          = "denied"
+)
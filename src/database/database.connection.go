@@ -0,0 +1,79 @@
+package database
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/glebarez/sqlite"
+	"github.com/pkg/errors"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/opentelemetry/tracing"
+)
+
+const (
+	MySQL    = "mysql"
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+func InitDatabase(conf *config.Database) (db *gorm.DB, err error) {
+	dialector, err := openDialector(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	// TranslateError maps the unique violations of every driver to gorm.ErrDuplicatedKey
+	db, err = gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Driver == SQLite {
+		sqlDb, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+
+		// sqlite takes a single writer, and every connection to :memory: would open a database of its own
+		sqlDb.SetMaxOpenConns(1)
+	}
+
+	err = db.Use(tracing.NewPlugin(tracing.WithoutMetrics()))
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func openDialector(conf *config.Database) (gorm.Dialector, error) {
+	switch conf.Driver {
+	case "", MySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True", conf.User, conf.Password, conf.Host, strconv.Itoa(conf.Port), conf.Name)
+		return mysql.Open(dsn), nil
+	case Postgres:
+		sslMode := conf.SSL
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(conf.User, conf.Password),
+			Host:     net.JoinHostPort(conf.Host, strconv.Itoa(conf.Port)),
+			Path:     conf.Name,
+			RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+		}
+		return postgres.Open(dsn.String()), nil
+	case SQLite:
+		// the name is the path of the database file, or :memory:
+		return sqlite.Open(conf.Name), nil
+	default:
+		return nil, errors.Errorf("Unknown database driver %q", conf.Driver)
+	}
+}
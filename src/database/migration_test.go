@@ -0,0 +1,117 @@
+package database
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationTest struct {
+	suite.Suite
+}
+
+func TestMigration(t *testing.T) {
+	suite.Run(t, new(MigrationTest))
+}
+
+func (t *MigrationTest) TestLoadMigrationsInOrder() {
+	dir := fstest.MapFS{
+		"0002_add_index.up.sql":        {Data: []byte("CREATE INDEX idx ON auths (role);")},
+		"0002_add_index.down.sql":      {Data: []byte("DROP INDEX idx ON auths;")},
+		"0001_initial_schema.up.sql":   {Data: []byte("CREATE TABLE auths (id varchar(191));")},
+		"0001_initial_schema.down.sql": {Data: []byte("DROP TABLE auths;")},
+	}
+
+	actual, err := loadMigrations(dir)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), []Migration{
+		{Version: 1, Name: "initial_schema", Up: "CREATE TABLE auths (id varchar(191));", Down: "DROP TABLE auths;"},
+		{Version: 2, Name: "add_index", Up: "CREATE INDEX idx ON auths (role);", Down: "DROP INDEX idx ON auths;"},
+	}, actual)
+}
+
+func (t *MigrationTest) TestLoadMigrationsMissingDown() {
+	dir := fstest.MapFS{
+		"0001_initial_schema.up.sql": {Data: []byte("CREATE TABLE auths (id varchar(191));")},
+	}
+
+	actual, err := loadMigrations(dir)
+
+	assert.Nil(t.T(), actual)
+	assert.NotNil(t.T(), err)
+}
+
+func (t *MigrationTest) TestLoadMigrationsUnexpectedFile() {
+	dir := fstest.MapFS{
+		"initial.sql": {Data: []byte("CREATE TABLE auths (id varchar(191));")},
+	}
+
+	actual, err := loadMigrations(dir)
+
+	assert.Nil(t.T(), actual)
+	assert.NotNil(t.T(), err)
+}
+
+func (t *MigrationTest) TestLoadEmbeddedMigrations() {
+	dir, err := fs.Sub(migrationFiles, "migrations/mysql")
+	assert.Nil(t.T(), err)
+
+	actual, err := loadMigrations(dir)
+
+	assert.Nil(t.T(), err)
+	assert.NotEmpty(t.T(), actual)
+	assert.Equal(t.T(), 1, actual[0].Version)
+}
+
+func (t *MigrationTest) TestSplitStatements() {
+	script := `-- two tables
+CREATE TABLE a (
+  id int
+);
+
+CREATE TABLE b (id int);
+DROP TABLE c`
+
+	actual := splitStatements(script)
+
+	assert.Equal(t.T(), []string{
+		"CREATE TABLE a (\n  id int\n)",
+		"CREATE TABLE b (id int)",
+		"DROP TABLE c",
+	}, actual)
+}
+
+func (t *MigrationTest) TestUpAdoptsBaselineSchema() {
+	db, err := InitDatabase(&config.Database{Driver: SQLite, Name: ":memory:"})
+	t.Require().Nil(err)
+
+	// the auths table of the baseline, created by AutoMigrate before the account status
+	err = db.Exec(`CREATE TABLE "auths" ("id" text, "created_at" timestamp, "updated_at" timestamp, "deleted_at" timestamp, "user_id" text, "role" text, "refresh_token" text, PRIMARY KEY ("id"))`).Error
+	t.Require().Nil(err)
+
+	m, err := NewMigrator(db)
+	t.Require().Nil(err)
+
+	_, err = m.Up()
+
+	assert.Nil(t.T(), err)
+	assert.True(t.T(), db.Migrator().HasColumn("auths", "status"))
+	assert.True(t.T(), db.Migrator().HasColumn("auths", "suspended_until"))
+	assert.True(t.T(), db.Migrator().HasColumn("auths", "status_reason"))
+
+	// roll back down to the account status, the second migration
+	var rolledBack *Migration
+	for rolledBack == nil || rolledBack.Version > 2 {
+		rolledBack, err = m.Down()
+		t.Require().Nil(err)
+		t.Require().NotNil(rolledBack)
+	}
+
+	assert.Equal(t.T(), 2, rolledBack.Version)
+	assert.False(t.T(), db.Migrator().HasColumn("auths", "status"))
+}
@@ -0,0 +1,16 @@
+-- Baseline of the schema previously created by gorm's AutoMigrate, which only migrated the auths.
+-- The table is only created when missing, so databases set up by AutoMigrate are adopted as they are.
+
+CREATE TABLE IF NOT EXISTS `auths` (
+  `id` varchar(191),
+  `created_at` timestamp NULL,
+  `updated_at` timestamp NULL,
+  `deleted_at` timestamp NULL,
+  `user_id` varchar(191),
+  `role` tinytext,
+  `refresh_token` varchar(191),
+  PRIMARY KEY (`id`),
+  INDEX `idx_auths_refresh_token` (`refresh_token`),
+  INDEX `idx_auths_deleted_at` (`deleted_at`),
+  UNIQUE INDEX `idx_auths_user_id` (`user_id`)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE `auths` DROP COLUMN `status_reason`;
+ALTER TABLE `auths` DROP COLUMN `suspended_until`;
+ALTER TABLE `auths` DROP COLUMN `status`;
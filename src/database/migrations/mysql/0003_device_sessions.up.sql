@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `device_sessions` (
+  `id` varchar(191),
+  `created_at` timestamp NULL,
+  `updated_at` timestamp NULL,
+  `deleted_at` timestamp NULL,
+  `user_id` varchar(191),
+  `client_id` longtext,
+  `refresh_token` varchar(191),
+  PRIMARY KEY (`id`),
+  INDEX `idx_device_sessions_deleted_at` (`deleted_at`),
+  INDEX `idx_device_sessions_user_id` (`user_id`),
+  INDEX `idx_device_sessions_refresh_token` (`refresh_token`)
+);
@@ -0,0 +1,16 @@
+-- Audit log of the admin impersonations
+
+CREATE TABLE IF NOT EXISTS `audits` (
+  `id` varchar(191),
+  `created_at` timestamp NULL,
+  `updated_at` timestamp NULL,
+  `deleted_at` timestamp NULL,
+  `actor_id` varchar(191),
+  `target_id` varchar(191),
+  `action` tinytext,
+  `detail` text,
+  PRIMARY KEY (`id`),
+  INDEX `idx_audits_deleted_at` (`deleted_at`),
+  INDEX `idx_audits_actor_id` (`actor_id`),
+  INDEX `idx_audits_target_id` (`target_id`)
+);
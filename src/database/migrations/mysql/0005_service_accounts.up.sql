@@ -0,0 +1,31 @@
+-- Service accounts and their api keys
+
+CREATE TABLE IF NOT EXISTS `service_accounts` (
+  `id` varchar(191),
+  `created_at` timestamp NULL,
+  `updated_at` timestamp NULL,
+  `deleted_at` timestamp NULL,
+  `name` varchar(255),
+  `description` text,
+  `role` tinytext,
+  PRIMARY KEY (`id`),
+  INDEX `idx_service_accounts_deleted_at` (`deleted_at`),
+  UNIQUE INDEX `idx_service_accounts_name` (`name`)
+);
+
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `id` varchar(191),
+  `created_at` timestamp NULL,
+  `updated_at` timestamp NULL,
+  `deleted_at` timestamp NULL,
+  `service_account_id` varchar(191),
+  `prefix` varchar(32),
+  `secret` longtext,
+  `scopes` text,
+  `expires_at` timestamp NULL,
+  `last_used_at` timestamp NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_api_keys_deleted_at` (`deleted_at`),
+  INDEX `idx_api_keys_service_account_id` (`service_account_id`),
+  UNIQUE INDEX `idx_api_keys_prefix` (`prefix`)
+);
@@ -0,0 +1,15 @@
+-- Consents given to the OpenID Connect clients
+
+CREATE TABLE IF NOT EXISTS `consents` (
+  `id` varchar(191),
+  `created_at` timestamp NULL,
+  `updated_at` timestamp NULL,
+  `deleted_at` timestamp NULL,
+  `user_id` varchar(191),
+  `client_id` varchar(191),
+  `scopes` text,
+  PRIMARY KEY (`id`),
+  INDEX `idx_consents_deleted_at` (`deleted_at`),
+  INDEX `idx_consents_user_id` (`user_id`),
+  INDEX `idx_consents_client_id` (`client_id`)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "auths" (
+  "id" text,
+  "created_at" timestamp,
+  "updated_at" timestamp,
+  "deleted_at" timestamp,
+  "user_id" text,
+  "role" text,
+  "refresh_token" text,
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS "idx_auths_refresh_token" ON "auths" ("refresh_token");
+CREATE INDEX IF NOT EXISTS "idx_auths_deleted_at" ON "auths" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_auths_user_id" ON "auths" ("user_id");
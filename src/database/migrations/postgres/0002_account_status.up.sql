@@ -0,0 +1,5 @@
+-- Account suspension and ban, added to the auths of the baseline schema
+
+ALTER TABLE "auths" ADD COLUMN "status" text;
+ALTER TABLE "auths" ADD COLUMN "suspended_until" timestamp;
+ALTER TABLE "auths" ADD COLUMN "status_reason" text;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "device_sessions" (
+  "id" text,
+  "created_at" timestamp,
+  "updated_at" timestamp,
+  "deleted_at" timestamp,
+  "user_id" text,
+  "client_id" text,
+  "refresh_token" text,
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS "idx_device_sessions_deleted_at" ON "device_sessions" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_device_sessions_user_id" ON "device_sessions" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_device_sessions_refresh_token" ON "device_sessions" ("refresh_token");
@@ -0,0 +1,17 @@
+-- Audit log of the admin impersonations
+
+CREATE TABLE IF NOT EXISTS "audits" (
+  "id" text,
+  "created_at" timestamp,
+  "updated_at" timestamp,
+  "deleted_at" timestamp,
+  "actor_id" text,
+  "target_id" text,
+  "action" text,
+  "detail" text,
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS "idx_audits_deleted_at" ON "audits" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_audits_actor_id" ON "audits" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_audits_target_id" ON "audits" ("target_id");
@@ -0,0 +1,33 @@
+-- Service accounts and their api keys
+
+CREATE TABLE IF NOT EXISTS "service_accounts" (
+  "id" text,
+  "created_at" timestamp,
+  "updated_at" timestamp,
+  "deleted_at" timestamp,
+  "name" varchar(255),
+  "description" text,
+  "role" text,
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS "idx_service_accounts_deleted_at" ON "service_accounts" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_service_accounts_name" ON "service_accounts" ("name");
+
+CREATE TABLE IF NOT EXISTS "api_keys" (
+  "id" text,
+  "created_at" timestamp,
+  "updated_at" timestamp,
+  "deleted_at" timestamp,
+  "service_account_id" text,
+  "prefix" varchar(32),
+  "secret" text,
+  "scopes" text,
+  "expires_at" timestamp,
+  "last_used_at" timestamp,
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS "idx_api_keys_deleted_at" ON "api_keys" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_api_keys_service_account_id" ON "api_keys" ("service_account_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_keys_prefix" ON "api_keys" ("prefix");
@@ -0,0 +1,16 @@
+-- Consents given to the OpenID Connect clients
+
+CREATE TABLE IF NOT EXISTS "consents" (
+  "id" text,
+  "created_at" timestamp,
+  "updated_at" timestamp,
+  "deleted_at" timestamp,
+  "user_id" text,
+  "client_id" text,
+  "scopes" text,
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS "idx_consents_deleted_at" ON "consents" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_consents_user_id" ON "consents" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_consents_client_id" ON "consents" ("client_id");
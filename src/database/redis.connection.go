@@ -1,22 +1,198 @@
 package database
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/go-redis/redis/extra/redisotel/v8"
 	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	RedisStandalone = "standalone"
+	RedisSentinel   = "sentinel"
+	RedisCluster    = "cluster"
 )
 
-func InitRedisConnect(conf *config.Redis) (cache *redis.Client, err error) {
-	cache = redis.NewClient(&redis.Options{
-		Addr:     conf.Host,
-		DB:       0,
-		Username: "",
-		Password: conf.Password,
-	})
+const (
+	defaultRedisPort           = 6379
+	defaultRedisConnectRetries = 5
+	defaultRedisConnectBackoff = time.Second
+	maxRedisConnectBackoff     = 30 * time.Second
+)
 
-	if cache == nil {
-		return nil, errors.New("Cannot connect to redis server")
+// InitRedisConnect connects to redis in the configured mode and pings it until it answers,
+// so the service fails on start rather than on the first login when redis is unreachable
+func InitRedisConnect(conf *config.Redis) (cache redis.UniversalClient, err error) {
+	opts, err := redisOptions(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	switch conf.Mode {
+	case "", RedisStandalone:
+		cache = redis.NewClient(opts.Simple())
+	case RedisSentinel:
+		if opts.MasterName == "" || len(opts.Addrs) == 0 {
+			return nil, errors.New("Sentinel mode needs the master name and the sentinel addresses")
+		}
+		cache = redis.NewFailoverClient(opts.Failover())
+	case RedisCluster:
+		if conf.DB != 0 {
+			return nil, errors.New("Cluster mode only has db 0")
+		}
+		if len(opts.Addrs) == 0 {
+			return nil, errors.New("Cluster mode needs the addresses of the cluster nodes")
+		}
+		cache = redis.NewClusterClient(opts.Cluster())
+	default:
+		return nil, errors.Errorf("Unknown redis mode %q", conf.Mode)
+	}
+
+	cache.AddHook(redisotel.NewTracingHook())
+
+	if err := pingRedis(cache, conf); err != nil {
+		_ = cache.Close()
+		return nil, err
 	}
 
 	return
 }
+
+func redisOptions(conf *config.Redis) (*redis.UniversalOptions, error) {
+	tlsConfig, err := redisTlsConfig(&conf.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := conf.Addrs
+	if conf.Mode == "" || conf.Mode == RedisStandalone {
+		addrs = []string{redisAddr(conf)}
+	}
+
+	return &redis.UniversalOptions{
+		Addrs:            addrs,
+		MasterName:       conf.MasterName,
+		SentinelUsername: conf.SentinelUsername,
+		SentinelPassword: conf.SentinelPassword,
+		DB:               conf.DB,
+		Username:         conf.Username,
+		Password:         conf.Password,
+		TLSConfig:        tlsConfig,
+		PoolSize:         conf.PoolSize,
+		MinIdleConns:     conf.MinIdleConns,
+		DialTimeout:      seconds(conf.DialTimeout),
+		ReadTimeout:      seconds(conf.ReadTimeout),
+		WriteTimeout:     seconds(conf.WriteTimeout),
+		PoolTimeout:      seconds(conf.PoolTimeout),
+		IdleTimeout:      seconds(conf.IdleTimeout),
+	}, nil
+}
+
+// redisAddr accepts the host with or without the port, older configs have it as host:port
+func redisAddr(conf *config.Redis) string {
+	if _, _, err := net.SplitHostPort(conf.Host); err == nil {
+		return conf.Host
+	}
+
+	port := conf.Port
+	if port == 0 {
+		port = defaultRedisPort
+	}
+
+	return net.JoinHostPort(conf.Host, strconv.Itoa(port))
+}
+
+func redisTlsConfig(conf *config.RedisTLS) (*tls.Config, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         conf.ServerName,
+		InsecureSkipVerify: conf.InsecureSkipVerify,
+	}
+
+	if conf.CaFile != "" {
+		ca, err := os.ReadFile(conf.CaFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot read the redis ca file")
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("Redis ca file has no certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if conf.CertFile != "" || conf.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot load the redis client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func pingRedis(cache redis.UniversalClient, conf *config.Redis) error {
+	retries := conf.ConnectRetries
+	if retries <= 0 {
+		retries = defaultRedisConnectRetries
+	}
+
+	backoff := seconds(conf.ConnectBackoff)
+	if backoff <= 0 {
+		backoff = defaultRedisConnectBackoff
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = cache.Ping(ctx).Err()
+		cancel()
+
+		if err == nil || attempt >= retries {
+			break
+		}
+
+		log.Warn().
+			Err(err).
+			Str("service", "redis").
+			Int("attempt", attempt).
+			Msgf("Cannot ping redis, retrying in %v", backoff)
+
+		time.Sleep(backoff)
+		backoff = min(2*backoff, maxRedisConnectBackoff)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "Cannot connect to redis server (%v)", strings.Join(redisNodes(conf), ", "))
+	}
+
+	return nil
+}
+
+func redisNodes(conf *config.Redis) []string {
+	if conf.Mode == "" || conf.Mode == RedisStandalone {
+		return []string{redisAddr(conf)}
+	}
+
+	return conf.Addrs
+}
+
+func seconds(s int32) time.Duration {
+	return time.Duration(s) * time.Second
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RedisConnectionTest struct {
+	suite.Suite
+}
+
+func TestRedisConnection(t *testing.T) {
+	suite.Run(t, new(RedisConnectionTest))
+}
+
+func (t *RedisConnectionTest) TestRedisAddr() {
+	assert.Equal(t.T(), "localhost:6379", redisAddr(&config.Redis{Host: "localhost"}))
+	assert.Equal(t.T(), "localhost:6380", redisAddr(&config.Redis{Host: "localhost", Port: 6380}))
+	assert.Equal(t.T(), "redis:6381", redisAddr(&config.Redis{Host: "redis:6381", Port: 6380}))
+}
+
+func (t *RedisConnectionTest) TestOptionsStandaloneIgnoresAddrs() {
+	actual, err := redisOptions(&config.Redis{
+		Host:        "localhost",
+		Addrs:       []string{"sentinel:26379"},
+		DialTimeout: 2,
+	})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), []string{"localhost:6379"}, actual.Addrs)
+	assert.Equal(t.T(), 2*time.Second, actual.DialTimeout)
+	assert.Nil(t.T(), actual.TLSConfig)
+}
+
+func (t *RedisConnectionTest) TestSentinelNeedsMasterName() {
+	actual, err := InitRedisConnect(&config.Redis{
+		Mode:  RedisSentinel,
+		Addrs: []string{"sentinel:26379"},
+	})
+
+	assert.Nil(t.T(), actual)
+	assert.NotNil(t.T(), err)
+}
+
+func (t *RedisConnectionTest) TestClusterRejectsDB() {
+	actual, err := InitRedisConnect(&config.Redis{
+		Mode:  RedisCluster,
+		Addrs: []string{"node:7000"},
+		DB:    1,
+	})
+
+	assert.Nil(t.T(), actual)
+	assert.NotNil(t.T(), err)
+}
+
+func (t *RedisConnectionTest) TestUnknownMode() {
+	actual, err := InitRedisConnect(&config.Redis{Mode: "replica"})
+
+	assert.Nil(t.T(), actual)
+	assert.NotNil(t.T(), err)
+}
+
+func (t *RedisConnectionTest) TestPingFailsAfterRetries() {
+	actual, err := InitRedisConnect(&config.Redis{
+		// nothing listens on the discard port
+		Host:           "127.0.0.1",
+		Port:           9,
+		DialTimeout:    1,
+		ConnectRetries: 1,
+	})
+
+	assert.Nil(t.T(), actual)
+	assert.ErrorContains(t.T(), err, "127.0.0.1:9")
+}
+
+func (t *RedisConnectionTest) TestTlsConfigLoadsCa() {
+	caFile := filepath.Join(t.T().TempDir(), "ca.pem")
+	assert.Nil(t.T(), os.WriteFile(caFile, selfSignedCert(t.T()), 0o600))
+
+	actual, err := redisTlsConfig(&config.RedisTLS{
+		Enabled:    true,
+		CaFile:     caFile,
+		ServerName: "redis.internal",
+	})
+
+	assert.Nil(t.T(), err)
+	assert.NotNil(t.T(), actual.RootCAs)
+	assert.Equal(t.T(), "redis.internal", actual.ServerName)
+}
+
+func (t *RedisConnectionTest) TestTlsConfigMissingCa() {
+	actual, err := redisTlsConfig(&config.RedisTLS{
+		Enabled: true,
+		CaFile:  filepath.Join(t.T().TempDir(), "missing.pem"),
+	})
+
+	assert.Nil(t.T(), actual)
+	assert.NotNil(t.T(), err)
+}
+
+func selfSignedCert(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
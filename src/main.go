@@ -4,25 +4,47 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 	"time"
 
+	ah "github.com/bookpanda/mygraderlist-auth/src/app/handler/auth"
+	gwh "github.com/bookpanda/mygraderlist-auth/src/app/handler/grpcweb"
+	hh "github.com/bookpanda/mygraderlist-auth/src/app/handler/health"
+	oh "github.com/bookpanda/mygraderlist-auth/src/app/handler/oauth"
+	oih "github.com/bookpanda/mygraderlist-auth/src/app/handler/oidc"
+	"github.com/bookpanda/mygraderlist-auth/src/app/interceptor"
+	adr "github.com/bookpanda/mygraderlist-auth/src/app/repository/audit"
 	ar "github.com/bookpanda/mygraderlist-auth/src/app/repository/auth"
 	"github.com/bookpanda/mygraderlist-auth/src/app/repository/cache"
+	cr "github.com/bookpanda/mygraderlist-auth/src/app/repository/consent"
+	sar "github.com/bookpanda/mygraderlist-auth/src/app/repository/serviceaccount"
 	as "github.com/bookpanda/mygraderlist-auth/src/app/service/auth"
+	ds "github.com/bookpanda/mygraderlist-auth/src/app/service/device"
+	hs "github.com/bookpanda/mygraderlist-auth/src/app/service/health"
 	js "github.com/bookpanda/mygraderlist-auth/src/app/service/jwt"
+	ocs "github.com/bookpanda/mygraderlist-auth/src/app/service/oauthclient"
+	ois "github.com/bookpanda/mygraderlist-auth/src/app/service/oidc"
+	sas "github.com/bookpanda/mygraderlist-auth/src/app/service/serviceaccount"
 	ts "github.com/bookpanda/mygraderlist-auth/src/app/service/token"
 	"github.com/bookpanda/mygraderlist-auth/src/app/service/user"
 	jsg "github.com/bookpanda/mygraderlist-auth/src/app/strategy"
+	"github.com/bookpanda/mygraderlist-auth/src/app/utils"
 	"github.com/bookpanda/mygraderlist-auth/src/client"
 	"github.com/bookpanda/mygraderlist-auth/src/config"
 	"github.com/bookpanda/mygraderlist-auth/src/database"
-	auth_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/bookpanda/mygraderlist-auth/src/tracing"
 	user_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/backend/user"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health"
@@ -32,7 +54,8 @@ import (
 
 type operation func(ctx context.Context) error
 
-func gracefulShutdown(ctx context.Context, timeout time.Duration, ops map[string]operation) <-chan struct{} {
+// gracefulShutdown waits for a termination signal, then calls drain before running the clean up operations
+func gracefulShutdown(ctx context.Context, timeout time.Duration, drain func(), ops map[string]operation) <-chan struct{} {
 	wait := make(chan struct{})
 	go func() {
 		s := make(chan os.Signal, 1)
@@ -53,6 +76,8 @@ func gracefulShutdown(ctx context.Context, timeout time.Duration, ops map[string
 
 		defer timeoutFunc.Stop()
 
+		drain()
+
 		var wg sync.WaitGroup
 
 		for key, op := range ops {
@@ -87,6 +112,17 @@ func gracefulShutdown(ctx context.Context, timeout time.Duration, ops map[string
 }
 
 func main() {
+	// the services log with the logger of the call, which carries the request id, or the global one outside of a call
+	zerolog.DefaultContextLogger = &log.Logger
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrate(os.Args[2:]))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		os.Exit(cacheKeys(os.Args[2:]))
+	}
+
 	conf, err := config.LoadConfig()
 	if err != nil {
 		log.Fatal().
@@ -97,6 +133,31 @@ func main() {
 
 	oauthConfig := config.LoadOauthConfig(conf.Oauth)
 
+	// the oidc provider signs in through google as well, but google sends the user back to this service
+	oidcOauthConfig := *oauthConfig
+	oidcOauthConfig.RedirectURL = conf.Oidc.GoogleRedirectUri
+
+	oidcKey, err := utils.LoadRsaPrivateKey(conf.Oidc.KeyFile)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str("service", "auth").
+			Msg("Failed to start service (load oidc key)")
+	}
+	if conf.Oidc.KeyFile == "" {
+		log.Warn().
+			Str("service", "auth").
+			Msg("No oidc key file is configured, id tokens are signed with a key generated for this run only")
+	}
+
+	tracerProvider, err := tracing.InitTracer(&conf.Tracing)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str("service", "auth").
+			Msg("Failed to start service (init tracer)")
+	}
+
 	db, err := database.InitDatabase(&conf.Database)
 	if err != nil {
 		log.Fatal().
@@ -105,12 +166,38 @@ func main() {
 			Msg("Failed to start service (init database)")
 	}
 
-	cacheDB, err := database.InitRedisConnect(&conf.Redis)
+	err = database.MigrateOnStart(db, conf.Database.Migrate)
 	if err != nil {
 		log.Fatal().
 			Err(err).
 			Str("service", "auth").
-			Msg("Failed to start service (init cache)")
+			Msg("Failed to start service (migrate database)")
+	}
+
+	// the memory cache keeps the sessions in process, so it only suits a single node
+	var cacheDB redis.UniversalClient
+	var cacheRepo cache.IRepository
+	var invalidator *cache.Invalidator
+	var revocationRepo as.IRevocationRepository
+	switch conf.Cache.Driver {
+	case "", "redis":
+		cacheDB, err = database.InitRedisConnect(&conf.Redis)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("service", "auth").
+				Msg("Failed to start service (init cache)")
+		}
+		cacheRepo = cache.NewBreakerRepository(cache.NewRepository(cacheDB, conf.Cache), conf.Cache)
+		invalidator = cache.NewInvalidator(cacheDB, conf.Cache)
+		revocationRepo = cache.NewRevocationRepository(cacheDB, conf.Cache)
+	case "memory":
+		cacheRepo = cache.NewMemoryRepository(conf.Cache)
+		revocationRepo = cache.NewMemoryRevocationRepository(conf.Cache)
+	default:
+		log.Fatal().
+			Str("service", "auth").
+			Msgf("Failed to start service (unknown cache driver %q)", conf.Cache.Driver)
 	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%v", conf.App.Port))
@@ -121,7 +208,41 @@ func main() {
 			Msg("Failed to start service (listen)")
 	}
 
-	backendConn, err := grpc.Dial(conf.Service.Backend, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// an unset lifetime takes the default, a negative one would issue tokens that are already expired
+	if conf.Jwt.ExpiresIn <= 0 || conf.Jwt.ImpersonationExpiresIn < 0 || conf.Jwt.ServiceExpiresIn < 0 {
+		log.Fatal().
+			Str("service", "auth").
+			Msg("Failed to start service (jwt lifetimes must be positive)")
+	}
+
+	stg := jsg.NewJwtStrategy(conf.Jwt.Secret)
+	jtSrv := js.NewJwtService(conf.Jwt, stg)
+
+	switch conf.Validation.Mode {
+	case "", ts.ValidationStrict, ts.ValidationDegraded:
+	default:
+		log.Fatal().
+			Str("service", "auth").
+			Msgf("Failed to start service (unknown validation mode %q)", conf.Validation.Mode)
+	}
+
+	// the other replicas are only reached through redis, the invalidations stay local with the memory cache
+	var tkSrv *ts.Service
+	invalidationCtx, stopInvalidation := context.WithCancel(context.Background())
+	if invalidator != nil {
+		tkSrv = ts.NewTokenService(jtSrv, cacheRepo, invalidator, conf.Validation, conf.Oidc)
+		go invalidator.Subscribe(invalidationCtx, tkSrv)
+	} else {
+		tkSrv = ts.NewTokenService(jtSrv, cacheRepo, nil, conf.Validation, conf.Oidc)
+	}
+
+	backendConn, err := grpc.Dial(
+		conf.Service.Backend,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(interceptor.UnaryClientRequestID()),
+		grpc.WithPerRPCCredentials(client.NewServiceTokenCredentials(tkSrv, conf.Service.ClientID, conf.Service.BackendAudience)),
+	)
 	if err != nil {
 		log.Fatal().
 			Err(err).
@@ -129,27 +250,175 @@ func main() {
 			Msg("Cannot connect to service (auth connecting to backend)")
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			interceptor.UnaryRequestID(),
+			interceptor.UnaryMetrics(),
+			interceptor.UnaryAccessLog(),
+			interceptor.UnaryRecovery(),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptor.StreamRequestID(),
+			interceptor.StreamMetrics(),
+			interceptor.StreamAccessLog(),
+			interceptor.StreamRecovery(),
+		),
+	)
 
 	gClient := client.NewGoogleOauthClient(oauthConfig)
 
-	cacheRepo := cache.NewRepository(cacheDB)
-
 	usrClient := user_proto.NewUserServiceClient(backendConn)
 	usrSrv := user.NewUserService(usrClient)
 
-	stg := jsg.NewJwtStrategy(conf.Jwt.Secret)
-	jtSrv := js.NewJwtService(conf.Jwt, stg)
-
-	tkSrv := ts.NewTokenService(jtSrv, cacheRepo)
+	saRepo := sar.NewRepository(db)
+	saSrv := sas.NewService(saRepo, revocationRepo, tkSrv)
 
 	aRepo := ar.NewRepository(db)
-	aSrv := as.NewService(aRepo, tkSrv, usrSrv, conf.App, oauthConfig, gClient)
+	adRepo := adr.NewRepository(db)
+	clSrv := ocs.NewService(conf.Clients)
+
+	aSrv := as.NewService(aRepo, adRepo, revocationRepo, tkSrv, saSrv, clSrv, usrSrv, conf.App, oauthConfig, gClient)
+
+	dSrv := ds.NewService(cacheRepo, tkSrv, aSrv, clSrv, conf.Device)
+
+	csRepo := cr.NewRepository(db)
+	oiSrv := ois.NewService(cacheRepo, csRepo, tkSrv, clSrv, aSrv, client.NewGoogleOauthClient(&oidcOauthConfig), &oidcOauthConfig, oidcKey, conf.Oidc)
 
-	grpc_health_v1.RegisterHealthServer(grpcServer, health.NewServer())
+	healthServer := health.NewServer()
+	healthSrv := hs.NewService(healthServer, []hs.Dependency{
+		{
+			Name:     "database",
+			Required: true,
+			Probe: func(ctx context.Context) error {
+				sqlDb, err := db.DB()
+				if err != nil {
+					return err
+				}
+				return sqlDb.PingContext(ctx)
+			},
+		},
+		{
+			Name:     "cache",
+			Required: true,
+			Probe: func(ctx context.Context) error {
+				if cacheDB == nil {
+					return nil
+				}
+				return cacheDB.Ping(ctx).Err()
+			},
+		},
+		{
+			// only the google login needs the backend, validating tokens keeps working without it
+			Name: "backend",
+			Probe: func(ctx context.Context) error {
+				res, err := grpc_health_v1.NewHealthClient(backendConn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+				if err != nil {
+					return err
+				}
+				if res.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+					return fmt.Errorf("backend is %v", res.Status)
+				}
+				return nil
+			},
+		},
+	}, []string{
+		auth_proto.AuthService_ServiceDesc.ServiceName,
+		auth_proto.ServiceAccountService_ServiceDesc.ServiceName,
+		auth_proto.DeviceService_ServiceDesc.ServiceName,
+	}, conf.Health)
+
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	healthSrv.Start(healthCtx)
+
+	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
 	auth_proto.RegisterAuthServiceServer(grpcServer, aSrv)
+	auth_proto.RegisterServiceAccountServiceServer(grpcServer, saSrv)
+	auth_proto.RegisterDeviceServiceServer(grpcServer, dSrv)
 
 	reflection.Register(grpcServer)
+
+	authHdr := ah.NewHandler(aSrv, conf.Session)
+	oauthHdr := oh.NewHandler(aSrv)
+	oidcHdr := oih.NewHandler(oiSrv)
+	healthHdr := hh.NewHandler(healthSrv)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/validate", authHdr.Validate)
+	mux.HandleFunc("/v1/auth/refresh", authHdr.RefreshToken)
+	mux.HandleFunc("/v1/auth/google/url", authHdr.GetGoogleLoginUrl)
+	mux.HandleFunc("/v1/auth/google/verify", authHdr.VerifyGoogleLogin)
+	mux.HandleFunc("/v1/session/google/verify", authHdr.SessionLogin)
+	mux.HandleFunc("/v1/session/refresh", authHdr.SessionRefresh)
+	mux.HandleFunc("/v1/session/logout", authHdr.SessionLogout)
+	mux.HandleFunc("/oauth/introspect", oauthHdr.Introspect)
+	mux.HandleFunc("/oauth/revoke", oauthHdr.Revoke)
+	mux.HandleFunc("/.well-known/openid-configuration", oidcHdr.Discovery)
+	mux.HandleFunc("/.well-known/jwks.json", oidcHdr.Jwks)
+	mux.HandleFunc("/oidc/authorize", oidcHdr.Authorize)
+	mux.HandleFunc("/oidc/callback", oidcHdr.Callback)
+	mux.HandleFunc("/oidc/consent", oidcHdr.Consent)
+	mux.HandleFunc("/oidc/token", oidcHdr.Token)
+	mux.HandleFunc("/oidc/userinfo", oidcHdr.UserInfo)
+	mux.HandleFunc("/healthz", healthHdr.Liveness)
+	mux.HandleFunc("/readyz", healthHdr.Readiness)
+
+	// grpc-web calls get an http span around the one recorded by the grpc stats handler
+	httpHandler := otelhttp.NewHandler(gwh.NewHandler(grpcServer, mux, conf.GrpcWeb), "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}),
+	)
+
+	httpServer := &http.Server{
+		Addr:              fmt.Sprintf(":%v", conf.App.HttpPort),
+		Handler:           httpHandler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Info().
+			Str("service", "auth").
+			Msgf("MyGraderList auth http starting at port %v", conf.App.HttpPort)
+
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal().
+				Err(err).
+				Str("service", "auth").
+				Msg("Failed to start http service")
+		}
+	}()
+
+	// the metrics are kept off the public listener, the admin port is only reachable from within the cluster
+	var adminServer *http.Server
+	if conf.App.AdminPort != 0 {
+		adminMux := http.NewServeMux()
+		adminMux.Handle("/metrics", promhttp.Handler())
+
+		adminServer = &http.Server{
+			Addr:              fmt.Sprintf(":%v", conf.App.AdminPort),
+			Handler:           adminMux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		go func() {
+			log.Info().
+				Str("service", "auth").
+				Msgf("MyGraderList auth admin starting at port %v", conf.App.AdminPort)
+
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal().
+					Err(err).
+					Str("service", "auth").
+					Msg("Failed to start admin service")
+			}
+		}()
+	} else {
+		log.Warn().
+			Str("service", "auth").
+			Msg("app.admin_port is not set, the metrics are disabled")
+	}
+
 	go func() {
 		log.Info().
 			Str("service", "auth").
@@ -163,7 +432,12 @@ func main() {
 		}
 	}()
 
-	wait := gracefulShutdown(context.Background(), 2*time.Second, map[string]operation{
+	wait := gracefulShutdown(context.Background(), 2*time.Second, func() {
+		stopInvalidation()
+		aSrv.Shutdown()
+		stopHealth()
+		healthSrv.Shutdown()
+	}, map[string]operation{
 		"database": func(ctx context.Context) error {
 			sqlDb, err := db.DB()
 			if err != nil {
@@ -175,9 +449,24 @@ func main() {
 			grpcServer.GracefulStop()
 			return nil
 		},
+		"http server": func(ctx context.Context) error {
+			return httpServer.Shutdown(ctx)
+		},
+		"admin server": func(ctx context.Context) error {
+			if adminServer == nil {
+				return nil
+			}
+			return adminServer.Shutdown(ctx)
+		},
 		"cache": func(ctx context.Context) error {
+			if cacheDB == nil {
+				return nil
+			}
 			return cacheDB.Close()
 		},
+		"tracer": func(ctx context.Context) error {
+			return tracerProvider.Shutdown(ctx)
+		},
 	})
 
 	<-wait
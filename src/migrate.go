@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bookpanda/mygraderlist-auth/src/config"
+	"github.com/bookpanda/mygraderlist-auth/src/database"
+	"github.com/rs/zerolog/log"
+)
+
+const migrateUsage = "usage: server migrate up|down|status"
+
+// migrate runs the `migrate` command against the configured database and returns the exit code
+func migrate(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		log.Error().Err(err).Str("service", "migration").Msg("Failed to load config")
+		return 1
+	}
+
+	db, err := database.InitDatabase(&conf.Database)
+	if err != nil {
+		log.Error().Err(err).Str("service", "migration").Msg("Failed to connect to the database")
+		return 1
+	}
+
+	m, err := database.NewMigrator(db)
+	if err != nil {
+		log.Error().Err(err).Str("service", "migration").Msg("Failed to load the migrations")
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up()
+		if err != nil {
+			log.Error().Err(err).Str("service", "migration").Msg("Failed to apply the migrations")
+			return 1
+		}
+		fmt.Printf("applied %v migration(s)\n", len(applied))
+	case "down":
+		migration, err := m.Down()
+		if err != nil {
+			log.Error().Err(err).Str("service", "migration").Msg("Failed to roll back the migration")
+			return 1
+		}
+		if migration == nil {
+			fmt.Println("no migration to roll back")
+			return 0
+		}
+		fmt.Printf("rolled back %v_%v\n", migration.Version, migration.Name)
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			log.Error().Err(err).Str("service", "migration").Msg("Failed to read the migrations")
+			return 1
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\n", status.Version, status.Name, appliedAt)
+		}
+		_ = w.Flush()
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
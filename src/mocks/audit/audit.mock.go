@@ -0,0 +1,17 @@
+package audit
+
+import (
+	"context"
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/audit"
+	"github.com/stretchr/testify/mock"
+)
+
+type RepositoryMock struct {
+	mock.Mock
+}
+
+func (r *RepositoryMock) Create(_ context.Context, in *model.Audit) error {
+	args := r.Called(in)
+
+	return args.Error(0)
+}
@@ -1,20 +1,24 @@
 package auth
 
 import (
+	"context"
+
 	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
+	oidcDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oidc"
 	model "github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
 	"github.com/bookpanda/mygraderlist-auth/src/config"
-	auth_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
 	user_proto "github.com/bookpanda/mygraderlist-proto/MyGraderList/backend/user"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
 )
 
 type RepositoryMock struct {
 	mock.Mock
 }
 
-func (r *RepositoryMock) FindByRefreshToken(id string, result *model.Auth) error {
+func (r *RepositoryMock) FindByRefreshToken(_ context.Context, id string, result *model.Auth) error {
 	args := r.Called(id, result)
 
 	if args.Get(0) != nil {
@@ -24,7 +28,7 @@ func (r *RepositoryMock) FindByRefreshToken(id string, result *model.Auth) error
 	return args.Error(1)
 }
 
-func (r *RepositoryMock) FindByUserID(id string, in *model.Auth) error {
+func (r *RepositoryMock) FindByUserID(_ context.Context, id string, in *model.Auth) error {
 	args := r.Called(id, in)
 
 	if args.Get(0) != nil {
@@ -34,7 +38,7 @@ func (r *RepositoryMock) FindByUserID(id string, in *model.Auth) error {
 	return args.Error(1)
 }
 
-func (r *RepositoryMock) Create(in *model.Auth) error {
+func (r *RepositoryMock) Create(_ context.Context, in *model.Auth) error {
 	args := r.Called(in)
 
 	if args.Get(0) != nil {
@@ -44,7 +48,7 @@ func (r *RepositoryMock) Create(in *model.Auth) error {
 	return args.Error(1)
 }
 
-func (r *RepositoryMock) Update(id string, in *model.Auth) error {
+func (r *RepositoryMock) Update(_ context.Context, id string, in *model.Auth) error {
 	args := r.Called(in)
 
 	if args.Get(0) != nil {
@@ -54,11 +58,99 @@ func (r *RepositoryMock) Update(id string, in *model.Auth) error {
 	return args.Error(1)
 }
 
+func (r *RepositoryMock) UpdateStatus(_ context.Context, id string, in *model.Auth) error {
+	args := r.Called(id, in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*model.Auth)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) UpdateRole(_ context.Context, id string, in *model.Auth) error {
+	args := r.Called(id, in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*model.Auth)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) ClearRefreshToken(_ context.Context, id string) error {
+	args := r.Called(id)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) CreateDeviceSession(_ context.Context, in *model.DeviceSession) error {
+	args := r.Called(in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*model.DeviceSession)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) FindDeviceSession(_ context.Context, id string, result *model.DeviceSession) error {
+	args := r.Called(id, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*model.DeviceSession)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) FindDeviceSessionByRefreshToken(_ context.Context, refreshToken string, result *model.DeviceSession) error {
+	args := r.Called(refreshToken, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*model.DeviceSession)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) UpdateDeviceSessionRefreshToken(_ context.Context, id string, refreshToken string) error {
+	args := r.Called(id, refreshToken)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) DeleteDeviceSession(_ context.Context, id string) error {
+	args := r.Called(id)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) FindDeviceSessions(_ context.Context, userID string, result *[]*model.DeviceSession) error {
+	args := r.Called(userID, result)
+
+	if args.Get(0) != nil {
+		*result = args.Get(0).([]*model.DeviceSession)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) DeleteDeviceSessions(_ context.Context, userID string, result *[]*model.DeviceSession) error {
+	args := r.Called(userID, result)
+
+	if args.Get(0) != nil {
+		*result = args.Get(0).([]*model.DeviceSession)
+	}
+
+	return args.Error(1)
+}
+
 type UserServiceMock struct {
 	mock.Mock
 }
 
-func (c *UserServiceMock) FindByEmail(email string) (result *user_proto.User, err error) {
+func (c *UserServiceMock) FindByEmail(_ context.Context, email string) (result *user_proto.User, err error) {
 	args := c.Called(email)
 
 	if args.Get(0) != nil {
@@ -68,7 +160,7 @@ func (c *UserServiceMock) FindByEmail(email string) (result *user_proto.User, er
 	return result, args.Error(1)
 }
 
-func (c *UserServiceMock) Create(in *user_proto.User) (result *user_proto.User, err error) {
+func (c *UserServiceMock) Create(_ context.Context, in *user_proto.User) (result *user_proto.User, err error) {
 	args := c.Called(in)
 
 	if args.Get(0) != nil {
@@ -88,6 +180,24 @@ func (s *JwtServiceMock) SignAuth(in *model.Auth) (token string, err error) {
 	return args.String(0), args.Error(1)
 }
 
+func (s *JwtServiceMock) SignDevice(in *model.Auth, sessionID string) (token string, err error) {
+	args := s.Called(in, sessionID)
+
+	return args.String(0), args.Error(1)
+}
+
+func (s *JwtServiceMock) SignImpersonation(in *model.Auth, actorID string) (token string, err error) {
+	args := s.Called(in, actorID)
+
+	return args.String(0), args.Error(1)
+}
+
+func (s *JwtServiceMock) SignService(clientID string, audience []string, scopes []string) (token string, err error) {
+	args := s.Called(clientID, audience, scopes)
+
+	return args.String(0), args.Error(1)
+}
+
 func (s *JwtServiceMock) VerifyAuth(token string) (decode *jwt.Token, err error) {
 	args := s.Called(token)
 
@@ -108,7 +218,7 @@ type TokenServiceMock struct {
 	mock.Mock
 }
 
-func (s *TokenServiceMock) CreateCredentials(in *model.Auth, secret string) (credential *auth_proto.Credential, err error) {
+func (s *TokenServiceMock) CreateCredentials(_ context.Context, in *model.Auth, secret string) (credential *auth_proto.Credential, err error) {
 	args := s.Called(in, secret)
 
 	if args.Get(0) != nil {
@@ -118,7 +228,7 @@ func (s *TokenServiceMock) CreateCredentials(in *model.Auth, secret string) (cre
 	return credential, args.Error(1)
 }
 
-func (s *TokenServiceMock) Validate(token string) (payload *dto.UserCredential, err error) {
+func (s *TokenServiceMock) Validate(_ context.Context, token string) (payload *dto.UserCredential, err error) {
 	args := s.Called(token)
 
 	if args.Get(0) != nil {
@@ -127,3 +237,252 @@ func (s *TokenServiceMock) Validate(token string) (payload *dto.UserCredential,
 
 	return payload, args.Error(1)
 }
+
+func (s *TokenServiceMock) ValidateBatch(_ context.Context, tokens []string) (credentials []*dto.UserCredential, errs []error) {
+	args := s.Called(tokens)
+
+	if args.Get(0) != nil {
+		credentials = args.Get(0).([]*dto.UserCredential)
+	}
+
+	if args.Get(1) != nil {
+		errs = args.Get(1).([]error)
+	}
+
+	return
+}
+
+func (s *TokenServiceMock) RevokeCredentials(_ context.Context, in *model.Auth) error {
+	args := s.Called(in)
+
+	return args.Error(0)
+}
+
+func (s *TokenServiceMock) RemoveCredentials(_ context.Context, userID string) error {
+	args := s.Called(userID)
+
+	return args.Error(0)
+}
+
+func (s *TokenServiceMock) CreateDeviceCredentials(_ context.Context, in *model.Auth, sessionID string) (credential *auth_proto.Credential, err error) {
+	args := s.Called(in, sessionID)
+
+	if args.Get(0) != nil {
+		credential = args.Get(0).(*auth_proto.Credential)
+	}
+
+	return credential, args.Error(1)
+}
+
+func (s *TokenServiceMock) RemoveDeviceCredentials(_ context.Context, sessionID string) error {
+	args := s.Called(sessionID)
+
+	return args.Error(0)
+}
+
+func (s *TokenServiceMock) CreateImpersonationCredentials(_ context.Context, in *model.Auth, actorID string) (credential *auth_proto.Credential, err error) {
+	args := s.Called(in, actorID)
+
+	if args.Get(0) != nil {
+		credential = args.Get(0).(*auth_proto.Credential)
+	}
+
+	return credential, args.Error(1)
+}
+
+func (s *TokenServiceMock) ImpersonatedUserID(_ context.Context, actorID string) (string, error) {
+	args := s.Called(actorID)
+
+	return args.String(0), args.Error(1)
+}
+
+func (s *TokenServiceMock) RemoveImpersonationCredentials(_ context.Context, actorID string) error {
+	args := s.Called(actorID)
+
+	return args.Error(0)
+}
+
+func (s *TokenServiceMock) CreateOidcAccessToken(_ context.Context, grant *oidcDto.CacheAccessToken) (string, error) {
+	args := s.Called(grant)
+
+	return args.String(0), args.Error(1)
+}
+
+func (s *TokenServiceMock) ValidateOidcAccessToken(_ context.Context, accessToken string) (grant *oidcDto.CacheAccessToken, err error) {
+	args := s.Called(accessToken)
+
+	if args.Get(0) != nil {
+		grant = args.Get(0).(*oidcDto.CacheAccessToken)
+	}
+
+	return grant, args.Error(1)
+}
+
+func (s *TokenServiceMock) RemoveOidcAccessToken(_ context.Context, accessToken string) error {
+	args := s.Called(accessToken)
+
+	return args.Error(0)
+}
+
+func (s *TokenServiceMock) RevokeOidcAccessTokens(_ context.Context, userID string) error {
+	args := s.Called(userID)
+
+	return args.Error(0)
+}
+
+type ApiKeyServiceMock struct {
+	mock.Mock
+}
+
+func (s *ApiKeyServiceMock) ValidateApiKey(_ context.Context, key string) (payload *dto.UserCredential, err error) {
+	args := s.Called(key)
+
+	if args.Get(0) != nil {
+		payload = args.Get(0).(*dto.UserCredential)
+	}
+
+	return payload, args.Error(1)
+}
+
+func (s *TokenServiceMock) RevokeImpersonations(_ context.Context, targetID string) (actorIDs []string, err error) {
+	args := s.Called(targetID)
+
+	if args.Get(0) != nil {
+		actorIDs = args.Get(0).([]string)
+	}
+
+	return actorIDs, args.Error(1)
+}
+
+func (s *TokenServiceMock) CreateServiceCredentials(clientID string, audience []string, scopes []string) (credential *auth_proto.Credential, err error) {
+	args := s.Called(clientID, audience, scopes)
+
+	if args.Get(0) != nil {
+		credential = args.Get(0).(*auth_proto.Credential)
+	}
+
+	return credential, args.Error(1)
+}
+
+type ClientServiceMock struct {
+	mock.Mock
+}
+
+func (s *ClientServiceMock) Authenticate(clientID string, clientSecret string) (client *config.Client, err error) {
+	args := s.Called(clientID, clientSecret)
+
+	if args.Get(0) != nil {
+		client = args.Get(0).(*config.Client)
+	}
+
+	return client, args.Error(1)
+}
+
+func (s *ClientServiceMock) Identify(clientID string, clientSecret string) (client *config.Client, err error) {
+	args := s.Called(clientID, clientSecret)
+
+	if args.Get(0) != nil {
+		client = args.Get(0).(*config.Client)
+	}
+
+	return client, args.Error(1)
+}
+
+func (s *ClientServiceMock) FindByClientID(clientID string) (client *config.Client, err error) {
+	args := s.Called(clientID)
+
+	if args.Get(0) != nil {
+		client = args.Get(0).(*config.Client)
+	}
+
+	return client, args.Error(1)
+}
+
+type CredentialServiceMock struct {
+	mock.Mock
+}
+
+func (s *CredentialServiceMock) IssueDeviceCredential(_ context.Context, userID string, clientID string) (credential *auth_proto.Credential, err error) {
+	args := s.Called(userID, clientID)
+
+	if args.Get(0) != nil {
+		credential = args.Get(0).(*auth_proto.Credential)
+	}
+
+	return credential, args.Error(1)
+}
+
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (s *ServiceMock) Validate(_ context.Context, in *auth_proto.ValidateRequest) (res *auth_proto.ValidateResponse, err error) {
+	args := s.Called(in)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*auth_proto.ValidateResponse)
+	}
+
+	return res, args.Error(1)
+}
+
+func (s *ServiceMock) RefreshToken(_ context.Context, in *auth_proto.RefreshTokenRequest) (res *auth_proto.RefreshTokenResponse, err error) {
+	args := s.Called(in)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*auth_proto.RefreshTokenResponse)
+	}
+
+	return res, args.Error(1)
+}
+
+func (s *ServiceMock) GetGoogleLoginUrl(_ context.Context, in *auth_proto.GetGoogleLoginUrlRequest) (res *auth_proto.GetGoogleLoginUrlResponse, err error) {
+	args := s.Called(in)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*auth_proto.GetGoogleLoginUrlResponse)
+	}
+
+	return res, args.Error(1)
+}
+
+func (s *ServiceMock) VerifyGoogleLogin(_ context.Context, in *auth_proto.VerifyGoogleLoginRequest) (res *auth_proto.VerifyGoogleLoginResponse, err error) {
+	args := s.Called(in)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*auth_proto.VerifyGoogleLoginResponse)
+	}
+
+	return res, args.Error(1)
+}
+func (s *ServiceMock) Logout(_ context.Context, in *auth_proto.LogoutRequest) (res *auth_proto.LogoutResponse, err error) {
+	args := s.Called(in)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*auth_proto.LogoutResponse)
+	}
+
+	return res, args.Error(1)
+}
+
+// RevocationStreamMock records the sent events and cancels its context once it got the expected count
+type RevocationStreamMock struct {
+	grpc.ServerStream
+	Ctx    context.Context
+	Cancel context.CancelFunc
+	Expect int
+	Events []*auth_proto.RevocationEvent
+}
+
+func (s *RevocationStreamMock) Context() context.Context {
+	return s.Ctx
+}
+
+func (s *RevocationStreamMock) Send(event *auth_proto.RevocationEvent) error {
+	s.Events = append(s.Events, event)
+	if len(s.Events) >= s.Expect {
+		s.Cancel()
+	}
+
+	return nil
+}
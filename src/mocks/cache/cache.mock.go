@@ -1,6 +1,9 @@
 package cache
 
 import (
+	"context"
+	"reflect"
+
 	dto "github.com/bookpanda/mygraderlist-auth/src/app/dto/auth"
 	"github.com/stretchr/testify/mock"
 )
@@ -10,7 +13,7 @@ type RepositoryMock struct {
 	V map[string]interface{}
 }
 
-func (t *RepositoryMock) SaveCache(key string, v interface{}, ttl int) error {
+func (t *RepositoryMock) SaveCache(_ context.Context, key string, v interface{}, ttl int) error {
 	args := t.Called(key, v, ttl)
 
 	t.V[key] = v
@@ -18,12 +21,86 @@ func (t *RepositoryMock) SaveCache(key string, v interface{}, ttl int) error {
 	return args.Error(0)
 }
 
-func (t *RepositoryMock) GetCache(key string, v interface{}) error {
+func (t *RepositoryMock) GetCache(_ context.Context, key string, v interface{}) error {
 	args := t.Called(key, v)
 
 	if args.Get(0) != nil {
-		*v.(*dto.CacheAuth) = *args.Get(0).(*dto.CacheAuth)
+		reflect.ValueOf(v).Elem().Set(reflect.ValueOf(args.Get(0)).Elem())
 	}
 
 	return args.Error(1)
 }
+
+func (t *RepositoryMock) TakeCache(_ context.Context, key string, v interface{}) error {
+	args := t.Called(key, v)
+
+	if args.Get(0) != nil {
+		reflect.ValueOf(v).Elem().Set(reflect.ValueOf(args.Get(0)).Elem())
+	}
+
+	return args.Error(1)
+}
+
+func (t *RepositoryMock) GetCaches(_ context.Context, keys []string, values []interface{}) ([]error, error) {
+	args := t.Called(keys)
+
+	if args.Get(0) != nil {
+		for i, v := range args.Get(0).([]interface{}) {
+			if v != nil {
+				reflect.ValueOf(values[i]).Elem().Set(reflect.ValueOf(v).Elem())
+			}
+		}
+	}
+
+	var errs []error
+	if args.Get(1) != nil {
+		errs = args.Get(1).([]error)
+	}
+
+	return errs, args.Error(2)
+}
+
+func (t *RepositoryMock) RemoveCache(_ context.Context, key string) error {
+	args := t.Called(key)
+
+	delete(t.V, key)
+
+	return args.Error(0)
+}
+
+type InvalidatorMock struct {
+	mock.Mock
+}
+
+func (t *InvalidatorMock) Publish(_ context.Context, key string) error {
+	args := t.Called(key)
+
+	return args.Error(0)
+}
+
+type RevocationRepositoryMock struct {
+	mock.Mock
+}
+
+func (t *RevocationRepositoryMock) Append(_ context.Context, event *dto.RevocationEvent) (string, error) {
+	args := t.Called(event)
+
+	return args.String(0), args.Error(1)
+}
+
+func (t *RevocationRepositoryMock) Seek(_ context.Context, offset string) (string, error) {
+	args := t.Called(offset)
+
+	return args.String(0), args.Error(1)
+}
+
+func (t *RevocationRepositoryMock) Read(_ context.Context, offset string, count int64) ([]*dto.RevocationEvent, error) {
+	args := t.Called(offset, count)
+
+	var events []*dto.RevocationEvent
+	if args.Get(0) != nil {
+		events = args.Get(0).([]*dto.RevocationEvent)
+	}
+
+	return events, args.Error(1)
+}
@@ -0,0 +1,15 @@
+package health
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (s *ServiceMock) Status() (bool, map[string]string) {
+	args := s.Called()
+
+	return args.Bool(0), args.Get(1).(map[string]string)
+}
@@ -0,0 +1,32 @@
+package oauth
+
+import (
+	"context"
+
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/stretchr/testify/mock"
+)
+
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (s *ServiceMock) Introspect(_ context.Context, in *auth_proto.IntrospectRequest) (res *auth_proto.IntrospectResponse, err error) {
+	args := s.Called(in)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*auth_proto.IntrospectResponse)
+	}
+
+	return res, args.Error(1)
+}
+
+func (s *ServiceMock) Revoke(_ context.Context, in *auth_proto.RevokeRequest) (res *auth_proto.RevokeResponse, err error) {
+	args := s.Called(in)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*auth_proto.RevokeResponse)
+	}
+
+	return res, args.Error(1)
+}
@@ -0,0 +1,133 @@
+package oidc
+
+import (
+	"context"
+
+	oidcDto "github.com/bookpanda/mygraderlist-auth/src/app/dto/oidc"
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/auth"
+	consentModel "github.com/bookpanda/mygraderlist-auth/src/app/model/consent"
+	"github.com/bookpanda/mygraderlist-auth/src/client"
+	"github.com/stretchr/testify/mock"
+)
+
+type ConsentRepositoryMock struct {
+	mock.Mock
+}
+
+func (r *ConsentRepositoryMock) FindByUserIDAndClientID(_ context.Context, userID string, clientID string, result *consentModel.Consent) error {
+	args := r.Called(userID, clientID, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*consentModel.Consent)
+	}
+
+	return args.Error(1)
+}
+
+func (r *ConsentRepositoryMock) Create(_ context.Context, in *consentModel.Consent) error {
+	args := r.Called(in)
+
+	return args.Error(0)
+}
+
+func (r *ConsentRepositoryMock) Update(_ context.Context, id string, in *consentModel.Consent) error {
+	args := r.Called(id, in)
+
+	return args.Error(0)
+}
+
+type AccountServiceMock struct {
+	mock.Mock
+}
+
+func (s *AccountServiceMock) FindOrCreateGoogleUser(_ context.Context, in *client.GoogleUserEmailResponse) (auth *model.Auth, err error) {
+	args := s.Called(in)
+
+	if args.Get(0) != nil {
+		auth = args.Get(0).(*model.Auth)
+	}
+
+	return auth, args.Error(1)
+}
+
+func (s *AccountServiceMock) FindActiveAccount(_ context.Context, userID string) (auth *model.Auth, err error) {
+	args := s.Called(userID)
+
+	if args.Get(0) != nil {
+		auth = args.Get(0).(*model.Auth)
+	}
+
+	return auth, args.Error(1)
+}
+
+type GoogleOauthClientMock struct {
+	mock.Mock
+}
+
+func (c *GoogleOauthClientMock) GetUserEmail(_ context.Context, code string) (res *client.GoogleUserEmailResponse, err error) {
+	args := c.Called(code)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*client.GoogleUserEmailResponse)
+	}
+
+	return res, args.Error(1)
+}
+
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (s *ServiceMock) Discovery() *oidcDto.DiscoveryDocument {
+	args := s.Called()
+
+	return args.Get(0).(*oidcDto.DiscoveryDocument)
+}
+
+func (s *ServiceMock) Jwks() *oidcDto.JwkSet {
+	args := s.Called()
+
+	return args.Get(0).(*oidcDto.JwkSet)
+}
+
+func (s *ServiceMock) Authorize(_ context.Context, in *oidcDto.AuthorizeRequest) (string, string, error) {
+	args := s.Called(in)
+
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (s *ServiceMock) Callback(_ context.Context, requestID string, code string) (prompt *oidcDto.ConsentPrompt, redirect string, err error) {
+	args := s.Called(requestID, code)
+
+	if args.Get(0) != nil {
+		prompt = args.Get(0).(*oidcDto.ConsentPrompt)
+	}
+
+	return prompt, args.String(1), args.Error(2)
+}
+
+func (s *ServiceMock) Consent(_ context.Context, requestID string, approved bool) (string, error) {
+	args := s.Called(requestID, approved)
+
+	return args.String(0), args.Error(1)
+}
+
+func (s *ServiceMock) Token(_ context.Context, in *oidcDto.TokenRequest) (res *oidcDto.TokenResponse, err error) {
+	args := s.Called(in)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*oidcDto.TokenResponse)
+	}
+
+	return res, args.Error(1)
+}
+
+func (s *ServiceMock) UserInfo(_ context.Context, accessToken string) (res *oidcDto.UserInfoResponse, err error) {
+	args := s.Called(accessToken)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*oidcDto.UserInfoResponse)
+	}
+
+	return res, args.Error(1)
+}
@@ -0,0 +1,101 @@
+package serviceaccount
+
+import (
+	"context"
+	"time"
+
+	model "github.com/bookpanda/mygraderlist-auth/src/app/model/serviceaccount"
+	"github.com/stretchr/testify/mock"
+)
+
+type RepositoryMock struct {
+	mock.Mock
+}
+
+func (r *RepositoryMock) FindAll(_ context.Context, result *[]*model.ServiceAccount) error {
+	args := r.Called(result)
+
+	if args.Get(0) != nil {
+		*result = args.Get(0).([]*model.ServiceAccount)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) FindOne(_ context.Context, id string, result *model.ServiceAccount) error {
+	args := r.Called(id, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*model.ServiceAccount)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) Create(_ context.Context, in *model.ServiceAccount) error {
+	args := r.Called(in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*model.ServiceAccount)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) Update(_ context.Context, id string, in *model.ServiceAccount) error {
+	args := r.Called(id, in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*model.ServiceAccount)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) Delete(_ context.Context, id string) error {
+	args := r.Called(id)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) FindAllApiKey(_ context.Context, serviceAccountID string, result *[]*model.ApiKey) error {
+	args := r.Called(serviceAccountID, result)
+
+	if args.Get(0) != nil {
+		*result = args.Get(0).([]*model.ApiKey)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) FindApiKeyByPrefix(_ context.Context, prefix string, result *model.ApiKey) error {
+	args := r.Called(prefix, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*model.ApiKey)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) CreateApiKey(_ context.Context, in *model.ApiKey) error {
+	args := r.Called(in)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) DeleteApiKey(_ context.Context, id string, result *model.ApiKey) error {
+	args := r.Called(id, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*model.ApiKey)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) UpdateApiKeyLastUsed(_ context.Context, id string, lastUsedAt time.Time) error {
+	args := r.Called(id, lastUsedAt)
+
+	return args.Error(0)
+}
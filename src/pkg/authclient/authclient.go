@@ -0,0 +1,192 @@
+// Package authclient lets the MyGraderList services validate the bearer tokens of their callers
+// against the auth service, with the gRPC interceptors and the http middleware putting the
+// credential of the caller in the request context.
+package authclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultTimeout  = 2 * time.Second
+	defaultAttempts = 3
+	defaultBackoff  = 100 * time.Millisecond
+	defaultCacheTTL = 5 * time.Second
+)
+
+type Config struct {
+	// Timeout of a single call to the auth service
+	Timeout time.Duration
+	// Attempts is how many times the auth service is called while it's unavailable, 1 turns the retries off
+	Attempts int
+	// Backoff is the wait before the first retry, it doubles after every attempt
+	Backoff time.Duration
+	// CacheSize is how many validations are kept, 0 turns the cache off
+	CacheSize int
+	// CacheTTL is how long a validation is kept, a session revoked in the meantime stays valid until then
+	CacheTTL time.Duration
+	// Audience the tokens of the callers must be issued for. The auth service doesn't check it when
+	// empty, while every locally verified token is rejected without it.
+	Audience string
+	// JwksUri turns on the local verification of the access tokens signed with an asymmetric key
+	JwksUri string
+	// Issuer the locally verified tokens must have, not checked when empty
+	Issuer string
+	// JwksRefresh is how often the signing keys are fetched again
+	JwksRefresh time.Duration
+	// PublicMethods are the full gRPC method names the interceptors let through without a token
+	PublicMethods []string
+}
+
+// Credential is the caller of a validated token
+type Credential struct {
+	UserId    string
+	Role      role.Role
+	ActorId   string
+	Scopes    []string
+	Audience  []string
+	ExpiresAt int64
+	IssuedAt  int64
+}
+
+// Client validates the tokens with the AuthService, the symmetrically signed ones can only be checked
+// by the auth service while the others are verified locally once a JWKS uri is configured
+type Client struct {
+	stub auth_proto.AuthServiceClient
+	conf Config
+	// cache is nil when it's turned off
+	cache *cache
+	// jwks is nil without a JWKS uri
+	jwks *jwks
+}
+
+func NewClient(conn grpc.ClientConnInterface, conf Config) *Client {
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaultTimeout
+	}
+
+	if conf.Attempts <= 0 {
+		conf.Attempts = defaultAttempts
+	}
+
+	if conf.Backoff <= 0 {
+		conf.Backoff = defaultBackoff
+	}
+
+	if conf.CacheTTL <= 0 {
+		conf.CacheTTL = defaultCacheTTL
+	}
+
+	c := &Client{
+		stub: auth_proto.NewAuthServiceClient(conn),
+		conf: conf,
+	}
+
+	if conf.CacheSize > 0 {
+		c.cache = newCache(conf.CacheSize, conf.CacheTTL)
+	}
+
+	if conf.JwksUri != "" {
+		c.jwks = newJwks(conf)
+	}
+
+	return c
+}
+
+// Validate returns the credential of the token, or the status the auth service rejected it with
+func (c *Client) Validate(ctx context.Context, token string) (*Credential, error) {
+	if c.jwks != nil && c.jwks.accepts(token) {
+		return c.jwks.verify(ctx, token)
+	}
+
+	var fingerprint string
+	if c.cache != nil {
+		fingerprint = hash(token)
+		if credential, ok := c.cache.get(fingerprint); ok {
+			return credential, nil
+		}
+	}
+
+	res, err := c.validate(ctx, &auth_proto.ValidateRequest{
+		Token:    token,
+		Audience: c.conf.Audience,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	credential := &Credential{
+		UserId:  res.UserId,
+		Role:    role.Role(res.Role),
+		ActorId: res.ActorId,
+		Scopes:  res.Scopes,
+	}
+
+	if c.cache != nil {
+		c.cache.set(fingerprint, credential, expiresAt(token))
+	}
+
+	return credential, nil
+}
+
+// validate calls the auth service again while it's unavailable, a rejected token isn't retried
+func (c *Client) validate(ctx context.Context, req *auth_proto.ValidateRequest) (*auth_proto.ValidateResponse, error) {
+	backoff := c.conf.Backoff
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.conf.Timeout)
+		res, err := c.stub.Validate(attemptCtx, req)
+		cancel()
+
+		if err == nil || attempt >= c.conf.Attempts || ctx.Err() != nil || !retryable(err) {
+			return res, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// expiresAt reads the expiry of the token without verifying it, so a cached validation doesn't
+// outlive the token. It's zero for the tokens that aren't a JWT, e.g. the API keys.
+func expiresAt(token string) time.Time {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return time.Time{}
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(exp), 0)
+}
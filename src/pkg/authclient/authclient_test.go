@@ -0,0 +1,310 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	auth_proto "github.com/bookpanda/mygraderlist-auth/src/proto/auth"
+	"github.com/bxcodec/faker/v3"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type AuthClientTest struct {
+	suite.Suite
+	Server *authServer
+	Conn   *grpc.ClientConn
+	Token  string
+	UserID string
+}
+
+// authServer answers Validate with the queued errors first, then with the response
+type authServer struct {
+	auth_proto.UnimplementedAuthServiceServer
+	mu       sync.Mutex
+	calls    int
+	errs     []error
+	response *auth_proto.ValidateResponse
+}
+
+func (s *authServer) Validate(_ context.Context, _ *auth_proto.ValidateRequest) (*auth_proto.ValidateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return nil, err
+	}
+
+	return s.response, nil
+}
+
+func (s *authServer) called() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls
+}
+
+func TestAuthClient(t *testing.T) {
+	suite.Run(t, new(AuthClientTest))
+}
+
+func (t *AuthClientTest) SetupTest() {
+	t.Token = faker.Word()
+	t.UserID = faker.UUIDDigit()
+	t.Server = &authServer{response: &auth_proto.ValidateResponse{
+		UserId: t.UserID,
+		Role:   string(role.USER),
+	}}
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	auth_proto.RegisterAuthServiceServer(server, t.Server)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.T().Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t.T(), err)
+	t.Conn = conn
+}
+
+func (t *AuthClientTest) TearDownTest() {
+	_ = t.Conn.Close()
+}
+
+func (t *AuthClientTest) incomingContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func (t *AuthClientTest) TestValidateSuccess() {
+	client := NewClient(t.Conn, Config{})
+
+	actual, err := client.Validate(context.Background(), t.Token)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), &Credential{UserId: t.UserID, Role: role.USER}, actual)
+}
+
+func (t *AuthClientTest) TestValidateRetriesUnavailable() {
+	t.Server.errs = []error{status.Error(codes.Unavailable, "Connection refused")}
+	client := NewClient(t.Conn, Config{Backoff: time.Millisecond})
+
+	actual, err := client.Validate(context.Background(), t.Token)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.UserID, actual.UserId)
+	assert.Equal(t.T(), 2, t.Server.called())
+}
+
+func (t *AuthClientTest) TestValidateGivesUpAfterAttempts() {
+	unavailable := status.Error(codes.Unavailable, "Connection refused")
+	t.Server.errs = []error{unavailable, unavailable, unavailable}
+	client := NewClient(t.Conn, Config{Attempts: 2, Backoff: time.Millisecond})
+
+	actual, err := client.Validate(context.Background(), t.Token)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unavailable, status.Code(err))
+	assert.Equal(t.T(), 2, t.Server.called())
+}
+
+func (t *AuthClientTest) TestValidateDoesNotRetryRejectedToken() {
+	t.Server.errs = []error{status.Error(codes.Unauthenticated, "Invalid token")}
+	client := NewClient(t.Conn, Config{Backoff: time.Millisecond})
+
+	actual, err := client.Validate(context.Background(), t.Token)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(err))
+	assert.Equal(t.T(), 1, t.Server.called())
+}
+
+func (t *AuthClientTest) TestValidateFromCache() {
+	client := NewClient(t.Conn, Config{CacheSize: 10})
+
+	want, err := client.Validate(context.Background(), t.Token)
+	assert.Nil(t.T(), err)
+
+	actual, err := client.Validate(context.Background(), t.Token)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+	assert.Equal(t.T(), 1, t.Server.called())
+}
+
+func (t *AuthClientTest) TestCacheKeptUntilTokenExpires() {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": float64(time.Now().Add(time.Second).Unix()),
+	}).SignedString([]byte(faker.Password()))
+	assert.Nil(t.T(), err)
+
+	client := NewClient(t.Conn, Config{CacheSize: 10, CacheTTL: time.Hour})
+	now := time.Now()
+	client.cache.now = func() time.Time {
+		return now
+	}
+
+	_, err = client.Validate(context.Background(), token)
+	assert.Nil(t.T(), err)
+
+	now = now.Add(2 * time.Second)
+	_, err = client.Validate(context.Background(), token)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), 2, t.Server.called())
+}
+
+func (t *AuthClientTest) TestUnaryInterceptor() {
+	client := NewClient(t.Conn, Config{})
+	interceptor := client.UnaryServerInterceptor(role.USER, role.ADMIN)
+
+	var actual *Credential
+	_, err := interceptor(t.incomingContext(t.Token), nil, &grpc.UnaryServerInfo{FullMethod: "/rating.RatingService/Create"},
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			actual, _ = FromContext(ctx)
+			return nil, nil
+		})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.UserID, actual.UserId)
+}
+
+func (t *AuthClientTest) TestUnaryInterceptorForbidden() {
+	client := NewClient(t.Conn, Config{})
+	interceptor := client.UnaryServerInterceptor(role.ADMIN)
+
+	_, err := interceptor(t.incomingContext(t.Token), nil, &grpc.UnaryServerInfo{FullMethod: "/rating.RatingService/Delete"},
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			t.T().Fatal("handler called")
+			return nil, nil
+		})
+
+	assert.Equal(t.T(), codes.PermissionDenied, status.Code(err))
+}
+
+func (t *AuthClientTest) TestUnaryInterceptorPublicMethod() {
+	client := NewClient(t.Conn, Config{PublicMethods: []string{"/grpc.health.v1.Health/Check"}})
+	interceptor := client.UnaryServerInterceptor()
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"},
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			_, ok := FromContext(ctx)
+			assert.False(t.T(), ok)
+			return nil, nil
+		})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), 0, t.Server.called())
+}
+
+func (t *AuthClientTest) TestUnaryInterceptorMissingToken() {
+	client := NewClient(t.Conn, Config{})
+	interceptor := client.UnaryServerInterceptor()
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/rating.RatingService/Create"},
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			return nil, nil
+		})
+
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(err))
+}
+
+func (t *AuthClientTest) TestStreamInterceptor() {
+	client := NewClient(t.Conn, Config{})
+	interceptor := client.StreamServerInterceptor()
+
+	var actual *Credential
+	err := interceptor(nil, &contextStream{ctx: t.incomingContext(t.Token)}, &grpc.StreamServerInfo{FullMethod: "/rating.RatingService/Watch"},
+		func(_ interface{}, stream grpc.ServerStream) error {
+			actual, _ = FromContext(stream.Context())
+			return nil
+		})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.UserID, actual.UserId)
+}
+
+func (t *AuthClientTest) TestMiddleware() {
+	client := NewClient(t.Conn, Config{})
+
+	var actual *Credential
+	handler := client.Middleware(role.USER)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual, _ = FromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/ranking", nil)
+	req.Header.Set("Authorization", "Bearer "+t.Token)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t.T(), http.StatusNoContent, rec.Code)
+	assert.Equal(t.T(), t.UserID, actual.UserId)
+}
+
+func (t *AuthClientTest) TestMiddlewareErrors() {
+	client := NewClient(t.Conn, Config{})
+	handler := client.Middleware(role.ADMIN)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.T().Fatal("handler called")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ranking", nil))
+
+	assert.Equal(t.T(), http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t.T(), rec.Header().Get("WWW-Authenticate"))
+
+	req := httptest.NewRequest(http.MethodGet, "/ranking", nil)
+	req.Header.Set("Authorization", "Bearer "+t.Token)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t.T(), http.StatusForbidden, rec.Code)
+
+	body := errorResponse{}
+	assert.Nil(t.T(), json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t.T(), errorBody{Code: http.StatusForbidden, Status: codes.PermissionDenied.String(), Message: "Insufficient permission"}, body.Error)
+}
+
+func (t *AuthClientTest) TestRequire() {
+	ctx := NewContext(context.Background(), &Credential{UserId: t.UserID, Role: role.USER})
+
+	assert.Nil(t.T(), Require(ctx))
+	assert.Nil(t.T(), Require(ctx, role.USER))
+	assert.Equal(t.T(), codes.PermissionDenied, status.Code(Require(ctx, role.ADMIN)))
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(Require(context.Background())))
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,85 @@
+package authclient
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cache keeps the recent validations by token fingerprint, the least recently used are evicted first
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	fingerprint string
+	credential  *Credential
+	expiresAt   time.Time
+}
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+func (c *cache) get(fingerprint string) (*Credential, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[fingerprint]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+	credential := *entry.credential
+
+	return &credential, true
+}
+
+// set keeps the credential for the ttl, or until the token expires when it's sooner
+func (c *cache) set(fingerprint string, credential *Credential, tokenExpiresAt time.Time) {
+	expiresAt := c.now().Add(c.ttl)
+	if !tokenExpiresAt.IsZero() && tokenExpiresAt.Before(expiresAt) {
+		expiresAt = tokenExpiresAt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[fingerprint]; ok {
+		c.remove(element)
+	}
+
+	stored := *credential
+	c.entries[fingerprint] = c.lru.PushFront(&cacheEntry{
+		fingerprint: fingerprint,
+		credential:  &stored,
+		expiresAt:   expiresAt,
+	})
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *cache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*cacheEntry)
+	delete(c.entries, entry.fingerprint)
+}
@@ -0,0 +1,41 @@
+package authclient
+
+import (
+	"context"
+	"slices"
+
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type credentialKey struct{}
+
+func NewContext(ctx context.Context, credential *Credential) context.Context {
+	return context.WithValue(ctx, credentialKey{}, credential)
+}
+
+// FromContext returns the credential the interceptors or the middleware put in the context
+func FromContext(ctx context.Context) (*Credential, bool) {
+	credential, ok := ctx.Value(credentialKey{}).(*Credential)
+	return credential, ok && credential != nil
+}
+
+// Require checks that the caller has one of the roles, any authenticated caller passes without one.
+// The handlers call it for the methods that need more than the roles of the interceptor.
+func Require(ctx context.Context, roles ...role.Role) error {
+	credential, ok := FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "Missing credential")
+	}
+
+	return authorize(credential, roles)
+}
+
+func authorize(credential *Credential, roles []role.Role) error {
+	if len(roles) > 0 && !slices.Contains(roles, credential.Role) {
+		return status.Error(codes.PermissionDenied, "Insufficient permission")
+	}
+
+	return nil
+}
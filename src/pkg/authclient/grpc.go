@@ -0,0 +1,88 @@
+package authclient
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor validates the bearer token of every call but the public methods and puts the
+// credential in the context, the caller must have one of the roles when any is given
+func (c *Client) UnaryServerInterceptor(roles ...role.Role) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := c.authenticate(ctx, info.FullMethod, roles)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (c *Client) StreamServerInterceptor(roles ...role.Role) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := c.authenticate(ss.Context(), info.FullMethod, roles)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (c *Client) authenticate(ctx context.Context, method string, roles []role.Role) (context.Context, error) {
+	if slices.Contains(c.conf.PublicMethods, method) {
+		return ctx, nil
+	}
+
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := c.Validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorize(credential, roles); err != nil {
+		return nil, err
+	}
+
+	return NewContext(ctx, credential), nil
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "Missing metadata")
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "Missing authorization header")
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return "", status.Error(codes.Unauthenticated, "Invalid authorization header")
+	}
+
+	return token, nil
+}
+
+// authenticatedStream passes the context with the credential to the stream handler
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,100 @@
+package authclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorResponse has the error body of the auth gateway, so the callers see the same errors behind
+// either service
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code     int               `json:"code"`
+	Status   string            `json:"status"`
+	Message  string            `json:"message"`
+	Reason   string            `json:"reason,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Middleware validates the bearer token of the requests and puts the credential in their context, the
+// caller must have one of the roles when any is given. The errors have the body of the auth gateway.
+func (c *Client) Middleware(roles ...role.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				writeError(w, status.Error(codes.Unauthenticated, "Missing bearer token"))
+				return
+			}
+
+			credential, err := c.Validate(r.Context(), token)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			if err := authorize(credential, roles); err != nil {
+				writeError(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), credential)))
+		})
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+
+	body := errorBody{
+		Code:    httpStatusFromCode(st.Code()),
+		Status:  st.Code().String(),
+		Message: st.Message(),
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			body.Reason = info.Reason
+			body.Metadata = info.Metadata
+		}
+	}
+
+	if st.Code() == codes.Unauthenticated {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mygraderlist"`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(body.Code)
+	_ = json.NewEncoder(w).Encode(&errorResponse{Error: body})
+}
+
+// httpStatusFromCode maps the status codes the validation fails with, the others are internal errors
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,289 @@
+package authclient
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultJwksRefresh = time.Hour
+	// minJwksRefresh limits how often an unknown key id makes the keys be fetched again
+	minJwksRefresh = 30 * time.Second
+	// accessTokenType is the typ header of the access tokens (RFC 9068), the id tokens signed with the
+	// same keys don't have it and are never taken as a credential
+	accessTokenType = "at+jwt"
+)
+
+var (
+	ErrUnknownKey      = errors.New("Unknown signing key")
+	ErrKeysUnavailable = errors.New("Signing keys are unavailable")
+)
+
+// jwks verifies the access tokens signed with the RSA keys of the auth service without calling it. The
+// revocations aren't seen, a locally verified token is valid until it expires.
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwks struct {
+	uri        string
+	issuer     string
+	audience   string
+	refresh    time.Duration
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func newJwks(conf Config) *jwks {
+	refresh := conf.JwksRefresh
+	if refresh <= 0 {
+		refresh = defaultJwksRefresh
+	}
+
+	return &jwks{
+		uri:        conf.JwksUri,
+		issuer:     conf.Issuer,
+		audience:   conf.Audience,
+		refresh:    refresh,
+		httpClient: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		now:        time.Now,
+	}
+}
+
+// accepts tells whether the token is signed with an RSA key, the others are left to the auth service
+func (j *jwks) accepts(token string) bool {
+	t, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return false
+	}
+
+	switch t.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return true
+	default:
+		return false
+	}
+}
+
+func (j *jwks) verify(ctx context.Context, token string) (*Credential, error) {
+	claims := jwt.MapClaims{}
+
+	t, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		default:
+			return nil, errors.New("Unexpected signing method")
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		return j.key(ctx, kid)
+	})
+	if errors.Is(err, ErrKeysUnavailable) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	// an id token is only meant for the client it's issued to, never to call a service with
+	typ, _ := t.Header["typ"].(string)
+	if clientID, _ := claims["client_id"].(string); !isAccessTokenType(typ) || clientID == "" {
+		return nil, status.Error(codes.Unauthenticated, "Not an access token")
+	}
+
+	if _, ok := claims["exp"].(float64); !ok {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	if j.issuer != "" && claims["iss"] != j.issuer {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	credential := credentialFromClaims(claims)
+	if credential.UserId == "" {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	if j.audience == "" || !slices.Contains(credential.Audience, j.audience) {
+		return nil, status.Error(codes.Unauthenticated, "Invalid audience")
+	}
+
+	return credential, nil
+}
+
+// isAccessTokenType accepts the media type of the typ header either with or without its prefix
+func isAccessTokenType(typ string) bool {
+	return strings.TrimPrefix(strings.ToLower(typ), "application/") == accessTokenType
+}
+
+// key returns the public key of the key id. The keys are fetched again once they are stale, or
+// sooner for an unknown key id since the auth service may have rotated its key.
+func (j *jwks) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key, ok := j.keys[kid]
+	age := j.now().Sub(j.fetchedAt)
+	switch {
+	case ok && age < j.refresh:
+		return key, nil
+	case !ok && j.keys != nil && age < minJwksRefresh:
+		return nil, ErrUnknownKey
+	}
+
+	keys, err := j.fetch(ctx)
+	if err != nil {
+		// the stale key is still better than failing every call while the auth service is down
+		if ok {
+			return key, nil
+		}
+		return nil, errors.Wrap(ErrKeysUnavailable, err.Error())
+	}
+	j.keys = keys
+	j.fetchedAt = j.now()
+
+	key, ok = j.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+func (j *jwks) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := j.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot fetch the signing keys")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Cannot fetch the signing keys, status %d", res.StatusCode)
+	}
+
+	set := jwkSet{}
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, errors.Wrap(err, "Cannot decode the signing keys")
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+
+		key, err := decodeRsaPublicKey(jwk.N, jwk.E)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Cannot decode the signing key %v", jwk.Kid)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func decodeRsaPublicKey(n string, e string) (*rsa.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid modulus")
+	}
+
+	exponent, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}, nil
+}
+
+// credentialFromClaims reads the credential from the claims of an access token, which is issued either
+// to the client itself or to the client on behalf of its subject
+func credentialFromClaims(claims jwt.MapClaims) *Credential {
+	credential := &Credential{}
+
+	if exp, ok := claims["exp"].(float64); ok {
+		credential.ExpiresAt = int64(exp)
+	}
+	if iat, ok := claims["iat"].(float64); ok {
+		credential.IssuedAt = int64(iat)
+	}
+
+	scope, _ := claims["scope"].(string)
+	credential.Scopes = strings.Fields(scope)
+	credential.Audience = audience(claims)
+
+	clientID, _ := claims["client_id"].(string)
+	subject, _ := claims["sub"].(string)
+	if subject == "" || subject == clientID {
+		credential.UserId = clientID
+		credential.Role = role.SERVICE
+
+		return credential
+	}
+
+	credential.UserId = subject
+
+	// the tokens without a role claim are only trusted as a user
+	credential.Role = role.USER
+	if r, ok := claims["role"].(string); ok && r != "" {
+		credential.Role = role.Role(r)
+	}
+
+	if act, ok := claims["act"].(map[string]interface{}); ok {
+		credential.ActorId, _ = act["sub"].(string)
+	}
+
+	return credential
+}
+
+// audience reads the aud claim, which is either a string or a list
+func audience(claims jwt.MapClaims) []string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		var audience []string
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audience = append(audience, s)
+			}
+		}
+		return audience
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,300 @@
+package authclient
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	role "github.com/bookpanda/mygraderlist-auth/src/constant/auth"
+	"github.com/bxcodec/faker/v3"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type JwksTest struct {
+	suite.Suite
+	Key      *rsa.PrivateKey
+	KeyID    string
+	Issuer   string
+	ClientID string
+	Fetches  atomic.Int32
+	Server   *httptest.Server
+}
+
+func TestJwks(t *testing.T) {
+	suite.Run(t, new(JwksTest))
+}
+
+func (t *JwksTest) SetupTest() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t.T(), err)
+
+	t.Key = key
+	t.KeyID = faker.UUIDDigit()
+	t.Issuer = "https://" + faker.DomainName()
+	t.ClientID = faker.Word()
+	t.Fetches.Store(0)
+
+	t.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fetches.Add(1)
+
+		_ = json.NewEncoder(w).Encode(&jwkSet{Keys: []jwk{{
+			Kty: "RSA",
+			Kid: t.KeyID,
+			N:   base64.RawURLEncoding.EncodeToString(t.Key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(t.Key.E)).Bytes()),
+		}}})
+	}))
+	t.T().Cleanup(t.Server.Close)
+}
+
+func (t *JwksTest) newJwks() *jwks {
+	return newJwks(Config{JwksUri: t.Server.URL, Issuer: t.Issuer, Audience: "mgl-backend"})
+}
+
+func (t *JwksTest) sign(kid string, claims jwt.MapClaims) string {
+	return t.signWithType(kid, accessTokenType, claims)
+}
+
+func (t *JwksTest) signWithType(kid string, typ string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	token.Header["typ"] = typ
+
+	signed, err := token.SignedString(t.Key)
+	assert.Nil(t.T(), err)
+
+	return signed
+}
+
+func (t *JwksTest) TestVerifyUserToken() {
+	userID := faker.UUIDDigit()
+	actorID := faker.UUIDDigit()
+	exp := time.Now().Add(time.Minute).Unix()
+
+	token := t.sign(t.KeyID, jwt.MapClaims{
+		"iss":       t.Issuer,
+		"exp":       float64(exp),
+		"aud":       "mgl-backend",
+		"client_id": t.ClientID,
+		"sub":       userID,
+		"scope":     "openid rating:read",
+		"role":      string(role.ADMIN),
+		"act":       map[string]interface{}{"sub": actorID},
+	})
+
+	j := t.newJwks()
+	assert.True(t.T(), j.accepts(token))
+
+	actual, err := j.verify(context.Background(), token)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), &Credential{
+		UserId:    userID,
+		Role:      role.ADMIN,
+		ActorId:   actorID,
+		Scopes:    []string{"openid", "rating:read"},
+		Audience:  []string{"mgl-backend"},
+		ExpiresAt: exp,
+	}, actual)
+}
+
+func (t *JwksTest) TestVerifyServiceTokenAudience() {
+	claims := jwt.MapClaims{
+		"iss":       t.Issuer,
+		"exp":       float64(time.Now().Add(time.Minute).Unix()),
+		"client_id": t.ClientID,
+		"aud":       []string{"mgl-backend"},
+		"scope":     "rating:read",
+	}
+
+	j := t.newJwks()
+
+	actual, err := j.verify(context.Background(), t.sign(t.KeyID, claims))
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), &Credential{
+		UserId:    t.ClientID,
+		Role:      role.SERVICE,
+		Scopes:    []string{"rating:read"},
+		Audience:  []string{"mgl-backend"},
+		ExpiresAt: int64(claims["exp"].(float64)),
+	}, actual)
+
+	claims["aud"] = "mgl-other"
+	_, err = j.verify(context.Background(), t.sign(t.KeyID, claims))
+
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(err))
+}
+
+func (t *JwksTest) TestVerifyRejectsIdToken() {
+	// an id token has the audience of the client it's issued to, without the type of an access token
+	token := t.signWithType(t.KeyID, "JWT", jwt.MapClaims{
+		"iss":   t.Issuer,
+		"exp":   float64(time.Now().Add(time.Minute).Unix()),
+		"aud":   "mgl-backend",
+		"sub":   faker.UUIDDigit(),
+		"nonce": faker.Word(),
+	})
+
+	_, err := t.newJwks().verify(context.Background(), token)
+
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(err))
+}
+
+func (t *JwksTest) TestVerifyRejectsWithoutClientID() {
+	token := t.sign(t.KeyID, jwt.MapClaims{
+		"iss": t.Issuer,
+		"exp": float64(time.Now().Add(time.Minute).Unix()),
+		"aud": "mgl-backend",
+		"sub": faker.UUIDDigit(),
+	})
+
+	_, err := t.newJwks().verify(context.Background(), token)
+
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(err))
+}
+
+func (t *JwksTest) TestVerifyRejectsUserTokenAudience() {
+	token := t.sign(t.KeyID, jwt.MapClaims{
+		"iss":       t.Issuer,
+		"exp":       float64(time.Now().Add(time.Minute).Unix()),
+		"aud":       "mgl-other",
+		"client_id": t.ClientID,
+		"sub":       faker.UUIDDigit(),
+	})
+
+	_, err := t.newJwks().verify(context.Background(), token)
+
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(err))
+}
+
+func (t *JwksTest) TestVerifyWithoutAudienceFailsClosed() {
+	token := t.sign(t.KeyID, jwt.MapClaims{
+		"iss":       t.Issuer,
+		"exp":       float64(time.Now().Add(time.Minute).Unix()),
+		"client_id": t.ClientID,
+		"sub":       faker.UUIDDigit(),
+	})
+
+	_, err := newJwks(Config{JwksUri: t.Server.URL, Issuer: t.Issuer}).verify(context.Background(), token)
+
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(err))
+}
+
+func (t *JwksTest) TestVerifyRejectsIssuer() {
+	token := t.sign(t.KeyID, jwt.MapClaims{
+		"iss":       "https://" + faker.DomainName(),
+		"exp":       float64(time.Now().Add(time.Minute).Unix()),
+		"aud":       "mgl-backend",
+		"client_id": t.ClientID,
+		"sub":       faker.UUIDDigit(),
+	})
+
+	_, err := t.newJwks().verify(context.Background(), token)
+
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(err))
+}
+
+func (t *JwksTest) TestVerifyRejectsExpired() {
+	token := t.sign(t.KeyID, jwt.MapClaims{
+		"iss":       t.Issuer,
+		"exp":       float64(time.Now().Add(-time.Minute).Unix()),
+		"aud":       "mgl-backend",
+		"client_id": t.ClientID,
+		"sub":       faker.UUIDDigit(),
+	})
+
+	_, err := t.newJwks().verify(context.Background(), token)
+
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(err))
+}
+
+func (t *JwksTest) TestKeysCached() {
+	claims := jwt.MapClaims{
+		"iss":       t.Issuer,
+		"exp":       float64(time.Now().Add(time.Minute).Unix()),
+		"aud":       "mgl-backend",
+		"client_id": t.ClientID,
+		"sub":       faker.UUIDDigit(),
+	}
+
+	j := t.newJwks()
+	for i := 0; i < 3; i++ {
+		_, err := j.verify(context.Background(), t.sign(t.KeyID, claims))
+		assert.Nil(t.T(), err)
+	}
+
+	assert.Equal(t.T(), int32(1), t.Fetches.Load())
+}
+
+func (t *JwksTest) TestRotatedKeyFetchedAgain() {
+	claims := jwt.MapClaims{
+		"iss":       t.Issuer,
+		"exp":       float64(time.Now().Add(time.Minute).Unix()),
+		"aud":       "mgl-backend",
+		"client_id": t.ClientID,
+		"sub":       faker.UUIDDigit(),
+	}
+
+	now := time.Now()
+	j := t.newJwks()
+	j.now = func() time.Time {
+		return now
+	}
+
+	_, err := j.verify(context.Background(), t.sign(t.KeyID, claims))
+	assert.Nil(t.T(), err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t.T(), err)
+	t.Key = key
+	t.KeyID = faker.UUIDDigit()
+	token := t.sign(t.KeyID, claims)
+
+	// an unknown key id only fetches the keys again once in a while
+	_, err = j.verify(context.Background(), token)
+	assert.Equal(t.T(), codes.Unauthenticated, status.Code(err))
+
+	now = now.Add(minJwksRefresh)
+	_, err = j.verify(context.Background(), token)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), int32(2), t.Fetches.Load())
+}
+
+func (t *JwksTest) TestKeysUnavailable() {
+	t.Server.Close()
+
+	token := t.sign(t.KeyID, jwt.MapClaims{
+		"iss":       t.Issuer,
+		"exp":       float64(time.Now().Add(time.Minute).Unix()),
+		"aud":       "mgl-backend",
+		"client_id": t.ClientID,
+		"sub":       faker.UUIDDigit(),
+	})
+
+	_, err := t.newJwks().verify(context.Background(), token)
+
+	assert.Equal(t.T(), codes.Unavailable, status.Code(err))
+}
+
+func (t *JwksTest) TestSymmetricTokenLeftToAuthService() {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": faker.UUIDDigit()}).
+		SignedString([]byte(faker.Password()))
+	assert.Nil(t.T(), err)
+
+	assert.False(t.T(), t.newJwks().accepts(token))
+	assert.False(t.T(), t.newJwks().accepts(faker.Word()))
+}